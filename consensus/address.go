@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/hash"
+)
+
+const (
+	// AddressChecksumSize is the number of bytes of checksum that are
+	// appended to a CoinAddress in its string form.
+	AddressChecksumSize = 6
+
+	// AddressStringLen is the length of the string form of a CoinAddress:
+	// the hex encoding of the address followed by its checksum.
+	AddressStringLen = 2 * (hash.HashSize + AddressChecksumSize)
+)
+
+var (
+	ErrAddressLength   = errors.New("address has the wrong length")
+	ErrAddressChecksum = errors.New("address has an invalid checksum")
+)
+
+// checksum returns the checksum of a CoinAddress, which is the first
+// AddressChecksumSize bytes of its hash.
+func (ca CoinAddress) checksum() []byte {
+	h := hash.HashBytes(ca[:])
+	return h[:AddressChecksumSize]
+}
+
+// String returns the string form of a CoinAddress, which is the hex encoding
+// of the address followed by a checksum. The checksum allows mistyped
+// addresses to be detected by ParseCoinAddress.
+func (ca CoinAddress) String() string {
+	return hex.EncodeToString(append(ca[:], ca.checksum()...))
+}
+
+// ParseCoinAddress decodes the string form of a CoinAddress, verifying its
+// checksum. Surrounding whitespace is ignored.
+func ParseCoinAddress(s string) (ca CoinAddress, err error) {
+	s = strings.TrimSpace(s)
+	if len(s) != AddressStringLen {
+		err = ErrAddressLength
+		return
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return
+	}
+	copy(ca[:], b)
+	if !bytes.Equal(ca.checksum(), b[hash.HashSize:]) {
+		err = ErrAddressChecksum
+		return
+	}
+	return
+}
@@ -0,0 +1,450 @@
+// Code generated by encoding.Generate; DO NOT EDIT.
+
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/hash"
+)
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x Block) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *Block) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x Block) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *Block) appendSia(b []byte) []byte {
+	b = append(b, x.ParentBlockID[:]...)
+	b = encoding.AppendUint64(b, uint64(x.Timestamp))
+	b = encoding.AppendUint64(b, x.Nonce)
+	b = append(b, x.MinerAddress[:]...)
+	b = append(b, x.MerkleRoot[:]...)
+	b = encoding.AppendUint64(b, uint64(len(x.Transactions)))
+	for i0 := range x.Transactions {
+		b = x.Transactions[i0].appendSia(b)
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *Block) ReadSia(r *encoding.SliceReader) {
+	copy(x.ParentBlockID[:], r.ReadBytes(32))
+	x.Timestamp = Timestamp(r.ReadUint64())
+	x.Nonce = r.ReadUint64()
+	copy(x.MinerAddress[:], r.ReadBytes(32))
+	copy(x.MerkleRoot[:], r.ReadBytes(32))
+	x.Transactions = make([]Transaction, r.ReadLen(56, 168))
+	for i0 := range x.Transactions {
+		x.Transactions[i0].ReadSia(r)
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x Transaction) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *Transaction) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x Transaction) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *Transaction) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.Inputs)))
+	for i0 := range x.Inputs {
+		b = x.Inputs[i0].appendSia(b)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.MinerFees)))
+	for i0 := range x.MinerFees {
+		b = encoding.AppendUint64(b, uint64(x.MinerFees[i0]))
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Outputs)))
+	for i0 := range x.Outputs {
+		b = x.Outputs[i0].appendSia(b)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.FileContracts)))
+	for i0 := range x.FileContracts {
+		b = x.FileContracts[i0].appendSia(b)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.StorageProofs)))
+	for i0 := range x.StorageProofs {
+		b = x.StorageProofs[i0].appendSia(b)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.ArbitraryData)))
+	for i0 := range x.ArbitraryData {
+		b = encoding.AppendUint64(b, uint64(len(x.ArbitraryData[i0])))
+		b = append(b, x.ArbitraryData[i0]...)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Signatures)))
+	for i0 := range x.Signatures {
+		b = x.Signatures[i0].appendSia(b)
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *Transaction) ReadSia(r *encoding.SliceReader) {
+	x.Inputs = make([]Input, r.ReadLen(56, 72))
+	for i0 := range x.Inputs {
+		x.Inputs[i0].ReadSia(r)
+	}
+	x.MinerFees = make([]Currency, r.ReadLen(8, 8))
+	for i0 := range x.MinerFees {
+		x.MinerFees[i0] = Currency(r.ReadUint64())
+	}
+	x.Outputs = make([]Output, r.ReadLen(40, 40))
+	for i0 := range x.Outputs {
+		x.Outputs[i0].ReadSia(r)
+	}
+	x.FileContracts = make([]FileContract, r.ReadLen(160, 160))
+	for i0 := range x.FileContracts {
+		x.FileContracts[i0].ReadSia(r)
+	}
+	x.StorageProofs = make([]StorageProof, r.ReadLen(112, 128))
+	for i0 := range x.StorageProofs {
+		x.StorageProofs[i0].ReadSia(r)
+	}
+	x.ArbitraryData = make([]string, r.ReadLen(8, 16))
+	for i0 := range x.ArbitraryData {
+		x.ArbitraryData[i0] = r.ReadString()
+	}
+	x.Signatures = make([]TransactionSignature, r.ReadLen(106, 232))
+	for i0 := range x.Signatures {
+		x.Signatures[i0].ReadSia(r)
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x Input) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *Input) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x Input) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *Input) appendSia(b []byte) []byte {
+	b = append(b, x.OutputID[:]...)
+	b = x.SpendConditions.appendSia(b)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *Input) ReadSia(r *encoding.SliceReader) {
+	copy(x.OutputID[:], r.ReadBytes(32))
+	x.SpendConditions.ReadSia(r)
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x Output) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *Output) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x Output) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *Output) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(x.Value))
+	b = append(b, x.SpendHash[:]...)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *Output) ReadSia(r *encoding.SliceReader) {
+	x.Value = Currency(r.ReadUint64())
+	copy(x.SpendHash[:], r.ReadBytes(32))
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x SpendConditions) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *SpendConditions) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x SpendConditions) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *SpendConditions) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(x.TimeLock))
+	b = encoding.AppendUint64(b, x.NumSignatures)
+	b = encoding.AppendUint64(b, uint64(len(x.PublicKeys)))
+	for i0 := range x.PublicKeys {
+		if x.PublicKeys[i0] == nil {
+			b = append(b, 0)
+		} else {
+			b = append(b, 1)
+			b = append(b, (*x.PublicKeys[i0])[:]...)
+		}
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *SpendConditions) ReadSia(r *encoding.SliceReader) {
+	x.TimeLock = BlockHeight(r.ReadUint64())
+	x.NumSignatures = r.ReadUint64()
+	x.PublicKeys = make([]crypto.PublicKey, r.ReadLen(1, 8))
+	for i0 := range x.PublicKeys {
+		if r.ReadBool() {
+			if x.PublicKeys[i0] == nil {
+				r.Alloc(32)
+				x.PublicKeys[i0] = crypto.PublicKey(new([32]byte))
+			}
+			copy((*x.PublicKeys[i0])[:], r.ReadBytes(32))
+		}
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x StorageProof) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *StorageProof) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x StorageProof) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *StorageProof) appendSia(b []byte) []byte {
+	b = append(b, x.ContractID[:]...)
+	b = encoding.AppendUint64(b, uint64(x.WindowIndex))
+	b = append(b, x.Segment[:]...)
+	b = encoding.AppendUint64(b, uint64(len(x.HashSet)))
+	for i0 := range x.HashSet {
+		b = append(b, x.HashSet[i0][:]...)
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *StorageProof) ReadSia(r *encoding.SliceReader) {
+	copy(x.ContractID[:], r.ReadBytes(32))
+	x.WindowIndex = BlockHeight(r.ReadUint64())
+	copy(x.Segment[:], r.ReadBytes(64))
+	x.HashSet = make([]hash.Hash, r.ReadLen(32, 32))
+	for i0 := range x.HashSet {
+		copy(x.HashSet[i0][:], r.ReadBytes(32))
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x FileContract) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *FileContract) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x FileContract) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *FileContract) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(x.ContractFund))
+	b = append(b, x.FileMerkleRoot[:]...)
+	b = encoding.AppendUint64(b, x.FileSize)
+	b = encoding.AppendUint64(b, uint64(x.Start))
+	b = encoding.AppendUint64(b, uint64(x.End))
+	b = encoding.AppendUint64(b, uint64(x.ChallengeWindow))
+	b = encoding.AppendUint64(b, x.Tolerance)
+	b = encoding.AppendUint64(b, uint64(x.ValidProofPayout))
+	b = append(b, x.ValidProofAddress[:]...)
+	b = encoding.AppendUint64(b, uint64(x.MissedProofPayout))
+	b = append(b, x.MissedProofAddress[:]...)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *FileContract) ReadSia(r *encoding.SliceReader) {
+	x.ContractFund = Currency(r.ReadUint64())
+	copy(x.FileMerkleRoot[:], r.ReadBytes(32))
+	x.FileSize = r.ReadUint64()
+	x.Start = BlockHeight(r.ReadUint64())
+	x.End = BlockHeight(r.ReadUint64())
+	x.ChallengeWindow = BlockHeight(r.ReadUint64())
+	x.Tolerance = r.ReadUint64()
+	x.ValidProofPayout = Currency(r.ReadUint64())
+	copy(x.ValidProofAddress[:], r.ReadBytes(32))
+	x.MissedProofPayout = Currency(r.ReadUint64())
+	copy(x.MissedProofAddress[:], r.ReadBytes(32))
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x TransactionSignature) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *TransactionSignature) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x TransactionSignature) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *TransactionSignature) appendSia(b []byte) []byte {
+	b = append(b, x.InputID[:]...)
+	b = encoding.AppendUint64(b, uint64(x.TimeLock))
+	b = x.CoveredFields.appendSia(b)
+	b = encoding.AppendUint64(b, x.PublicKeyIndex)
+	if x.Signature == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		b = append(b, (*x.Signature)[:]...)
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *TransactionSignature) ReadSia(r *encoding.SliceReader) {
+	copy(x.InputID[:], r.ReadBytes(32))
+	x.TimeLock = BlockHeight(r.ReadUint64())
+	x.CoveredFields.ReadSia(r)
+	x.PublicKeyIndex = r.ReadUint64()
+	if r.ReadBool() {
+		if x.Signature == nil {
+			r.Alloc(64)
+			x.Signature = crypto.Signature(new([64]byte))
+		}
+		copy((*x.Signature)[:], r.ReadBytes(64))
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x CoveredFields) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *CoveredFields) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x CoveredFields) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *CoveredFields) appendSia(b []byte) []byte {
+	if x.WholeTransaction {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.MinerFees)))
+	for i0 := range x.MinerFees {
+		b = encoding.AppendUint64(b, x.MinerFees[i0])
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Inputs)))
+	for i0 := range x.Inputs {
+		b = encoding.AppendUint64(b, x.Inputs[i0])
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Outputs)))
+	for i0 := range x.Outputs {
+		b = encoding.AppendUint64(b, x.Outputs[i0])
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Contracts)))
+	for i0 := range x.Contracts {
+		b = encoding.AppendUint64(b, x.Contracts[i0])
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.StorageProofs)))
+	for i0 := range x.StorageProofs {
+		b = encoding.AppendUint64(b, x.StorageProofs[i0])
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.ArbitraryData)))
+	for i0 := range x.ArbitraryData {
+		b = encoding.AppendUint64(b, x.ArbitraryData[i0])
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Signatures)))
+	for i0 := range x.Signatures {
+		b = encoding.AppendUint64(b, x.Signatures[i0])
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *CoveredFields) ReadSia(r *encoding.SliceReader) {
+	x.WholeTransaction = r.ReadBool()
+	x.MinerFees = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.MinerFees {
+		x.MinerFees[i0] = r.ReadUint64()
+	}
+	x.Inputs = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.Inputs {
+		x.Inputs[i0] = r.ReadUint64()
+	}
+	x.Outputs = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.Outputs {
+		x.Outputs[i0] = r.ReadUint64()
+	}
+	x.Contracts = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.Contracts {
+		x.Contracts[i0] = r.ReadUint64()
+	}
+	x.StorageProofs = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.StorageProofs {
+		x.StorageProofs[i0] = r.ReadUint64()
+	}
+	x.ArbitraryData = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.ArbitraryData {
+		x.ArbitraryData[i0] = r.ReadUint64()
+	}
+	x.Signatures = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.Signatures {
+		x.Signatures[i0] = r.ReadUint64()
+	}
+}
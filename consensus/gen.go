@@ -0,0 +1,30 @@
+// +build ignore
+
+// This program generates encoding_gen.go. It is run by go generate.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/encoding"
+)
+
+func main() {
+	err := encoding.GenerateFile("encoding_gen.go", "github.com/NebulousLabs/Sia/consensus",
+		consensus.Block{},
+		consensus.Transaction{},
+		consensus.Input{},
+		consensus.Output{},
+		consensus.SpendConditions{},
+		consensus.StorageProof{},
+		consensus.FileContract{},
+		consensus.TransactionSignature{},
+		consensus.CoveredFields{},
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
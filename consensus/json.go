@@ -0,0 +1,169 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+)
+
+// The JSON representation of the consensus types is designed to be readable
+// and lossless. Hashes and IDs are hex strings, addresses are checksummed hex
+// strings (see CoinAddress.String), currencies are decimal strings (so that
+// they survive parsers that store numbers as floats), and public keys and
+// signatures are hex strings. Converting a block or transaction to JSON
+// and back yields the same binary encoding.
+
+// MarshalJSON marshals an ID as a hex string.
+func (bid BlockID) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(bid[:])
+}
+
+// UnmarshalJSON decodes an ID from a hex string.
+func (bid *BlockID) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, bid[:])
+}
+
+// MarshalJSON marshals an ID as a hex string.
+func (oid OutputID) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(oid[:])
+}
+
+// UnmarshalJSON decodes an ID from a hex string.
+func (oid *OutputID) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, oid[:])
+}
+
+// MarshalJSON marshals an ID as a hex string.
+func (cid ContractID) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(cid[:])
+}
+
+// UnmarshalJSON decodes an ID from a hex string.
+func (cid *ContractID) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, cid[:])
+}
+
+// MarshalJSON marshals an ID as a hex string.
+func (tid TransactionID) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(tid[:])
+}
+
+// UnmarshalJSON decodes an ID from a hex string.
+func (tid *TransactionID) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, tid[:])
+}
+
+// MarshalJSON marshals an address in its checksummed string form.
+func (ca CoinAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ca.String())
+}
+
+// UnmarshalJSON decodes an address from its checksummed string form.
+func (ca *CoinAddress) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	addr, err := ParseCoinAddress(s)
+	if err != nil {
+		return err
+	}
+	*ca = addr
+	return nil
+}
+
+// MarshalJSON marshals a target as a hex string.
+func (t Target) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(t[:])
+}
+
+// UnmarshalJSON decodes a target from a hex string.
+func (t *Target) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, t[:])
+}
+
+// MarshalJSON marshals a currency as a decimal string.
+func (c Currency) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(c), 10))
+}
+
+// UnmarshalJSON decodes a currency from a decimal string.
+func (c *Currency) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("currency must be a decimal string")
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*c = Currency(u)
+	return nil
+}
+
+// MarshalJSON marshals spend conditions, encoding the public keys as hex
+// strings.
+func (sc SpendConditions) MarshalJSON() ([]byte, error) {
+	type plain SpendConditions
+	jsc := struct {
+		plain
+		PublicKeys []*crypto.JSONPublicKey
+	}{plain: plain(sc)}
+	if sc.PublicKeys != nil {
+		jsc.PublicKeys = make([]*crypto.JSONPublicKey, len(sc.PublicKeys))
+		for i, pk := range sc.PublicKeys {
+			jsc.PublicKeys[i] = crypto.ToJSONPublicKey(pk)
+		}
+	}
+	return json.Marshal(jsc)
+}
+
+// UnmarshalJSON decodes spend conditions that were marshalled with
+// MarshalJSON.
+func (sc *SpendConditions) UnmarshalJSON(b []byte) error {
+	type plain SpendConditions
+	var jsc struct {
+		plain
+		PublicKeys []*crypto.JSONPublicKey
+	}
+	if err := json.Unmarshal(b, &jsc); err != nil {
+		return err
+	}
+	*sc = SpendConditions(jsc.plain)
+	if jsc.PublicKeys != nil {
+		sc.PublicKeys = make([]crypto.PublicKey, len(jsc.PublicKeys))
+		for i, pk := range jsc.PublicKeys {
+			sc.PublicKeys[i] = pk.PublicKey()
+		}
+	}
+	return nil
+}
+
+// MarshalJSON marshals a transaction signature, encoding the signature as a
+// hex string.
+func (ts TransactionSignature) MarshalJSON() ([]byte, error) {
+	type plain TransactionSignature
+	return json.Marshal(struct {
+		plain
+		Signature *crypto.JSONSignature
+	}{plain(ts), crypto.ToJSONSignature(ts.Signature)})
+}
+
+// UnmarshalJSON decodes a transaction signature that was marshalled with
+// MarshalJSON.
+func (ts *TransactionSignature) UnmarshalJSON(b []byte) error {
+	type plain TransactionSignature
+	var jts struct {
+		plain
+		Signature *crypto.JSONSignature
+	}
+	if err := json.Unmarshal(b, &jts); err != nil {
+		return err
+	}
+	*ts = TransactionSignature(jts.plain)
+	ts.Signature = jts.Signature.Signature()
+	return nil
+}
@@ -152,6 +152,7 @@ func (s *State) validTransaction(t Transaction) (err error) {
 		newInputSignatures := &InputSignatures{
 			RemainingSignatures: input.SpendConditions.NumSignatures,
 			PossibleKeys:        input.SpendConditions.PublicKeys,
+			UsedKeys:            make(map[uint64]struct{}),
 			Index:               i,
 		}
 		inputSignaturesMap[input.OutputID] = newInputSignatures
@@ -208,14 +209,26 @@ func (s *State) validTransaction(t Transaction) (err error) {
 
 	// Check all of the signatures for validity.
 	for i, sig := range t.Signatures {
+		// Check that each signature refers to an input and one of its keys.
+		// Signatures may be collected from several parties, so they cannot
+		// be trusted to be well formed.
+		inputSignatures, exists := inputSignaturesMap[sig.InputID]
+		if !exists {
+			err = errors.New("signature refers to an unknown input")
+			return
+		}
+		if sig.PublicKeyIndex >= uint64(len(inputSignatures.PossibleKeys)) {
+			err = errors.New("signature refers to an unknown public key")
+			return
+		}
+
 		// Check that each signature signs a unique pubkey where
 		// RemainingSignatures > 0.
-		if inputSignaturesMap[sig.InputID].RemainingSignatures == 0 {
+		if inputSignatures.RemainingSignatures == 0 {
 			err = errors.New("friviolous signature detected.")
 			return
 		}
-		_, exists := inputSignaturesMap[sig.InputID].UsedKeys[sig.PublicKeyIndex]
-		if exists {
+		if _, exists := inputSignatures.UsedKeys[sig.PublicKeyIndex]; exists {
 			err = errors.New("public key used twice while signing")
 			return
 		}
@@ -228,13 +241,15 @@ func (s *State) validTransaction(t Transaction) (err error) {
 
 		// Check that the signature matches the public key.
 		sigHash := t.SigHash(i)
-		if !crypto.VerifyBytes(sigHash[:], inputSignaturesMap[sig.InputID].PossibleKeys[sig.PublicKeyIndex], sig.Signature) {
+		if !crypto.VerifyBytes(sigHash[:], inputSignatures.PossibleKeys[sig.PublicKeyIndex], sig.Signature) {
 			err = InvalidSignatureErr
 			return
 		}
 
-		// Subtract the number of signatures remaining in the InputSignatures field.
-		inputSignaturesMap[sig.InputID].RemainingSignatures -= 1
+		// Subtract the number of signatures remaining in the InputSignatures
+		// field, and record the key so that it cannot sign again.
+		inputSignatures.RemainingSignatures -= 1
+		inputSignatures.UsedKeys[sig.PublicKeyIndex] = struct{}{}
 	}
 
 	// Check that all inputs have been signed by sufficient public keys.
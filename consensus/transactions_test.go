@@ -2,6 +2,8 @@ package consensus
 
 import (
 	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
 )
 
 // TestApplyTransaction provides testing coverage for State.applyTransaction()
@@ -28,3 +30,67 @@ func TestApplyTransaction(t *testing.T) {
 		t.Error("new output not added during applyTransaction")
 	}
 }
+
+// TestMultisigTransaction checks that a 2-of-3 output can be spent with two
+// signatures, and that signatures referring to an unknown input or key, or
+// reusing a key, are rejected.
+func TestMultisigTransaction(t *testing.T) {
+	s, _ := CreateGenesisState()
+
+	// Create a 2-of-3 output.
+	var sks []crypto.SecretKey
+	sc := SpendConditions{NumSignatures: 2}
+	for i := 0; i < 3; i++ {
+		sk, pk, err := crypto.GenerateSignatureKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sks = append(sks, sk)
+		sc.PublicKeys = append(sc.PublicKeys, pk)
+	}
+	id := OutputID{1}
+	s.unspentOutputs[id] = Output{Value: 10, SpendHash: sc.CoinAddress()}
+
+	// signedTxn returns a transaction spending the output, signed by the
+	// keys at the given indices. Each signature's InputID is taken from
+	// inputIDs.
+	signedTxn := func(keys []uint64, inputIDs []OutputID) Transaction {
+		txn := Transaction{
+			Inputs:  []Input{{OutputID: id, SpendConditions: sc}},
+			Outputs: []Output{{Value: 10}},
+		}
+		for i, key := range keys {
+			txn.Signatures = append(txn.Signatures, TransactionSignature{
+				InputID:        inputIDs[i],
+				CoveredFields:  CoveredFields{WholeTransaction: true},
+				PublicKeyIndex: key,
+			})
+			if key >= uint64(len(sks)) {
+				continue
+			}
+			sigHash := txn.SigHash(i)
+			sig, err := crypto.SignBytes(sigHash[:], sks[key])
+			if err != nil {
+				t.Fatal(err)
+			}
+			txn.Signatures[i].Signature = sig
+		}
+		return txn
+	}
+
+	if err := s.ValidTransaction(signedTxn([]uint64{0, 2}, []OutputID{id, id})); err != nil {
+		t.Error("valid 2-of-3 spend was rejected:", err)
+	}
+	if s.ValidTransaction(signedTxn([]uint64{0}, []OutputID{id})) == nil {
+		t.Error("accepted a 2-of-3 spend with one signature")
+	}
+	if s.ValidTransaction(signedTxn([]uint64{0, 1}, []OutputID{id, {2}})) == nil {
+		t.Error("accepted a signature for an unknown input")
+	}
+	if s.ValidTransaction(signedTxn([]uint64{0, 3}, []OutputID{id, id})) == nil {
+		t.Error("accepted a signature with an out of range public key index")
+	}
+	if s.ValidTransaction(signedTxn([]uint64{1, 1}, []OutputID{id, id})) == nil {
+		t.Error("accepted two signatures from the same key")
+	}
+}
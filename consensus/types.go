@@ -1,5 +1,7 @@
 package consensus
 
+//go:generate go run gen.go
+
 import (
 	"bytes"
 	"errors"
@@ -240,6 +242,11 @@ func (t Transaction) SigHash(i int) hash.Hash {
 	return hash.HashBytes(signedData)
 }
 
+// ID returns the id of a transaction, which is the hash of the transaction.
+func (t Transaction) ID() TransactionID {
+	return TransactionID(hash.HashObject(t))
+}
+
 // Transaction.OuptutID() takes the index of the output and returns the
 // output's ID.
 func (t Transaction) OutputID(index int) OutputID {
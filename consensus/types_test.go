@@ -1,11 +1,18 @@
 package consensus
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"math"
 	"math/big"
+	"reflect"
+	"strconv"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/hash"
 )
@@ -76,3 +83,198 @@ func TestTypeMarshalling(t *testing.T) {
 		t.Error("MerkleRoot marshalling problems.")
 	}
 }
+
+// testBlock returns a block with random values in every field, containing a
+// transaction with every type of object.
+func testBlock(t *testing.T) Block {
+	pk := new([32]byte)
+	pk[0] = 1
+	sig := new([64]byte)
+	sig[0] = 2
+	sc := SpendConditions{
+		TimeLock:      BlockHeight(randomUint64(t)),
+		NumSignatures: 1,
+		PublicKeys:    []crypto.PublicKey{pk, nil},
+	}
+	txn := Transaction{
+		Inputs:    []Input{{OutputID(randomHash(t)), sc}},
+		MinerFees: []Currency{Currency(randomUint64(t))},
+		Outputs:   []Output{{Currency(randomUint64(t)), sc.CoinAddress()}},
+		FileContracts: []FileContract{{
+			ContractFund:       Currency(randomUint64(t)),
+			FileMerkleRoot:     randomHash(t),
+			FileSize:           randomUint64(t),
+			Start:              1,
+			End:                2,
+			ChallengeWindow:    3,
+			Tolerance:          4,
+			ValidProofPayout:   5,
+			ValidProofAddress:  CoinAddress(randomHash(t)),
+			MissedProofPayout:  6,
+			MissedProofAddress: CoinAddress(randomHash(t)),
+		}},
+		StorageProofs: []StorageProof{{
+			ContractID:  ContractID(randomHash(t)),
+			WindowIndex: 7,
+			HashSet:     []hash.Hash{randomHash(t), randomHash(t)},
+		}},
+		ArbitraryData: []string{"foo", ""},
+		Signatures: []TransactionSignature{{
+			InputID:       OutputID(randomHash(t)),
+			TimeLock:      8,
+			CoveredFields: CoveredFields{WholeTransaction: true, Inputs: []uint64{0}},
+			Signature:     sig,
+		}, {}},
+	}
+	return Block{
+		ParentBlockID: BlockID(randomHash(t)),
+		Timestamp:     Timestamp(randomInt64(t)),
+		Nonce:         randomUint64(t),
+		MinerAddress:  CoinAddress(randomHash(t)),
+		MerkleRoot:    randomHash(t),
+		Transactions:  []Transaction{txn, {}},
+	}
+
+}
+
+// TestGeneratedMarshalling checks that the generated marshalling methods
+// produce exactly the same bytes as the reflective encoding, and that the
+// generated unmarshalling methods are the inverse of both.
+func TestGeneratedMarshalling(t *testing.T) {
+	block := testBlock(t)
+	txn := block.Transactions[0]
+	sc := txn.Inputs[0].SpendConditions
+
+	objects := []interface{}{block, txn, sc}
+	for _, obj := range objects {
+		generated := encoding.Marshal(obj)
+		reflected := encoding.MarshalReflect(obj)
+		if !bytes.Equal(generated, reflected) {
+			t.Fatalf("generated encoding of %T does not match reflective encoding", obj)
+		}
+
+		fromGenerated := reflect.New(reflect.TypeOf(obj))
+		if err := encoding.Unmarshal(generated, fromGenerated.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		fromReflected := reflect.New(reflect.TypeOf(obj))
+		if err := encoding.UnmarshalReflect(generated, fromReflected.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromGenerated.Interface(), fromReflected.Interface()) {
+			t.Errorf("generated decoding of %T does not match reflective decoding", obj)
+		}
+		if !bytes.Equal(encoding.Marshal(fromGenerated.Elem().Interface()), generated) {
+			t.Errorf("%T did not survive a round trip", obj)
+		}
+	}
+
+	// truncated input should be rejected by the generated unmarshaller, and
+	// the error should identify the value that was truncated
+	b := encoding.Marshal(block)
+	var de *encoding.DecodeError
+	err := encoding.Unmarshal(b[:len(b)-1], new(Block))
+	if !errors.As(err, &de) || !errors.Is(err, encoding.ErrUnexpectedEnd) {
+		t.Fatal("expected DecodeError for truncated input, got", err)
+	} else if de.Offset != len(b)-8 || de.Path != "consensus.Block.Transactions[1].Signatures" {
+		t.Errorf("truncation reported at %v (offset %d)", de.Path, de.Offset)
+	}
+
+	// the generated unmarshaller should respect the allocation budget
+	if err := encoding.UnmarshalLimit(b, new(Block), 1<<16); err != nil {
+		t.Fatal(err)
+	}
+	err = encoding.UnmarshalLimit(b, new(Block), 64)
+	if !errors.As(err, &de) || !errors.Is(err, encoding.ErrAllocLimit) {
+		t.Fatal("expected ErrAllocLimit, got", err)
+	} else if de.Offset != 120 || de.Path != "consensus.Block.Transactions" {
+		t.Errorf("allocation limit reported at %v (offset %d)", de.Path, de.Offset)
+	}
+}
+
+// TestJSONMarshalling checks that blocks survive a round trip through JSON,
+// and that hashes and currencies are encoded as strings.
+func TestJSONMarshalling(t *testing.T) {
+	block := testBlock(t)
+	js, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Block
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoding.Marshal(decoded), encoding.Marshal(block)) {
+		t.Fatal("block did not survive a round trip through JSON")
+	}
+
+	// check the representation of individual fields
+	var fields struct {
+		ParentBlockID string
+		Transactions  []struct {
+			MinerFees []string
+			Inputs    []struct {
+				SpendConditions struct {
+					PublicKeys []*string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(js, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields.ParentBlockID != hex.EncodeToString(block.ParentBlockID[:]) {
+		t.Error("BlockID was not encoded as hex:", fields.ParentBlockID)
+	}
+	if fields.Transactions[0].MinerFees[0] != strconv.FormatUint(uint64(block.Transactions[0].MinerFees[0]), 10) {
+		t.Error("Currency was not encoded as a decimal string:", fields.Transactions[0].MinerFees[0])
+	}
+	pks := fields.Transactions[0].Inputs[0].SpendConditions.PublicKeys
+	if len(pks) != 2 || pks[0] == nil || len(*pks[0]) != 64 || pks[1] != nil {
+		t.Error("public keys were encoded incorrectly:", pks)
+	}
+
+	// malformed values should be rejected
+	var id BlockID
+	if json.Unmarshal([]byte(`"abcd"`), &id) == nil {
+		t.Error("decoded short BlockID")
+	}
+	var c Currency
+	if json.Unmarshal([]byte(`12`), &c) == nil || json.Unmarshal([]byte(`"-12"`), &c) == nil {
+		t.Error("decoded malformed Currency")
+	}
+}
+
+// TestCoinAddressString checks that addresses survive a round trip through
+// their string form, and that mistyped addresses are rejected.
+func TestCoinAddressString(t *testing.T) {
+	var ca CoinAddress
+	rand.Read(ca[:])
+	s := ca.String()
+	if len(s) != AddressStringLen {
+		t.Fatal("wrong address length:", len(s))
+	}
+	parsed, err := ParseCoinAddress(s)
+	if err != nil {
+		t.Fatal(err)
+	} else if parsed != ca {
+		t.Fatal("address did not survive a round trip")
+	}
+
+	// change a single character
+	typo := []byte(s)
+	if typo[10] == '0' {
+		typo[10] = '1'
+	} else {
+		typo[10] = '0'
+	}
+	if _, err := ParseCoinAddress(string(typo)); err != ErrAddressChecksum {
+		t.Error("expected checksum error, got", err)
+	}
+	if _, err := ParseCoinAddress(s[:64]); err != ErrAddressLength {
+		t.Error("expected length error, got", err)
+	}
+	if _, err := ParseCoinAddress("zz" + s[2:]); err == nil {
+		t.Error("decoded address containing non-hex characters")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/rand"
+	"io/ioutil"
 	"testing"
 )
 
@@ -117,3 +118,99 @@ func TestEntropy(t *testing.T) {
 		t.Error("supposedly high entropy ciphertext has been compressed!")
 	}
 }
+
+// TestStreamEncryption encrypts and decrypts streams of various sizes, and
+// checks that modified, reordered and truncated streams are rejected.
+func TestStreamEncryption(t *testing.T) {
+	key, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(plaintext []byte) []byte {
+		var buf bytes.Buffer
+		w, err := NewEncryptWriter(key, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// write in uneven pieces
+		for p := plaintext; len(p) > 0; {
+			n := 1000
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := w.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	decrypt := func(key EncryptionKey, ciphertext []byte) ([]byte, error) {
+		r, err := NewDecryptReader(key, bytes.NewReader(ciphertext))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(r)
+	}
+
+	for _, size := range []int{0, 1, StreamChunkSize, StreamChunkSize + 1, 3*StreamChunkSize + 5} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		ciphertext := encrypt(plaintext)
+		if int64(len(ciphertext)) != EncryptedSize(int64(size)) {
+			t.Error("EncryptedSize is wrong for", size, "bytes:", len(ciphertext))
+		}
+		decrypted, err := decrypt(key, ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plaintext, decrypted) {
+			t.Fatal("decrypted stream does not match plaintext for", size, "bytes")
+		}
+	}
+
+	plaintext := make([]byte, 3*StreamChunkSize+5)
+	rand.Read(plaintext)
+	ciphertext := encrypt(plaintext)
+	chunk := func(i int) []byte {
+		start := streamHeaderSize + i*sealedChunkSize
+		return ciphertext[start : start+sealedChunkSize]
+	}
+
+	// modified
+	modified := append([]byte(nil), ciphertext...)
+	modified[len(modified)/2]++
+	if _, err := decrypt(key, modified); err != ErrStreamCorrupted {
+		t.Error("expected ErrStreamCorrupted for modified stream, got", err)
+	}
+
+	// reordered
+	var reordered []byte
+	reordered = append(reordered, ciphertext[:streamHeaderSize]...)
+	reordered = append(reordered, chunk(1)...)
+	reordered = append(reordered, chunk(0)...)
+	reordered = append(reordered, ciphertext[streamHeaderSize+2*sealedChunkSize:]...)
+	if _, err := decrypt(key, reordered); err != ErrStreamCorrupted {
+		t.Error("expected ErrStreamCorrupted for reordered stream, got", err)
+	}
+
+	// truncated at a chunk boundary, and within a chunk
+	if _, err := decrypt(key, ciphertext[:streamHeaderSize+2*sealedChunkSize]); err != ErrStreamTruncated {
+		t.Error("expected ErrStreamTruncated for truncated stream, got", err)
+	}
+	if _, err := decrypt(key, ciphertext[:streamHeaderSize+2*sealedChunkSize+100]); err != ErrStreamCorrupted {
+		t.Error("expected ErrStreamCorrupted for truncated stream, got", err)
+	}
+
+	// wrong key
+	key2, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decrypt(key2, ciphertext); err != ErrStreamCorrupted {
+		t.Error("expected ErrStreamCorrupted for wrong key, got", err)
+	}
+}
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// SaltSize is the size of the random salt used when deriving a key from a
+	// password.
+	SaltSize = 16
+
+	// The default cost of deriving a key, which takes a few hundred
+	// milliseconds on a typical machine. The memory cost makes brute force
+	// attacks on dedicated hardware expensive.
+	DefaultKDFTime    = 3
+	DefaultKDFMemory  = 64 * 1024 // in KiB
+	DefaultKDFThreads = 4
+
+	// The largest costs accepted by CheckKDFParams. They are far above the
+	// defaults, but prevent a corrupted or malicious file from making key
+	// derivation run or allocate without bound.
+	MaxKDFTime    = 64
+	MaxKDFMemory  = 4 * 1024 * 1024 // in KiB
+	MaxKDFThreads = 64
+)
+
+var (
+	ErrInvalidKDFParams = errors.New("key derivation parameters are out of range")
+)
+
+// KDFParams holds the parameters used to derive a key from a password. They
+// are stored alongside the data encrypted with the key, so that the cost of
+// derivation can be raised in the future without breaking existing files.
+type KDFParams struct {
+	Salt    [SaltSize]byte
+	Time    uint32
+	Memory  uint32 // in KiB
+	Threads uint8
+}
+
+// NewKDFParams returns the default KDF parameters with a random salt.
+func NewKDFParams() (p KDFParams, err error) {
+	p = KDFParams{
+		Time:    DefaultKDFTime,
+		Memory:  DefaultKDFMemory,
+		Threads: DefaultKDFThreads,
+	}
+	_, err = rand.Read(p.Salt[:])
+	return
+}
+
+// CheckKDFParams returns ErrInvalidKDFParams if p cannot be used by DeriveKey,
+// or would make it unreasonably expensive. Parameters read from a file should
+// be checked before they are used.
+func CheckKDFParams(p KDFParams) error {
+	if p.Time == 0 || p.Time > MaxKDFTime ||
+		p.Threads == 0 || p.Threads > MaxKDFThreads ||
+		p.Memory < 8*uint32(p.Threads) || p.Memory > MaxKDFMemory {
+		return ErrInvalidKDFParams
+	}
+	return nil
+}
+
+// DeriveKey derives an encryption key from a password using Argon2id, a
+// memory-hard password hashing function.
+func DeriveKey(password []byte, p KDFParams) (key EncryptionKey) {
+	copy(key[:], argon2.IDKey(password, p.Salt[:], p.Time, p.Memory, p.Threads, KeySize))
+	return
+}
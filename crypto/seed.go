@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"strings"
+
+	"github.com/agl/ed25519"
+
+	"github.com/NebulousLabs/Sia/encoding"
+)
+
+const (
+	// SeedSize is the size of a Seed, which is enough entropy to make
+	// guessing a seed infeasible.
+	SeedSize = 16
+
+	// SeedChecksumSize is the number of bytes of checksum that are appended
+	// to a seed in its mnemonic form.
+	SeedChecksumSize = 2
+
+	// MnemonicLength is the number of words in a mnemonic.
+	MnemonicLength = SeedSize + SeedChecksumSize
+)
+
+var (
+	ErrMnemonicLength   = errors.New("mnemonic has the wrong number of words")
+	ErrMnemonicWord     = errors.New("mnemonic contains an unrecognized word")
+	ErrMnemonicChecksum = errors.New("mnemonic has an invalid checksum")
+)
+
+// A Seed is a secret from which any number of signature keys can be derived.
+// Backing up the seed backs up every key derived from it.
+type Seed [SeedSize]byte
+
+// GenerateSeed returns a random Seed.
+func GenerateSeed() (s Seed, err error) {
+	_, err = rand.Read(s[:])
+	return
+}
+
+// DeriveSignatureKeys derives the keypair at the given index from a seed. The
+// same seed and index always produce the same keys.
+func DeriveSignatureKeys(s Seed, index uint64) (sk SecretKey, pk PublicKey) {
+	entropy := sha256.Sum256(append(s[:], encoding.EncUint64(index)...))
+	pk, sk, err := ed25519.GenerateKey(bytes.NewReader(entropy[:]))
+	if err != nil {
+		// GenerateKey only fails if reading from its input fails.
+		panic(err)
+	}
+	return
+}
+
+// checksum returns the checksum of a seed, which is the first
+// SeedChecksumSize bytes of its hash.
+func (s Seed) checksum() []byte {
+	h := sha256.Sum256(s[:])
+	return h[:SeedChecksumSize]
+}
+
+// Mnemonic returns the seed as a list of words, followed by a checksum, so
+// that it can be written down and typed back in.
+func (s Seed) Mnemonic() string {
+	words := make([]string, 0, MnemonicLength)
+	for _, b := range append(s[:], s.checksum()...) {
+		words = append(words, mnemonicWords[b])
+	}
+	return strings.Join(words, " ")
+}
+
+// ParseMnemonic decodes a mnemonic created by Seed.Mnemonic, verifying its
+// checksum. Words are matched case-insensitively, and words longer than four
+// letters may be abbreviated to their first four letters.
+func ParseMnemonic(mnemonic string) (s Seed, err error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) != MnemonicLength {
+		err = ErrMnemonicLength
+		return
+	}
+	b := make([]byte, MnemonicLength)
+	for i, word := range words {
+		index := -1
+		for j, w := range mnemonicWords {
+			if w == word || (len(word) == 4 && strings.HasPrefix(w, word)) {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			err = ErrMnemonicWord
+			return
+		}
+		b[i] = byte(index)
+	}
+	copy(s[:], b)
+	if !bytes.Equal(s.checksum(), b[SeedSize:]) {
+		err = ErrMnemonicChecksum
+		return
+	}
+	return
+}
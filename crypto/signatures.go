@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 
 	"github.com/agl/ed25519"
+
+	"github.com/NebulousLabs/Sia/encoding"
 )
 
 // One thing that worries me about this file is that the library returns a
@@ -17,6 +19,16 @@ type (
 	Signature *[ed25519.SignatureSize]byte
 )
 
+// PublicKey and Signature are pointer types, and so cannot have methods of
+// their own. JSONPublicKey and JSONSignature share their memory layout, and
+// are encoded in JSON as hex strings. Types that contain a PublicKey or a
+// Signature convert it in their MarshalJSON and UnmarshalJSON methods; a nil
+// key or signature is encoded as null.
+type (
+	JSONPublicKey [ed25519.PublicKeySize]byte
+	JSONSignature [ed25519.SignatureSize]byte
+)
+
 // GenerateKeyPair creates a public-secret keypair that can be used to sign and
 // verify messages.
 func GenerateSignatureKeys() (sk SecretKey, pk PublicKey, err error) {
@@ -37,3 +49,43 @@ func SignBytes(data []byte, sk SecretKey) (sig Signature, err error) {
 func VerifyBytes(data []byte, pk PublicKey, sig Signature) bool {
 	return ed25519.Verify(pk, data, sig)
 }
+
+// ToJSONPublicKey converts a PublicKey to a *JSONPublicKey without copying.
+func ToJSONPublicKey(pk PublicKey) *JSONPublicKey {
+	return (*JSONPublicKey)((*[ed25519.PublicKeySize]byte)(pk))
+}
+
+// PublicKey converts a *JSONPublicKey to a PublicKey without copying.
+func (pk *JSONPublicKey) PublicKey() PublicKey {
+	return PublicKey((*[ed25519.PublicKeySize]byte)(pk))
+}
+
+// MarshalJSON marshals a public key as a hex string.
+func (pk JSONPublicKey) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(pk[:])
+}
+
+// UnmarshalJSON decodes a public key from a hex string.
+func (pk *JSONPublicKey) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, pk[:])
+}
+
+// ToJSONSignature converts a Signature to a *JSONSignature without copying.
+func ToJSONSignature(sig Signature) *JSONSignature {
+	return (*JSONSignature)((*[ed25519.SignatureSize]byte)(sig))
+}
+
+// Signature converts a *JSONSignature to a Signature without copying.
+func (sig *JSONSignature) Signature() Signature {
+	return Signature((*[ed25519.SignatureSize]byte)(sig))
+}
+
+// MarshalJSON marshals a signature as a hex string.
+func (sig JSONSignature) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(sig[:])
+}
+
+// UnmarshalJSON decodes a signature from a hex string.
+func (sig *JSONSignature) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, sig[:])
+}
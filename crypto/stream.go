@@ -0,0 +1,215 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Streams are encrypted in chunks, each of which is sealed separately with
+// XChaCha20-Poly1305, so that arbitrarily large files can be encrypted and
+// decrypted without holding them in memory, and so that any modification of
+// the ciphertext is detected.
+//
+// An encrypted stream begins with a header holding a version byte and a
+// random nonce prefix. The nonce of each chunk is the prefix followed by the
+// index of the chunk, so chunks cannot be reordered. The final chunk is always
+// shorter than a full chunk (it may hold no data at all), and its nonce has a
+// flag set, so a stream that has been truncated is detected as well.
+
+const (
+	// StreamChunkSize is the amount of plaintext in each chunk of an
+	// encrypted stream.
+	StreamChunkSize = 1 << 16
+
+	// StreamOverhead is the number of bytes that are added to each chunk of
+	// an encrypted stream.
+	StreamOverhead = chacha20poly1305.Overhead
+
+	streamVersion    = 1
+	streamPrefixSize = 16
+	streamHeaderSize = 1 + streamPrefixSize
+	sealedChunkSize  = StreamChunkSize + StreamOverhead
+)
+
+var (
+	ErrStreamTruncated = errors.New("encrypted stream was truncated")
+	ErrStreamCorrupted = errors.New("encrypted stream has been modified or was encrypted with a different key")
+	ErrStreamVersion   = errors.New("encrypted stream has an unrecognized version")
+)
+
+// EncryptedSize returns the size of the encrypted stream of a plaintext with
+// the given size.
+func EncryptedSize(plaintextSize int64) int64 {
+	return streamHeaderSize + plaintextSize + (plaintextSize/StreamChunkSize+1)*StreamOverhead
+}
+
+// streamCipher seals or opens the chunks of a stream in order.
+type streamCipher struct {
+	aead  cipher.AEAD
+	nonce [chacha20poly1305.NonceSizeX]byte
+	index uint64
+}
+
+// nextNonce returns the nonce of the next chunk.
+func (sc *streamCipher) nextNonce(final bool) []byte {
+	for i := 0; i < 8; i++ {
+		sc.nonce[streamPrefixSize+i] = byte(sc.index >> (8 * uint(i)))
+	}
+	if final {
+		sc.nonce[len(sc.nonce)-1] |= 0x80
+	}
+	sc.index++
+	return sc.nonce[:]
+}
+
+// An encryptWriter encrypts the data written to it.
+type encryptWriter struct {
+	w      io.Writer
+	sc     streamCipher
+	buf    []byte
+	closed bool
+}
+
+// NewEncryptWriter returns a WriteCloser that encrypts data with key and
+// writes it to w. Close must be called once all of the data has been written,
+// to write the final chunk; it does not close w.
+func NewEncryptWriter(key EncryptionKey, w io.Writer) (io.WriteCloser, error) {
+	aead, err := chacha20poly1305.NewX(key[:])
+	if err != nil {
+		return nil, err
+	}
+	ew := &encryptWriter{
+		w:   w,
+		sc:  streamCipher{aead: aead},
+		buf: make([]byte, 0, sealedChunkSize),
+	}
+
+	// Write the header.
+	header := make([]byte, streamHeaderSize)
+	header[0] = streamVersion
+	if _, err = rand.Read(header[1:]); err != nil {
+		return nil, err
+	}
+	copy(ew.sc.nonce[:], header[1:])
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+// seal encrypts the buffered plaintext as a chunk and writes it.
+func (ew *encryptWriter) seal(final bool) error {
+	sealed := ew.sc.aead.Seal(ew.buf[:0], ew.sc.nextNonce(final), ew.buf, nil)
+	ew.buf = ew.buf[:0]
+	_, err := ew.w.Write(sealed)
+	return err
+}
+
+// Write implements io.Writer.
+func (ew *encryptWriter) Write(p []byte) (n int, err error) {
+	if ew.closed {
+		return 0, errors.New("write to closed stream")
+	}
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, because the
+		// final chunk must be shorter than a full chunk.
+		if len(ew.buf) == StreamChunkSize {
+			if err = ew.seal(false); err != nil {
+				return
+			}
+		}
+		fill := StreamChunkSize - len(ew.buf)
+		if fill > len(p) {
+			fill = len(p)
+		}
+		ew.buf = append(ew.buf, p[:fill]...)
+		p = p[fill:]
+		n += fill
+	}
+	return
+}
+
+// Close writes the final chunk of the stream.
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	if len(ew.buf) == StreamChunkSize {
+		if err := ew.seal(false); err != nil {
+			return err
+		}
+	}
+	return ew.seal(true)
+}
+
+// A decryptReader decrypts an encrypted stream.
+type decryptReader struct {
+	r     io.Reader
+	sc    streamCipher
+	buf   []byte // sealed chunk
+	plain []byte // unread plaintext of the current chunk
+	done  bool   // the final chunk has been read
+}
+
+// NewDecryptReader returns a Reader that decrypts the stream r, which was
+// encrypted with key by an encryptWriter. Read returns an error if the stream
+// has been modified, reordered, or truncated. Data is only returned once the
+// chunk containing it has been authenticated.
+func NewDecryptReader(key EncryptionKey, r io.Reader) (io.Reader, error) {
+	aead, err := chacha20poly1305.NewX(key[:])
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, streamHeaderSize)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return nil, ErrStreamTruncated
+	}
+	if header[0] != streamVersion {
+		return nil, ErrStreamVersion
+	}
+	dr := &decryptReader{
+		r:   r,
+		sc:  streamCipher{aead: aead},
+		buf: make([]byte, sealedChunkSize),
+	}
+	copy(dr.sc.nonce[:], header[1:])
+	return dr, nil
+}
+
+// Read implements io.Reader.
+func (dr *decryptReader) Read(p []byte) (n int, err error) {
+	for len(dr.plain) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err = dr.next(); err != nil {
+			return
+		}
+	}
+	n = copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return
+}
+
+// next reads and decrypts the next chunk of the stream.
+func (dr *decryptReader) next() error {
+	n, err := io.ReadFull(dr.r, dr.buf)
+	final := err == io.ErrUnexpectedEOF
+	if err == io.EOF || (final && n < StreamOverhead) {
+		return ErrStreamTruncated
+	} else if err != nil && !final {
+		return err
+	}
+
+	dr.plain, err = dr.sc.aead.Open(dr.buf[:0], dr.sc.nextNonce(final), dr.buf[:n], nil)
+	if err != nil {
+		return ErrStreamCorrupted
+	}
+	dr.done = final
+	return nil
+}
@@ -0,0 +1,45 @@
+package crypto
+
+// mnemonicWords is the list of words used to encode a Seed as a mnemonic. Each
+// word encodes one byte. The words are sorted, and no two words share their
+// first four letters, so a word can be identified by its first four letters.
+// The only shorter word, "fox", is identified by the whole word.
+var mnemonicWords = [256]string{
+	"acid", "acorn", "actor", "adult", "alarm", "album", "amber",
+	"angel", "ankle", "apple", "april", "arena", "armor", "arrow",
+	"aspen", "atlas", "attic", "audio", "autumn", "avocado", "badge",
+	"bagel", "baker", "bamboo", "banjo", "barn", "basket", "beach",
+	"beaver", "bench", "bicycle", "blanket", "blossom", "board",
+	"bottle", "bracket", "branch", "bread", "breeze", "brick", "bridge",
+	"bronze", "brush", "bubble", "bucket", "buffalo", "bundle", "butter",
+	"cabin", "canal", "candle", "canoe", "canyon", "carbon", "carpet",
+	"cattle", "cedar", "cement", "cherry", "chess", "chimney", "cider",
+	"circle", "clock", "cloud", "clover", "cobalt", "coconut", "comet",
+	"copper", "coral", "cotton", "crater", "cricket", "crystal",
+	"cushion", "dagger", "daisy", "dance", "denim", "desert", "diamond",
+	"dinner", "dolphin", "donkey", "dragon", "drum", "eagle", "earth",
+	"echo", "eclipse", "elbow", "elder", "empire", "engine", "feather",
+	"fence", "ferry", "fiber", "fiddle", "figure", "flame", "forest",
+	"fossil", "fox", "frost", "fruit", "galaxy", "garden", "garlic",
+	"gazelle", "giant", "ginger", "glacier", "globe", "goat", "gold",
+	"gorilla", "grape", "gravel", "guitar", "habit", "hammer", "harbor",
+	"hermit", "hockey", "honey", "horizon", "hotel", "hunter", "igloo",
+	"impact", "index", "iron", "island", "ivory", "jacket", "jaguar",
+	"jelly", "jewel", "kernel", "kettle", "kingdom", "kitten", "koala",
+	"label", "ladder", "lagoon", "lamp", "lantern", "laser", "lemon",
+	"lentil", "lettuce", "lizard", "locket", "lumber", "lunar", "magnet",
+	"mango", "meadow", "meteor", "mirror", "monkey", "mosaic", "motor",
+	"muffin", "mustard", "napkin", "nectar", "needle", "nickel", "north",
+	"nutmeg", "oasis", "ocean", "olive", "onion", "orange", "orbit",
+	"otter", "oven", "oxygen", "palace", "panda", "paper", "parrot",
+	"peach", "pebble", "pencil", "pepper", "piano", "pigeon", "pillow",
+	"pilot", "pirate", "planet", "plum", "pocket", "polar", "pony",
+	"potato", "prism", "puzzle", "quartz", "quilt", "rabbit", "radar",
+	"radio", "raven", "ribbon", "river", "robot", "rocket", "saddle",
+	"salmon", "sandal", "satin", "scarf", "shadow", "shell", "silver",
+	"sloth", "socket", "spoon", "squid", "statue", "summit", "sunset",
+	"swan", "tablet", "tiger", "timber", "tomato", "torch", "tractor",
+	"tulip", "tunnel", "turtle", "umbrella", "unicorn", "valley",
+	"velvet", "violin", "wagon", "walnut", "whale", "willow", "window",
+	"winter", "wizard", "wolf", "yacht", "yogurt", "zebra", "zipper",
+}
@@ -1,6 +1,10 @@
 package encoding
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
 	"testing"
 )
 
@@ -68,3 +72,219 @@ func TestEncoding(t *testing.T) {
 		}
 	}
 }
+
+// versioned structs, representing two versions of the same type
+type (
+	versioned1 struct {
+		A uint64
+		B string
+	}
+	versioned2 struct {
+		A uint64
+		B string
+		C []uint64 `sia:"v2"`
+	}
+)
+
+func (versioned1) SiaVersion() uint64 { return 1 }
+func (versioned2) SiaVersion() uint64 { return 2 }
+
+// TestMapEncoding checks that maps are encoded deterministically, and that
+// non-canonical encodings are rejected.
+func TestMapEncoding(t *testing.T) {
+	m := map[string]uint64{"foo": 1, "bar": 2, "baz": 3, "": 4}
+	b := Marshal(m)
+	for i := 0; i < 10; i++ {
+		if !bytes.Equal(Marshal(m), b) {
+			t.Fatal("map encoding is not deterministic")
+		}
+	}
+	var m2 map[string]uint64
+	if err := Unmarshal(b, &m2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m2) {
+		t.Fatal("map did not survive a round trip:", m2)
+	}
+
+	// swapping the first two pairs should make the encoding invalid
+	pair1 := Marshal("")
+	pair1 = append(pair1, EncUint64(4)...)
+	pair2 := append(Marshal("bar"), EncUint64(2)...)
+	swapped := append(EncUint64(4), pair2...)
+	swapped = append(swapped, pair1...)
+	swapped = append(swapped, b[8+len(pair1)+len(pair2):]...)
+	if err := Unmarshal(swapped, &m2); err == nil {
+		t.Error("decoded map with unsorted keys")
+	}
+	if err := NewDecoder(bytes.NewReader(swapped)).Decode(&m2); err == nil {
+		t.Error("Decoder decoded map with unsorted keys")
+	}
+
+	// maps should also be decodable from a stream
+	m2 = nil
+	if err := NewDecoder(bytes.NewReader(b)).Decode(&m2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m2) {
+		t.Fatal("map did not survive a round trip through a Decoder:", m2)
+	}
+}
+
+// TestVersionedStruct checks that versioned structs can be decoded by both
+// older and newer versions of the struct.
+func TestVersionedStruct(t *testing.T) {
+	v1 := versioned1{A: 1, B: "foo"}
+	v2 := versioned2{A: 2, B: "bar", C: []uint64{3, 4}}
+
+	// an old encoding leaves new fields unchanged
+	var newFromOld versioned2
+	if err := Unmarshal(Marshal(v1), &newFromOld); err != nil {
+		t.Fatal(err)
+	}
+	if newFromOld.A != v1.A || newFromOld.B != v1.B || newFromOld.C != nil {
+		t.Error("old encoding was decoded incorrectly:", newFromOld)
+	}
+
+	// a new encoding has its unknown fields skipped, even when followed by
+	// other data
+	var oldFromNew versioned1
+	var trailer uint64
+	b := MarshalAll(v2, uint64(5))
+	for _, dec := range []func(interface{}) error{
+		func(v interface{}) error { return Unmarshal(b[:len(b)-8], v) },
+		func(v interface{}) error { return NewDecoder(bytes.NewReader(b)).DecodeAll(v, &trailer) },
+	} {
+		if err := dec(&oldFromNew); err != nil {
+			t.Fatal(err)
+		}
+		if oldFromNew.A != v2.A || oldFromNew.B != v2.B {
+			t.Error("new encoding was decoded incorrectly:", oldFromNew)
+		}
+	}
+	if trailer != 5 {
+		t.Error("Decoder did not skip unknown fields")
+	}
+
+	// the same version must consume all of its fields
+	b = Marshal(v1)
+	b[8]++ // increase the length prefix
+	b = append(b, 0)
+	if err := Unmarshal(b, &oldFromNew); !errors.Is(err, ErrTrailingBytes) {
+		t.Error("expected ErrTrailingBytes, got", err)
+	}
+}
+
+// TestUnmarshalLimits checks that malformed input is rejected with a
+// DecodeError that identifies where decoding failed.
+func TestUnmarshalLimits(t *testing.T) {
+	checkErr := func(err error, cause error, offset int, path string) {
+		t.Helper()
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Fatal("expected DecodeError, got", err)
+		}
+		if cause != nil && !errors.Is(err, cause) {
+			t.Error("expected", cause, "got", de.Err)
+		}
+		if de.Offset != offset || de.Path != path {
+			t.Errorf("expected error at %v (offset %d), got %v (offset %d)", path, offset, de.Path, de.Offset)
+		}
+	}
+
+	// a length prefix that exceeds the input should be rejected before
+	// anything is allocated
+	b := append(EncInt64(1), EncUint64(1<<62)...)
+	checkErr(Unmarshal(b, new(test0)), nil, 16, "encoding.test0.S")
+	b = append(EncUint64(1<<40), make([]byte, 16)...)
+	checkErr(Unmarshal(b, new([]int32)), nil, 8, "[]int32")
+
+	// elements that occupy no input or memory must still be bounded, rather
+	// than overflowing the length or looping for each element
+	checkErr(Unmarshal(EncUint64(1<<63), new([]struct{})), nil, 8, "[]struct {}")
+	checkErr(Unmarshal(EncUint64(1<<40), new([]struct{})), nil, 8, "[]struct {}")
+	checkErr(Unmarshal(EncUint64(1<<30), new([][0]byte)), ErrAllocLimit, 8, "[][0]uint8")
+
+	// truncated input
+	b = Marshal(testStructs[1])
+	checkErr(Unmarshal(b[:len(b)-1], new(test1)), ErrUnexpectedEnd, len(b)-3, "encoding.test1.Ba")
+	b = Marshal(test4{&test0{1, "foo"}})
+	checkErr(Unmarshal(b[:5], new(test4)), ErrUnexpectedEnd, 1, "encoding.test4.P.I")
+
+	// trailing bytes
+	b = append(Marshal(testStructs[0]), 0)
+	checkErr(Unmarshal(b, new(test0)), ErrTrailingBytes, len(b)-1, "encoding.test0")
+
+	// the allocation budget should be enforced, even if the input is large
+	// enough to hold the object
+	b = Marshal(make([]byte, 100))
+	if err := UnmarshalLimit(b, new([]byte), 100); err != nil {
+		t.Error(err)
+	}
+	checkErr(UnmarshalLimit(b, new([]byte), 99), ErrAllocLimit, 8, "[]uint8")
+	b = Marshal(test4{&test0{1, "foo"}})
+	checkErr(UnmarshalLimit(b, new(test4), 8), ErrAllocLimit, 1, "encoding.test4.P")
+}
+
+// TestStream checks that an Encoder produces the same bytes as Marshal, and
+// that a Decoder can read a sequence of objects back from a stream.
+func TestStream(t *testing.T) {
+	// test3 contains an unexported embedded field, and test5 has a custom
+	// encoding, so neither can be decoded from a stream
+	objects := []interface{}{testStructs[0], testStructs[1], testStructs[2], testStructs[4]}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.EncodeAll(objects...); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), MarshalAll(objects...)) {
+		t.Fatal("Encoder output does not match Marshal")
+	}
+
+	dec := NewDecoder(&buf)
+	for _, obj := range objects {
+		v := reflect.New(reflect.TypeOf(obj))
+		if err := dec.Decode(v.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v.Elem().Interface(), obj) {
+			t.Errorf("decoded %v, expected %v", v.Elem().Interface(), obj)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Error("Decoder did not consume the entire stream")
+	}
+
+	// truncated input should produce a DecodeError, whose offset is relative
+	// to the start of the stream
+	b := MarshalAll(testStructs[0], testStructs[1])
+	dec = NewDecoder(bytes.NewReader(b[:len(b)-1]))
+	var de *DecodeError
+	if err := dec.Decode(new(test0)); err != nil {
+		t.Fatal(err)
+	} else if err := dec.Decode(new(test1)); !errors.As(err, &de) || !errors.Is(err, ErrUnexpectedEnd) {
+		t.Error("expected DecodeError for truncated input, got", err)
+	} else if de.Offset != len(b)-3 || de.Path != "encoding.test1.Ba" {
+		t.Errorf("expected error at encoding.test1.Ba (offset %d), got %v (offset %d)", len(b)-3, de.Path, de.Offset)
+	}
+	if err := NewDecoder(bytes.NewReader(nil)).Decode(new(test0)); err != io.EOF {
+		t.Error("expected io.EOF for empty stream, got", err)
+	}
+
+	// the allocation budget applies to streams as well
+	if err := NewDecoder(bytes.NewReader(EncUint64(1 << 30))).Decode(new([]struct{})); !errors.Is(err, ErrAllocLimit) {
+		t.Error("expected ErrAllocLimit, got", err)
+	}
+
+	// a huge length prefix should fail without allocating the full length
+	b = EncUint64(1 << 60)
+	var bs []byte
+	if err := NewDecoder(bytes.NewReader(b)).Decode(&bs); err == nil {
+		t.Error("decoded bogus length prefix without error")
+	}
+
+	if err := NewDecoder(bytes.NewReader(Marshal(testStructs[5]))).Decode(new(test5)); err == nil {
+		t.Error("decoded SiaUnmarshaler from a stream")
+	}
+}
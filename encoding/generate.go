@@ -0,0 +1,448 @@
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+)
+
+var (
+	encodingPkgPath = reflect.TypeOf(SliceReader{}).PkgPath()
+	marshalerType   = reflect.TypeOf((*SiaMarshaler)(nil)).Elem()
+	readerType      = reflect.TypeOf((*SiaReader)(nil)).Elem()
+)
+
+// A generator writes the source of generated marshalers for a single package.
+type generator struct {
+	pkgPath string
+	types   map[reflect.Type]bool
+	imports map[string]bool
+	depth   int
+	temps   int
+	reader  string
+	buf     bytes.Buffer
+}
+
+// Generate writes a Go source file to w that declares MarshalSia, UnmarshalSia,
+// ReadSia and SiaGenerated methods for each of the provided types, which must be
+// structs declared in the package with import path pkgPath. The generated
+// methods produce exactly the same encoding as Marshal, without the overhead
+// of reflection.
+//
+// Generate is intended to be called from a small program that is run by go
+// generate; see consensus/gen.go for an example.
+func Generate(w io.Writer, pkgPath string, types ...interface{}) error {
+	g := &generator{
+		pkgPath: pkgPath,
+		types:   make(map[reflect.Type]bool),
+		imports: make(map[string]bool),
+		reader:  "r",
+	}
+	for _, v := range types {
+		t := reflect.TypeOf(v)
+		if t.Kind() != reflect.Struct || t.PkgPath() != pkgPath {
+			return fmt.Errorf("cannot generate marshalers for %v: must be a struct declared in %v", t, pkgPath)
+		}
+		g.types[t] = true
+	}
+	for _, v := range types {
+		if err := g.generateType(reflect.TypeOf(v)); err != nil {
+			return err
+		}
+	}
+
+	// write the header, followed by the generated methods
+	var src bytes.Buffer
+	fmt.Fprintln(&src, "// Code generated by encoding.Generate; DO NOT EDIT.")
+	fmt.Fprintln(&src)
+	fmt.Fprintln(&src, "package", path.Base(pkgPath))
+	fmt.Fprintln(&src)
+	var imports []string
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	fmt.Fprintln(&src, "import (")
+	for _, imp := range imports {
+		fmt.Fprintf(&src, "\t%q\n", imp)
+	}
+	fmt.Fprintln(&src, ")")
+	src.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// GenerateFile calls Generate, writing the output to the named file.
+func GenerateFile(filename, pkgPath string, types ...interface{}) error {
+	var buf bytes.Buffer
+	if err := Generate(&buf, pkgPath, types...); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0666)
+}
+
+// printf writes a line of generated code.
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format+"\n", args...)
+}
+
+// enc returns the qualifier for identifiers in the encoding package.
+func (g *generator) enc() string {
+	if g.pkgPath == encodingPkgPath {
+		return ""
+	}
+	g.imports[encodingPkgPath] = true
+	return "encoding."
+}
+
+// typeName returns the name of t as it should appear in the generated code.
+func (g *generator) typeName(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if t == reflect.TypeOf(byte(0)) {
+			return "byte", nil
+		} else if t.PkgPath() == "" || t.PkgPath() == g.pkgPath {
+			return t.Name(), nil
+		}
+		g.imports[t.PkgPath()] = true
+		return path.Base(t.PkgPath()) + "." + t.Name(), nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		elem, err := g.typeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		switch t.Kind() {
+		case reflect.Ptr:
+			return "*" + elem, nil
+		case reflect.Slice:
+			return "[]" + elem, nil
+		default:
+			return fmt.Sprintf("[%d]%s", t.Len(), elem), nil
+		}
+	default:
+		return "", errors.New("cannot generate marshalers for unnamed type " + t.String())
+	}
+}
+
+// convert returns expr converted to type t, unless t is unnamed or is the
+// basic type named by kind.
+func (g *generator) convert(t reflect.Type, kind, expr string) (string, error) {
+	if t.Name() == "" || (t.PkgPath() == "" && t.Name() == kind) {
+		return expr, nil
+	}
+	name, err := g.typeName(t)
+	if err != nil {
+		return "", err
+	}
+	return name + "(" + expr + ")", nil
+}
+
+// loopVar returns a fresh index variable for a loop at the current depth.
+func (g *generator) loopVar() string {
+	return fmt.Sprintf("i%d", g.depth)
+}
+
+// tempVar returns a variable name with the given prefix that has not been
+// used within the current method.
+func (g *generator) tempVar(prefix string) string {
+	g.temps++
+	return fmt.Sprintf("%s%d", prefix, g.temps)
+}
+
+// hasMarshaler reports whether t, or a pointer to t, implements SiaMarshaler.
+func hasMarshaler(t reflect.Type) bool {
+	return t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType)
+}
+
+// generateType writes the methods for the struct type t.
+func (g *generator) generateType(t reflect.Type) error {
+	name := t.Name()
+	g.printf("")
+	g.printf("// MarshalSia implements the encoding.SiaMarshaler interface.")
+	g.printf("func (x %s) MarshalSia() []byte {", name)
+	g.printf("return x.appendSia(nil)")
+	g.printf("}")
+	g.printf("")
+	g.printf("// UnmarshalSia implements the encoding.SiaUnmarshaler interface.")
+	g.printf("func (x *%s) UnmarshalSia(b []byte) int {", name)
+	g.printf("r := %sNewSliceReader(b)", g.enc())
+	g.printf("x.ReadSia(r)")
+	g.printf("return r.Consumed()")
+	g.printf("}")
+	g.printf("")
+	g.printf("// SiaGenerated implements the encoding.GeneratedMarshaler interface.")
+	g.printf("func (x %s) SiaGenerated() {}", name)
+	g.printf("")
+	g.printf("// appendSia appends the encoding of x to b.")
+	g.temps = 0
+	g.printf("func (x *%s) appendSia(b []byte) []byte {", name)
+	if err := g.marshalStruct("x", t); err != nil {
+		return err
+	}
+	g.printf("return b")
+	g.printf("}")
+	g.printf("")
+	g.printf("// ReadSia implements the encoding.SiaReader interface.")
+	g.temps = 0
+	g.printf("func (x *%s) ReadSia(r *%sSliceReader) {", name, g.enc())
+	if err := g.unmarshalStruct("x", t); err != nil {
+		return err
+	}
+	g.printf("}")
+	return nil
+}
+
+// marshalStruct writes code that appends each field of the struct expr,
+// preceded by a version header if the struct is versioned.
+func (g *generator) marshalStruct(expr string, t reflect.Type) error {
+	version, versioned := structVersion(t)
+	if !versioned {
+		return g.marshalFields(expr, t)
+	}
+	start := g.tempVar("s")
+	g.printf("%s := len(b)", start)
+	g.printf("b = %sAppendVersionHeader(b, %d)", g.enc(), version)
+	if err := g.marshalFields(expr, t); err != nil {
+		return err
+	}
+	g.printf("%sSetVersionLength(b, %s)", g.enc(), start)
+	return nil
+}
+
+// marshalFields writes code that appends each field of the struct expr.
+func (g *generator) marshalFields(expr string, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			return fmt.Errorf("cannot generate marshalers for %v: field %v is unexported", t, f.Name)
+		}
+		if err := g.marshal(expr+"."+f.Name, f.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalStruct writes code that decodes each field of the struct expr. If
+// the struct is versioned, its header is decoded first, and fields that are
+// newer than the encoding are skipped.
+func (g *generator) unmarshalStruct(expr string, t reflect.Type) error {
+	version, versioned := structVersion(t)
+	if !versioned {
+		return g.unmarshalFields(expr, t, "")
+	}
+	outer := g.reader
+	encVersion := g.tempVar("v")
+	g.reader = g.tempVar("r")
+	defer func() { g.reader = outer }()
+	g.printf("%s, %s := %s.ReadVersioned()", encVersion, g.reader, outer)
+	if err := g.unmarshalFields(expr, t, encVersion); err != nil {
+		return err
+	}
+	g.printf("%s.FinishVersioned(%s, %d)", g.reader, encVersion, version)
+	return nil
+}
+
+// unmarshalFields writes code that decodes each field of the struct expr. If
+// encVersion is set, it names the variable holding the version of the
+// encoding, and fields that are newer than the encoding are skipped.
+func (g *generator) unmarshalFields(expr string, t reflect.Type, encVersion string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			return fmt.Errorf("cannot generate marshalers for %v: field %v is unexported", t, f.Name)
+		}
+		var fv uint64 = 1
+		if encVersion != "" {
+			var err error
+			if fv, err = fieldVersion(f); err != nil {
+				return err
+			}
+		}
+		if fv > 1 {
+			g.printf("if %s >= %d {", encVersion, fv)
+		}
+		if err := g.unmarshal(expr+"."+f.Name, f.Type); err != nil {
+			return err
+		}
+		if fv > 1 {
+			g.printf("}")
+		}
+	}
+	return nil
+}
+
+// marshal writes code that appends the encoding of expr, which has type t, to
+// b. expr must be addressable.
+func (g *generator) marshal(expr string, t reflect.Type) error {
+	if g.types[t] {
+		g.printf("b = %s.appendSia(b)", expr)
+		return nil
+	} else if hasMarshaler(t) {
+		g.printf("b = append(b, %s.MarshalSia()...)", expr)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		g.printf("if %s == nil {", expr)
+		g.printf("b = append(b, 0)")
+		g.printf("} else {")
+		g.printf("b = append(b, 1)")
+		if err := g.marshal("(*"+expr+")", t.Elem()); err != nil {
+			return err
+		}
+		g.printf("}")
+	case reflect.Bool:
+		g.printf("if %s {", expr)
+		g.printf("b = append(b, 1)")
+		g.printf("} else {")
+		g.printf("b = append(b, 0)")
+		g.printf("}")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t != reflect.TypeOf(uint64(0)) {
+			expr = "uint64(" + expr + ")"
+		}
+		g.printf("b = %sAppendUint64(b, %s)", g.enc(), expr)
+	case reflect.String:
+		g.printf("b = %sAppendUint64(b, uint64(len(%s)))", g.enc(), expr)
+		g.printf("b = append(b, %s...)", expr)
+	case reflect.Slice:
+		g.printf("b = %sAppendUint64(b, uint64(len(%s)))", g.enc(), expr)
+		if t.Elem().Kind() == reflect.Uint8 {
+			g.printf("b = append(b, %s...)", expr)
+			return nil
+		}
+		return g.marshalElems(expr, t)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			g.printf("b = append(b, %s[:]...)", expr)
+			return nil
+		}
+		return g.marshalElems(expr, t)
+	case reflect.Struct:
+		return g.marshalStruct(expr, t)
+	default:
+		return errors.New("cannot generate marshalers for type " + t.String())
+	}
+	return nil
+}
+
+// marshalElems writes a loop that appends each element of the slice or array
+// expr.
+func (g *generator) marshalElems(expr string, t reflect.Type) error {
+	i := g.loopVar()
+	g.depth++
+	defer func() { g.depth-- }()
+	g.printf("for %s := range %s {", i, expr)
+	if err := g.marshal(expr+"["+i+"]", t.Elem()); err != nil {
+		return err
+	}
+	g.printf("}")
+	return nil
+}
+
+// unmarshal writes code that decodes expr, which has type t, from r. expr must
+// be addressable.
+func (g *generator) unmarshal(expr string, t reflect.Type) error {
+	if g.types[t] || reflect.PtrTo(t).Implements(readerType) {
+		g.printf("%s.ReadSia(%s)", expr, g.reader)
+		return nil
+	} else if hasMarshaler(t) {
+		g.printf("%s.Skip(%s.UnmarshalSia(%s.Remaining()))", g.reader, expr, g.reader)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		g.printf("if %s.ReadBool() {", g.reader)
+		g.printf("if %s == nil {", expr)
+		g.printf("%s.Alloc(%d)", g.reader, t.Elem().Size())
+		elem, err := g.typeName(t.Elem())
+		if err != nil {
+			return err
+		}
+		alloc, err := g.convert(t, "", "new("+elem+")")
+		if err != nil {
+			return err
+		}
+		g.printf("%s = %s", expr, alloc)
+		g.printf("}")
+		if err := g.unmarshal("(*"+expr+")", t.Elem()); err != nil {
+			return err
+		}
+		g.printf("}")
+	case reflect.Bool:
+		val, err := g.convert(t, "bool", g.reader+".ReadBool()")
+		if err != nil {
+			return err
+		}
+		g.printf("%s = %s", expr, val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val, err := g.convert(t, "uint64", g.reader+".ReadUint64()")
+		if err != nil {
+			return err
+		}
+		g.printf("%s = %s", expr, val)
+	case reflect.String:
+		val, err := g.convert(t, "string", g.reader+".ReadString()")
+		if err != nil {
+			return err
+		}
+		g.printf("%s = %s", expr, val)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			val, err := g.convert(t, "", g.reader+".ReadPrefixedBytes()")
+			if err != nil {
+				return err
+			}
+			g.printf("%s = %s", expr, val)
+			return nil
+		}
+		name, err := g.typeName(t)
+		if err != nil {
+			return err
+		}
+		g.printf("%s = make(%s, %s.ReadLen(%d, %d))", expr, name, g.reader, minEncodedSize(t.Elem()), t.Elem().Size())
+		return g.unmarshalElems(expr, t)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			g.printf("copy(%s[:], %s.ReadBytes(%d))", expr, g.reader, t.Len())
+			return nil
+		}
+		return g.unmarshalElems(expr, t)
+	case reflect.Struct:
+		return g.unmarshalStruct(expr, t)
+	default:
+		return errors.New("cannot generate unmarshalers for type " + t.String())
+	}
+	return nil
+}
+
+// unmarshalElems writes a loop that decodes each element of the slice or
+// array expr.
+func (g *generator) unmarshalElems(expr string, t reflect.Type) error {
+	i := g.loopVar()
+	g.depth++
+	defer func() { g.depth-- }()
+	g.printf("for %s := range %s {", i, expr)
+	if err := g.unmarshal(expr+"["+i+"]", t.Elem()); err != nil {
+		return err
+	}
+	g.printf("}")
+	return nil
+}
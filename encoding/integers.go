@@ -33,3 +33,8 @@ func DecUint64(b []byte) uint64 {
 	copy(b2, b)
 	return binary.LittleEndian.Uint64(b2)
 }
+
+// AppendUint64 appends the 8-byte encoding of a uint64 to b.
+func AppendUint64(b []byte, i uint64) []byte {
+	return binary.LittleEndian.AppendUint64(b, i)
+}
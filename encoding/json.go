@@ -0,0 +1,28 @@
+package encoding
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalHexJSON encodes b as a JSON string containing its hex
+// representation. It is used by the MarshalJSON methods of hashes, keys and
+// other fixed-size byte arrays.
+func MarshalHexJSON(b []byte) ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+// UnmarshalHexJSON decodes a JSON hex string into dst, which must be exactly
+// the size of the decoded string.
+func UnmarshalHexJSON(data []byte, dst []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return fmt.Errorf("expected %d hex characters, got %d", hex.EncodedLen(len(dst)), len(s))
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
@@ -1,10 +1,43 @@
 package encoding
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+const (
+	// DefaultAllocLimit is the number of bytes that Unmarshal may allocate
+	// while decoding a single object.
+	DefaultAllocLimit = 1 << 26
+)
+
+var (
+	ErrUnexpectedEnd = errors.New("unexpected end of input")
+	ErrAllocLimit    = errors.New("allocation limit exceeded")
+	ErrTrailingBytes = errors.New("input was not fully consumed")
+
+	unmarshalerType = reflect.TypeOf((*SiaUnmarshaler)(nil)).Elem()
+	generatedType   = reflect.TypeOf((*GeneratedMarshaler)(nil)).Elem()
+)
+
+// A DecodeError describes a failure to decode an object. Offset is the
+// position in the input at which decoding failed, and Path identifies the
+// value that was being decoded, e.g. "consensus.Block.Transactions[2].Outputs".
+// Err is the underlying cause.
+type DecodeError struct {
+	Offset int
+	Path   string
+	Err    error
+}
+
 // A Marshaler can be encoded as a byte slice.
 // Marshaler and Unmarshaler are separate interfaces because Unmarshaler must
 // have a pointer receiver, while Marshaler does not.
@@ -19,6 +52,25 @@ type SiaUnmarshaler interface {
 	UnmarshalSia([]byte) int
 }
 
+// A GeneratedMarshaler is a type whose MarshalSia and UnmarshalSia methods
+// were produced by Generate. Its encoding is always identical to the
+// reflective encoding, so it can also be encoded and decoded by reflection
+// (e.g. by a Decoder, or by MarshalReflect).
+type GeneratedMarshaler interface {
+	SiaMarshaler
+	SiaGenerated()
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("could not decode %v at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
 // Marshal encodes a value as a byte slice. The encoding rules are as follows:
 //
 // Most types are encoded as their binary representation.
@@ -36,6 +88,13 @@ type SiaUnmarshaler interface {
 // Slices and structs are simply the concatenation of their encoded elements.
 // Byte slices are not subject to the 8-byte integer rule; they are encoded as
 // their literal representation, one byte per byte.
+//
+// Maps are prefaced by their length, followed by each key and its value. The
+// pairs are sorted by the encoding of their keys, so that a map always has
+// the same encoding.
+//
+// Structs that implement VersionedStruct are prefaced by their version and the
+// length of their encoded fields; see VersionedStruct for details.
 // The ordering of struct fields is determined by their type definition. For example:
 //
 //   type foo struct {
@@ -45,164 +104,634 @@ type SiaUnmarshaler interface {
 //
 //   Marshal(foo{"bar", 3}) = append(Marshal("bar"), Marshal(3)...)
 func Marshal(v interface{}) []byte {
-	return marshal(reflect.ValueOf(v))
+	return marshal(reflect.ValueOf(v), false)
 }
 
-func marshal(val reflect.Value) (b []byte) {
+// MarshalReflect is like Marshal, but uses reflection to encode types that
+// implement GeneratedMarshaler instead of calling their MarshalSia methods. It
+// is used to verify the output of generated marshalers.
+func MarshalReflect(v interface{}) []byte {
+	return marshal(reflect.ValueOf(v), true)
+}
+
+// marshal encodes val. If reflectGenerated is set, the MarshalSia methods of
+// generated types are ignored.
+func marshal(val reflect.Value, reflectGenerated bool) []byte {
+	var buf bytes.Buffer
+	e := &encodeState{w: &buf, reflectGenerated: reflectGenerated}
+	if err := e.encode(val); err != nil {
+		// Marshalling should never fail. If it panics, you're doing something wrong,
+		// like trying to encode a channel or an unexported struct field.
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeState writes the encoding of values to an output stream. It is shared
+// by Marshal, which writes to a buffer, and Encoder, which writes directly to
+// its stream.
+type encodeState struct {
+	w   io.Writer
+	buf [8]byte
+
+	// reflectGenerated causes the MarshalSia methods of generated types to
+	// be ignored.
+	reflectGenerated bool
+}
+
+// write writes b to the output.
+func (e *encodeState) write(b []byte) error {
+	_, err := e.w.Write(b)
+	return err
+}
+
+// writeUint64 writes an 8-byte integer to the output.
+func (e *encodeState) writeUint64(u uint64) error {
+	binary.LittleEndian.PutUint64(e.buf[:], u)
+	return e.write(e.buf[:])
+}
+
+// encode writes the encoding of val to the output.
+func (e *encodeState) encode(val reflect.Value) error {
 	// check for MarshalSia interface first
-	if m, ok := val.Interface().(SiaMarshaler); ok {
-		return m.MarshalSia()
-	} else if val.CanAddr() {
-		if m, ok := val.Addr().Interface().(SiaMarshaler); ok {
-			return m.MarshalSia()
+	if val.CanInterface() {
+		if m, ok := val.Interface().(SiaMarshaler); ok && !(e.reflectGenerated && isGenerated(m)) {
+			return e.write(m.MarshalSia())
+		}
+	}
+	if val.CanAddr() && val.Addr().CanInterface() {
+		if m, ok := val.Addr().Interface().(SiaMarshaler); ok && !(e.reflectGenerated && isGenerated(m)) {
+			return e.write(m.MarshalSia())
 		}
 	}
 
 	switch val.Kind() {
 	case reflect.Ptr:
 		if val.IsNil() {
-			return []byte{0}
+			return e.write([]byte{0})
 		}
-		return append([]byte{1}, marshal(val.Elem())...)
+		if err := e.write([]byte{1}); err != nil {
+			return err
+		}
+		return e.encode(val.Elem())
 	case reflect.Bool:
 		if val.Bool() {
-			return []byte{1}
-		} else {
-			return []byte{0}
+			return e.write([]byte{1})
 		}
+		return e.write([]byte{0})
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return EncInt64(val.Int())
+		return e.writeUint64(uint64(val.Int()))
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return EncUint64(val.Uint())
+		return e.writeUint64(val.Uint())
 	case reflect.String:
-		s := val.String()
-		return append(EncUint64(uint64(len(s))), s...)
+		if err := e.writeUint64(uint64(val.Len())); err != nil {
+			return err
+		}
+		_, err := io.WriteString(e.w, val.String())
+		return err
 	case reflect.Slice:
 		// slices are variable length, so prepend the length and then fallthrough to array logic
-		b = EncUint64(uint64(val.Len()))
+		if err := e.writeUint64(uint64(val.Len())); err != nil {
+			return err
+		}
 		// special case for byte slices
 		if val.Type().Elem().Kind() == reflect.Uint8 {
-			return append(b, val.Bytes()...)
+			return e.write(val.Bytes())
 		}
 		fallthrough
 	case reflect.Array:
 		// special case for byte arrays
 		if val.Type().Elem().Kind() == reflect.Uint8 {
-			b = make([]byte, val.Len())
-			for i := range b {
-				b[i] = byte(val.Index(i).Uint())
-			}
-			return
+			b := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(b), val)
+			return e.write(b)
 		}
 		// normal slices/arrays are encoded by sequentially encoding their elements
 		for i := 0; i < val.Len(); i++ {
-			b = append(b, marshal(val.Index(i))...)
+			if err := e.encode(val.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		// maps are encoded like slices of key/value pairs, sorted by the
+		// encoding of their keys, so the pairs are encoded in memory first
+		pairs := make([][2][]byte, 0, val.Len())
+		for _, k := range val.MapKeys() {
+			pairs = append(pairs, [2][]byte{marshal(k, e.reflectGenerated), marshal(val.MapIndex(k), e.reflectGenerated)})
 		}
-		return
+		sort.Slice(pairs, func(i, j int) bool {
+			return bytes.Compare(pairs[i][0], pairs[j][0]) < 0
+		})
+		if err := e.writeUint64(uint64(len(pairs))); err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			if err := e.write(pair[0]); err != nil {
+				return err
+			} else if err := e.write(pair[1]); err != nil {
+				return err
+			}
+		}
+		return nil
 	case reflect.Struct:
-		for i := 0; i < val.NumField(); i++ {
-			b = append(b, marshal(val.Field(i))...)
+		version, versioned := structVersion(val.Type())
+		if !versioned {
+			return e.encodeFields(val)
+		}
+		// versioned structs are prefixed with their version and the length
+		// of their fields, so the fields are encoded in memory first
+		checkFieldVersions(val.Type(), version)
+		var buf bytes.Buffer
+		fields := &encodeState{w: &buf, reflectGenerated: e.reflectGenerated}
+		if err := fields.encodeFields(val); err != nil {
+			return err
+		}
+		if err := e.writeUint64(version); err != nil {
+			return err
+		} else if err := e.writeUint64(uint64(buf.Len())); err != nil {
+			return err
 		}
-		return
+		return e.write(buf.Bytes())
 	default:
-		// Marshalling should never fail. If it panics, you're doing something wrong,
-		// like trying to encode a map or an unexported struct field.
-		panic("could not marshal type " + val.Type().String())
+		return errors.New("could not marshal type " + val.Type().String())
+	}
+}
+
+// encodeFields sequentially encodes the fields of a struct.
+func (e *encodeState) encodeFields(val reflect.Value) error {
+	for i := 0; i < val.NumField(); i++ {
+		if err := e.encode(val.Field(i)); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Unmarshal decodes a byte slice into the provided interface. The interface must be a pointer.
 // The decoding rules are the inverse of those described under Marshal.
-func Unmarshal(b []byte, v interface{}) (err error) {
+// Unmarshal allocates at most DefaultAllocLimit bytes while decoding.
+func Unmarshal(b []byte, v interface{}) error {
+	return UnmarshalLimit(b, v, DefaultAllocLimit)
+}
+
+// UnmarshalLimit is like Unmarshal, but allocates at most allocLimit bytes
+// while decoding. Every length prefix is checked against the remaining input
+// and the allocation budget before any memory is allocated. If decoding fails,
+// a *DecodeError is returned.
+func UnmarshalLimit(b []byte, v interface{}, allocLimit uint64) error {
+	return unmarshalTop(b, v, allocLimit, false)
+}
+
+// UnmarshalReflect is like Unmarshal, but uses reflection to decode types that
+// implement GeneratedMarshaler instead of calling their UnmarshalSia methods.
+// It is used to verify the output of generated unmarshalers.
+func UnmarshalReflect(b []byte, v interface{}) error {
+	return unmarshalTop(b, v, DefaultAllocLimit, true)
+}
+
+// unmarshalTop decodes b into v, which must be a pointer.
+func unmarshalTop(b []byte, v interface{}, allocLimit uint64, reflectGenerated bool) error {
 	// v must be a pointer
 	pval := reflect.ValueOf(v)
 	if pval.Kind() != reflect.Ptr || pval.IsNil() {
 		return errors.New("must pass a valid pointer to Unmarshal")
 	}
 
-	// unmarshal may panic
-	// note that this allows us to skip any boundary checks while unmarshalling
-	var consumed int
+	d := &decodeState{
+		b:                b,
+		end:              len(b),
+		budget:           allocLimit,
+		path:             []string{pval.Elem().Type().String()},
+		reflectGenerated: reflectGenerated,
+	}
+	if err := d.unmarshal(pval.Elem()); err != nil {
+		return err
+	}
+	if d.off != len(b) {
+		return d.errorf(ErrTrailingBytes)
+	}
+	return nil
+}
+
+// decodeState tracks the progress of a call to Unmarshal or Decode: the input,
+// the current offset within it, the remaining allocation budget, and the path
+// of the value being decoded. The input is either a byte slice, or a stream
+// that is read as decoding proceeds.
+type decodeState struct {
+	b      []byte
+	r      io.Reader
+	off    int
+	end    int
+	budget uint64
+	path   []string
+	buf    [8]byte
+
+	// reflectGenerated causes the UnmarshalSia methods of generated types to
+	// be ignored.
+	reflectGenerated bool
+}
+
+// errorf returns a DecodeError for the current offset and path.
+func (d *decodeState) errorf(err error) *DecodeError {
+	return &DecodeError{
+		Offset: d.off,
+		Path:   strings.Join(d.path, ""),
+		Err:    err,
+	}
+}
+
+// remaining returns the number of undecoded bytes. The length of a stream is
+// unknown, so for a stream, remaining only reflects the end of the versioned
+// struct being decoded, if any.
+func (d *decodeState) remaining() uint64 {
+	return uint64(d.end - d.off)
+}
+
+// next returns the next n bytes of the input and advances the offset. When
+// reading from a stream, the returned slice is only valid until the next call
+// to next.
+func (d *decodeState) next(n uint64) ([]byte, error) {
+	if n > d.remaining() {
+		return nil, d.errorf(ErrUnexpectedEnd)
+	} else if d.r != nil {
+		return d.read(int(n))
+	}
+	b := d.b[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// nextUint64 decodes the next 8 bytes of the input as a uint64.
+func (d *decodeState) nextUint64() (uint64, error) {
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return DecUint64(b), nil
+}
+
+// alloc deducts n bytes from the allocation budget.
+func (d *decodeState) alloc(n uint64) error {
+	if n > d.budget {
+		return d.errorf(ErrAllocLimit)
+	}
+	d.budget -= n
+	return nil
+}
+
+// nextLen decodes a length prefix for a sequence of elements that each
+// occupy at least minSize bytes of input and allocSize bytes of memory,
+// checking that the input contains enough bytes to hold them and that
+// allocating them will not exceed the budget. Elements that occupy no memory
+// are still charged one byte each, so that the budget also bounds the number
+// of elements that are decoded.
+func (d *decodeState) nextLen(minSize, allocSize uint64) (int, error) {
+	n, err := d.nextUint64()
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, d.errorf(fmt.Errorf("length %d is too large", n))
+	}
+	if minSize > 0 && n > d.remaining()/minSize {
+		return 0, d.errorf(fmt.Errorf("length %d exceeds remaining input", n))
+	}
+	if allocSize == 0 {
+		allocSize = 1
+	}
+	if n > d.budget/allocSize {
+		return 0, d.errorf(ErrAllocLimit)
+	}
+	if err := d.alloc(n * allocSize); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// unmarshalCustom calls a SiaUnmarshaler on the remaining input, guarding
+// against a misbehaving implementation.
+func (d *decodeState) unmarshalCustom(u SiaUnmarshaler) (err error) {
 	defer func() {
-		if r := recover(); r != nil || consumed != len(b) {
-			err = errors.New("could not unmarshal type " + pval.Elem().Type().String())
+		if r := recover(); r != nil {
+			// generated unmarshalers panic with an error
+			if e, ok := r.(error); ok {
+				err = d.errorf(e)
+			} else {
+				err = d.errorf(fmt.Errorf("UnmarshalSia panicked: %v", r))
+			}
 		}
 	}()
+	if d.r != nil {
+		return d.errorf(errStreamUnmarshaler)
+	}
+	n := u.UnmarshalSia(d.b[d.off:d.end])
+	if n < 0 || uint64(n) > d.remaining() {
+		return d.errorf(fmt.Errorf("UnmarshalSia consumed %d of %d bytes", n, d.remaining()))
+	}
+	d.off += n
+	return nil
+}
 
-	consumed = unmarshal(b, pval.Elem())
-	return
+// unmarshalGenerated decodes val using its generated ReadSia method, which
+// shares the input and allocation budget of d. Generated decoders do not
+// track the path of the value being decoded, so if decoding fails, val is
+// decoded again by reflection to produce an accurate DecodeError.
+func (d *decodeState) unmarshalGenerated(val reflect.Value, sr SiaReader) (err error) {
+	budget := d.budget
+	defer func() {
+		if r := recover(); r != nil {
+			d.budget = budget
+			d.reflectGenerated = true
+			err = d.unmarshal(val)
+			d.reflectGenerated = false
+			if err == nil {
+				// the generated decoder disagrees with reflection
+				err = d.errorf(fmt.Errorf("ReadSia panicked: %v", r))
+			}
+		}
+	}()
+	r := &SliceReader{b: d.b[:d.end], off: d.off, budget: &d.budget}
+	sr.ReadSia(r)
+	d.off = r.off
+	return nil
 }
 
-func unmarshal(b []byte, val reflect.Value) (consumed int) {
+func (d *decodeState) unmarshal(val reflect.Value) error {
+	// generated types are decoded directly from the input
+	if !d.reflectGenerated && val.CanAddr() && val.Addr().CanInterface() {
+		if sr, ok := val.Addr().Interface().(SiaReader); ok {
+			return d.unmarshalGenerated(val, sr)
+		}
+	}
 	// check for UnmarshalSia interface first
-	if u, ok := val.Interface().(SiaUnmarshaler); ok {
-		return u.UnmarshalSia(b)
-	} else if val.CanAddr() {
-		if m, ok := val.Addr().Interface().(SiaUnmarshaler); ok {
-			return m.UnmarshalSia(b)
+	if val.CanInterface() {
+		if u, ok := val.Interface().(SiaUnmarshaler); ok && !(d.reflectGenerated && isGenerated(u)) {
+			if val.Kind() == reflect.Ptr && val.IsNil() {
+				return d.errorf(errors.New("cannot call UnmarshalSia on nil pointer"))
+			}
+			return d.unmarshalCustom(u)
+		}
+	}
+	if val.CanAddr() && val.Addr().CanInterface() {
+		if u, ok := val.Addr().Interface().(SiaUnmarshaler); ok && !(d.reflectGenerated && isGenerated(u)) {
+			return d.unmarshalCustom(u)
 		}
 	}
+	if !val.CanSet() {
+		return d.errorf(errors.New("cannot decode into unexported field"))
+	}
 
 	switch val.Kind() {
 	case reflect.Ptr:
+		b, err := d.next(1)
+		if err != nil {
+			return err
+		}
 		// nil pointer, nothing to decode
 		if b[0] == 0 {
-			return 1
+			return nil
 		}
 		// make sure we aren't decoding into nil
 		if val.IsNil() {
+			if err := d.alloc(uint64(val.Type().Elem().Size())); err != nil {
+				return err
+			}
 			val.Set(reflect.New(val.Type().Elem()))
 		}
-		return unmarshal(b[1:], val.Elem()) + 1
+		return d.unmarshal(val.Elem())
 	case reflect.Bool:
+		b, err := d.next(1)
+		if err != nil {
+			return err
+		}
 		val.SetBool(b[0] != 0)
-		return 1
+		return nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		val.SetInt(DecInt64(b[:8]))
-		return 8
+		u, err := d.nextUint64()
+		if err != nil {
+			return err
+		}
+		val.SetInt(int64(u))
+		return nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		val.SetUint(DecUint64(b[:8]))
-		return 8
+		u, err := d.nextUint64()
+		if err != nil {
+			return err
+		}
+		val.SetUint(u)
+		return nil
 	case reflect.String:
-		n := DecUint64(b[:8]) + 8
-		val.SetString(string(b[8:n]))
-		return int(n)
+		n, err := d.nextLen(1, 1)
+		if err != nil {
+			return err
+		}
+		b, err := d.next(uint64(n))
+		if err != nil {
+			return err
+		}
+		val.SetString(string(b))
+		return nil
 	case reflect.Slice:
 		// slices are variable length, but otherwise the same as arrays.
-		// just have to allocate them first, then we can fallthrough to the array logic.
-		var sliceLen int
-		sliceLen, b, consumed = int(DecUint64(b[:8])), b[8:], 8
-		val.Set(reflect.MakeSlice(val.Type(), sliceLen, sliceLen))
+		// the length is validated before the slice is allocated.
+		elem := val.Type().Elem()
+		n, err := d.nextLen(minEncodedSize(elem), uint64(elem.Size()))
+		if err != nil {
+			return err
+		}
 		// special case for byte slices
 		if val.Type().Elem().Kind() == reflect.Uint8 {
-			val.SetBytes(b[:sliceLen])
-			return consumed + sliceLen
+			b, err := d.next(uint64(n))
+			if err != nil {
+				return err
+			}
+			val.SetBytes(append([]byte(nil), b...))
+			return nil
 		}
-		fallthrough
+		if d.r != nil {
+			return d.appendElems(val, n)
+		}
+		val.Set(reflect.MakeSlice(val.Type(), n, n))
+		return d.unmarshalElems(val)
 	case reflect.Array:
 		// special case for byte arrays (e.g. hashes)
 		if val.Type().Elem().Kind() == reflect.Uint8 {
-			for i := 0; i < val.Len(); i++ {
-				val.Index(i).SetUint(uint64(b[i]))
+			b, err := d.next(uint64(val.Len()))
+			if err != nil {
+				return err
 			}
-			return val.Len()
+			reflect.Copy(val, reflect.ValueOf(b))
+			return nil
 		}
-		// arrays are unmarshalled by sequentially unmarshalling their elements
-		for i := 0; i < val.Len(); i++ {
-			elem := val.Index(i)
-			n := unmarshal(b, elem)
-			consumed, b = consumed+n, b[n:]
+		return d.unmarshalElems(val)
+	case reflect.Map:
+		return d.unmarshalMap(val)
+	case reflect.Struct:
+		version, versioned := structVersion(val.Type())
+		if !versioned {
+			return d.unmarshalFields(val, 0)
 		}
-		return
+		return d.unmarshalVersioned(val, version)
+	default:
+		return d.errorf(errors.New("cannot decode type " + val.Type().String()))
+	}
+}
+
+// unmarshalFields sequentially unmarshals the fields of a struct. If version
+// is non-zero, fields that are newer than version are skipped.
+func (d *decodeState) unmarshalFields(val reflect.Value, version uint64) error {
+	for i := 0; i < val.NumField(); i++ {
+		f := val.Type().Field(i)
+		d.path = append(d.path, "."+f.Name)
+		var fv uint64
+		if version != 0 {
+			var err error
+			if fv, err = fieldVersion(f); err != nil {
+				return d.errorf(err)
+			}
+		}
+		if fv <= version {
+			if err := d.unmarshal(val.Field(i)); err != nil {
+				return err
+			}
+		}
+		d.path = d.path[:len(d.path)-1]
+	}
+	return nil
+}
+
+// unmarshalVersioned unmarshals a VersionedStruct whose current version is
+// version. Fields that are newer than the encoding are left unchanged, and
+// fields that are newer than the struct are skipped.
+func (d *decodeState) unmarshalVersioned(val reflect.Value, version uint64) error {
+	encVersion, err := d.nextUint64()
+	if err != nil {
+		return err
+	} else if encVersion == 0 {
+		return d.errorf(errors.New("invalid struct version 0"))
+	}
+	n, err := d.nextUint64()
+	if err != nil {
+		return err
+	} else if n > d.remaining() {
+		return d.errorf(ErrUnexpectedEnd)
+	}
+
+	// restrict the input to the encoded fields
+	end, full := d.off+int(n), d.end
+	d.end = end
+	defer func() { d.end = full }()
+	if err := d.unmarshalFields(val, encVersion); err != nil {
+		return err
+	}
+	if encVersion > version {
+		return d.skip(end - d.off)
+	} else if d.off != end {
+		return d.errorf(ErrTrailingBytes)
+	}
+	return nil
+}
+
+// unmarshalMap unmarshals a map. The keys must appear in strictly increasing
+// order of their encodings, so that each map has exactly one encoding.
+func (d *decodeState) unmarshalMap(val reflect.Value) error {
+	kt, vt := val.Type().Key(), val.Type().Elem()
+	n, err := d.nextLen(minEncodedSize(kt)+minEncodedSize(vt), uint64(kt.Size()+vt.Size()))
+	if err != nil {
+		return err
+	}
+	if d.r != nil {
+		// pairs are inserted one at a time, so the map only grows as fast
+		// as data arrives
+		val.Set(reflect.MakeMap(val.Type()))
+	} else {
+		val.Set(reflect.MakeMapWithSize(val.Type(), n))
+	}
+	var prev []byte
+	for i := 0; i < n; i++ {
+		d.path = append(d.path, "["+strconv.Itoa(i)+"]")
+		start := d.off
+		k := reflect.New(kt).Elem()
+		if err := d.unmarshal(k); err != nil {
+			return err
+		}
+		var key []byte
+		if d.r != nil {
+			key = marshal(k, d.reflectGenerated)
+		} else {
+			key = d.b[start:d.off]
+		}
+		if i > 0 && bytes.Compare(prev, key) >= 0 {
+			return d.errorf(errors.New("map keys are not in sorted order"))
+		}
+		prev = key
+		v := reflect.New(vt).Elem()
+		if err := d.unmarshal(v); err != nil {
+			return err
+		}
+		val.SetMapIndex(k, v)
+		d.path = d.path[:len(d.path)-1]
+	}
+	return nil
+}
+
+// unmarshalElems sequentially unmarshals the elements of a slice or array.
+func (d *decodeState) unmarshalElems(val reflect.Value) error {
+	for i := 0; i < val.Len(); i++ {
+		d.path = append(d.path, "["+strconv.Itoa(i)+"]")
+		if err := d.unmarshal(val.Index(i)); err != nil {
+			return err
+		}
+		d.path = d.path[:len(d.path)-1]
+	}
+	return nil
+}
+
+// isGenerated reports whether v implements GeneratedMarshaler.
+func isGenerated(v interface{}) bool {
+	_, ok := v.(GeneratedMarshaler)
+	return ok
+}
+
+// minEncodedSize returns the smallest number of bytes that an encoded value of
+// type t can occupy. Types with a custom encoding are assumed to have a
+// minimum size of 0.
+func minEncodedSize(t reflect.Type) uint64 {
+	// generated types follow the reflective rules
+	custom := t.Implements(unmarshalerType) || reflect.PtrTo(t).Implements(unmarshalerType)
+	generated := t.Implements(generatedType) || reflect.PtrTo(t).Implements(generatedType)
+	if custom && !generated {
+		return 0
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Bool, reflect.Uint8:
+		// a single byte is the minimum size of a byte slice element, even
+		// though a lone uint8 is encoded as 8 bytes
+		return 1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String, reflect.Slice, reflect.Map:
+		return 8
+	case reflect.Array:
+		return uint64(t.Len()) * minEncodedSize(t.Elem())
 	case reflect.Struct:
-		for i := 0; i < val.NumField(); i++ {
-			f := val.Field(i)
-			n := unmarshal(b, f)
-			consumed, b = consumed+n, b[n:]
+		// versioned structs always contain their version, length, and the
+		// fields of the first version
+		var size uint64
+		_, versioned := structVersion(t)
+		if versioned {
+			size = 16
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if v, _ := fieldVersion(t.Field(i)); versioned && v > 1 {
+				continue
+			}
+			size += minEncodedSize(t.Field(i).Type)
 		}
-		return
+		return size
 	default:
-		panic("unknown type")
+		return 0
 	}
 }
 
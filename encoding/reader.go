@@ -0,0 +1,139 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A SliceReader decodes primitive values from a byte slice. It is used by the
+// UnmarshalSia and ReadSia methods that are produced by Generate. Each length
+// prefix is checked against the remaining input and the allocation budget
+// before anything is allocated.
+//
+// SliceReader methods panic if the input is malformed. The panic is recovered
+// by Unmarshal and reported as a DecodeError.
+type SliceReader struct {
+	b      []byte
+	off    int
+	budget *uint64
+}
+
+// A SiaReader can decode itself from a SliceReader. It is implemented by the
+// types passed to Generate, allowing Unmarshal to share its input offset and
+// allocation budget with their generated decoders.
+type SiaReader interface {
+	ReadSia(r *SliceReader)
+}
+
+// NewSliceReader returns a SliceReader that reads from b, and allocates at
+// most DefaultAllocLimit bytes.
+func NewSliceReader(b []byte) *SliceReader {
+	budget := uint64(DefaultAllocLimit)
+	return &SliceReader{b: b, budget: &budget}
+}
+
+// Consumed returns the number of bytes that have been read.
+func (r *SliceReader) Consumed() int {
+	return r.off
+}
+
+// Remaining returns the unread portion of the input.
+func (r *SliceReader) Remaining() []byte {
+	return r.b[r.off:]
+}
+
+// Skip advances past the next n bytes of the input.
+func (r *SliceReader) Skip(n int) {
+	r.ReadBytes(n)
+}
+
+// ReadBytes returns the next n bytes of the input. The returned slice aliases
+// the input.
+func (r *SliceReader) ReadBytes(n int) []byte {
+	if n < 0 || n > len(r.b)-r.off {
+		panic(ErrUnexpectedEnd)
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+// ReadBool decodes a single byte as a bool.
+func (r *SliceReader) ReadBool() bool {
+	return r.ReadBytes(1)[0] != 0
+}
+
+// ReadUint64 decodes an 8-byte integer.
+func (r *SliceReader) ReadUint64() uint64 {
+	return DecUint64(r.ReadBytes(8))
+}
+
+// Alloc deducts n bytes from the allocation budget.
+func (r *SliceReader) Alloc(n uint64) {
+	if n > *r.budget {
+		panic(ErrAllocLimit)
+	}
+	*r.budget -= n
+}
+
+// ReadLen decodes a length prefix for a slice whose elements each occupy at
+// least minSize bytes of input and allocSize bytes of memory. It checks that
+// the remaining input is large enough to hold the slice, and deducts the
+// memory it occupies from the allocation budget. As in Unmarshal, elements
+// that occupy no memory are charged one byte each.
+func (r *SliceReader) ReadLen(minSize, allocSize uint64) int {
+	n := r.ReadUint64()
+	if n > math.MaxInt32 {
+		panic(fmt.Errorf("length %d is too large", n))
+	}
+	if minSize > 0 && n > uint64(len(r.b)-r.off)/minSize {
+		panic(fmt.Errorf("length %d exceeds remaining input", n))
+	}
+	if allocSize == 0 {
+		allocSize = 1
+	}
+	if n > *r.budget/allocSize {
+		panic(ErrAllocLimit)
+	}
+	r.Alloc(n * allocSize)
+	return int(n)
+}
+
+// ReadPrefixedBytes decodes a length-prefixed byte slice. The returned slice
+// is a copy, and is nil if the length is 0.
+func (r *SliceReader) ReadPrefixedBytes() []byte {
+	return append([]byte(nil), r.ReadBytes(r.ReadLen(1, 1))...)
+}
+
+// ReadString decodes a length-prefixed string.
+func (r *SliceReader) ReadString() string {
+	return string(r.ReadBytes(r.ReadLen(1, 1)))
+}
+
+// ReadVersioned decodes the header of a VersionedStruct. It returns the
+// version of the encoding and a SliceReader containing only the encoded
+// fields, and advances past the fields. The returned SliceReader shares the
+// allocation budget of r, and its offsets are relative to the same input.
+func (r *SliceReader) ReadVersioned() (uint64, *SliceReader) {
+	version := r.ReadUint64()
+	if version == 0 {
+		panic(errors.New("invalid struct version 0"))
+	}
+	n := r.ReadUint64()
+	if n > uint64(len(r.b)-r.off) {
+		panic(ErrUnexpectedEnd)
+	}
+	fields := &SliceReader{b: r.b[:r.off+int(n)], off: r.off, budget: r.budget}
+	r.off += int(n)
+	return version, fields
+}
+
+// FinishVersioned checks that the fields of a VersionedStruct were fully
+// consumed. Fields that are newer than the current version of the struct are
+// ignored.
+func (r *SliceReader) FinishVersioned(version, current uint64) {
+	if version <= current && r.off != len(r.b) {
+		panic(ErrTrailingBytes)
+	}
+}
@@ -0,0 +1,171 @@
+package encoding
+
+import (
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+const (
+	// chunkSize is the largest number of bytes that a Decoder will allocate
+	// for a byte slice or string before the corresponding data has been read.
+	// Larger objects are read in chunks, so that a bogus length prefix cannot
+	// cause a large allocation.
+	chunkSize = 1 << 16
+)
+
+var (
+	errStreamUnmarshaler = errors.New("types implementing SiaUnmarshaler cannot be decoded from a stream")
+)
+
+// An Encoder writes objects to an output stream. Objects are written field by
+// field, so the full encoding of an object is never held in memory. The
+// output of an Encoder is identical to the output of Marshal.
+//
+// Each primitive value results in a separate call to Write, so an Encoder that
+// writes to a connection or file should be given a buffered writer.
+type Encoder struct {
+	e encodeState
+}
+
+// A Decoder reads objects from an input stream. Objects are read field by
+// field, so the full encoding of an object is never held in memory. A Decoder
+// reads exactly the bytes that Marshal would produce for the object, and
+// nothing more.
+//
+// Like Unmarshal, a Decoder allocates at most DefaultAllocLimit bytes for
+// each object, and reports malformed input as a *DecodeError, whose Offset is
+// relative to the start of the stream.
+//
+// Since a stream does not reveal how many bytes remain, types that implement
+// SiaUnmarshaler cannot be decoded by a Decoder, unless they also implement
+// GeneratedMarshaler.
+type Decoder struct {
+	r   io.Reader
+	off int
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{e: encodeState{w: w}}
+}
+
+// Encode writes the encoding of v to the stream.
+func (e *Encoder) Encode(v interface{}) error {
+	return e.e.encode(reflect.ValueOf(v))
+}
+
+// EncodeAll encodes each of its inputs in order, stopping at the first error.
+func (e *Encoder) EncodeAll(vs ...interface{}) error {
+	for _, v := range vs {
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next encoded object from the stream and stores it in v,
+// which must be a pointer. If the stream ends before the object begins, Decode
+// returns io.EOF.
+func (d *Decoder) Decode(v interface{}) error {
+	pval := reflect.ValueOf(v)
+	if pval.Kind() != reflect.Ptr || pval.IsNil() {
+		return errors.New("must pass a valid pointer to Decode")
+	}
+
+	// generated types are decoded by reflection, since their UnmarshalSia
+	// methods require the full encoding
+	ds := &decodeState{
+		r:                d.r,
+		off:              d.off,
+		end:              math.MaxInt64,
+		budget:           DefaultAllocLimit,
+		path:             []string{pval.Elem().Type().String()},
+		reflectGenerated: true,
+	}
+	err := ds.unmarshal(pval.Elem())
+	var de *DecodeError
+	if errors.As(err, &de) && de.Offset == d.off && errors.Is(err, ErrUnexpectedEnd) {
+		err = io.EOF
+	}
+	d.off = ds.off
+	return err
+}
+
+// DecodeAll decodes each of its inputs in order, stopping at the first error.
+func (d *Decoder) DecodeAll(vs ...interface{}) error {
+	for _, v := range vs {
+		if err := d.Decode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read reads the next n bytes from the stream. The returned slice grows as
+// data arrives, rather than being allocated up front.
+func (d *decodeState) read(n int) ([]byte, error) {
+	var b []byte
+	if n <= len(d.buf) {
+		b = d.buf[:0]
+	}
+	for len(b) < n {
+		chunk := n - len(b)
+		if chunk > chunkSize {
+			chunk = chunkSize
+		}
+		start := len(b)
+		b = append(b, make([]byte, chunk)...)
+		if _, err := io.ReadFull(d.r, b[start:]); err != nil {
+			return nil, d.streamErr(err)
+		}
+	}
+	d.off += n
+	return b, nil
+}
+
+// skip advances past the next n bytes of the input.
+func (d *decodeState) skip(n int) error {
+	if d.r == nil {
+		d.off += n
+		return nil
+	}
+	if _, err := io.CopyN(io.Discard, d.r, int64(n)); err != nil {
+		return d.streamErr(err)
+	}
+	d.off += n
+	return nil
+}
+
+// streamErr returns a DecodeError for a failed read from the stream. A
+// premature end of the stream is reported as ErrUnexpectedEnd.
+func (d *decodeState) streamErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = ErrUnexpectedEnd
+	}
+	return d.errorf(err)
+}
+
+// appendElems decodes n elements of a slice from the stream. Elements are
+// appended one at a time, so the slice only grows as fast as data arrives.
+func (d *decodeState) appendElems(val reflect.Value, n int) error {
+	val.Set(reflect.MakeSlice(val.Type(), 0, 0))
+	for i := 0; i < n; i++ {
+		d.path = append(d.path, "["+strconv.Itoa(i)+"]")
+		elem := reflect.New(val.Type().Elem()).Elem()
+		if err := d.unmarshal(elem); err != nil {
+			return err
+		}
+		val.Set(reflect.Append(val, elem))
+		d.path = d.path[:len(d.path)-1]
+	}
+	return nil
+}
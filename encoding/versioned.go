@@ -0,0 +1,89 @@
+package encoding
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	versionedType = reflect.TypeOf((*VersionedStruct)(nil)).Elem()
+)
+
+// A VersionedStruct is a struct whose encoding is prefixed with a version
+// number and the length of its fields, so that fields can be added to it
+// without breaking existing encodings. SiaVersion returns the current version
+// of the struct, and must have a value receiver.
+//
+// Fields that were present in the first version of the struct are untagged.
+// Each field that is added later must be appended to the end of the struct,
+// and tagged with the version that introduced it:
+//
+//	type foo struct {
+//		A uint64
+//		B string `sia:"v2"`
+//	}
+//
+//	func (foo) SiaVersion() uint64 { return 2 }
+//
+// When an older encoding is decoded, fields that are newer than the encoding
+// are left unchanged. When a newer encoding is decoded, the fields that are
+// unknown to the decoder are skipped. Fields may never be removed or
+// reordered.
+type VersionedStruct interface {
+	SiaVersion() uint64
+}
+
+// structVersion returns the current version of t, and whether t is a
+// VersionedStruct.
+func structVersion(t reflect.Type) (uint64, bool) {
+	if t.Kind() != reflect.Struct || !t.Implements(versionedType) {
+		return 0, false
+	}
+	return reflect.Zero(t).Interface().(VersionedStruct).SiaVersion(), true
+}
+
+// fieldVersion returns the version that introduced f, according to its sia
+// tag. Untagged fields belong to version 1.
+func fieldVersion(f reflect.StructField) (uint64, error) {
+	tag := f.Tag.Get("sia")
+	if tag == "" {
+		return 1, nil
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(tag, "v"), 10, 64)
+	if err != nil || !strings.HasPrefix(tag, "v") || v == 0 {
+		return 0, fmt.Errorf("invalid version tag %q on field %v", tag, f.Name)
+	}
+	return v, nil
+}
+
+// checkFieldVersions verifies that the fields of the versioned struct t are
+// tagged with non-decreasing versions, none of which exceed the version of the
+// struct. It panics otherwise, as this indicates a programming error.
+func checkFieldVersions(t reflect.Type, version uint64) {
+	var prev uint64 = 1
+	for i := 0; i < t.NumField(); i++ {
+		v, err := fieldVersion(t.Field(i))
+		if err != nil {
+			panic(err)
+		} else if v < prev || v > version {
+			panic(fmt.Sprintf("field %v of %v has version %v, which is out of order or newer than the struct version %v", t.Field(i).Name, t, v, version))
+		}
+		prev = v
+	}
+}
+
+// AppendVersionHeader appends the version of a VersionedStruct to b, followed
+// by a placeholder for the length of its fields. Once the fields have been
+// appended, SetVersionLength should be called with the length of b prior to
+// calling AppendVersionHeader. It is used by generated marshalers.
+func AppendVersionHeader(b []byte, version uint64) []byte {
+	return append(AppendUint64(b, version), make([]byte, 8)...)
+}
+
+// SetVersionLength fills in the length placeholder of a header that was
+// appended to b at offset start by AppendVersionHeader.
+func SetVersionLength(b []byte, start int) {
+	copy(b[start+8:], EncUint64(uint64(len(b)-start-16)))
+}
@@ -0,0 +1,160 @@
+package hash
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	// CacheHeight is the height of the lowest level of Merkle nodes stored in
+	// a cache. Each node on that level covers a chunk of 2^CacheHeight
+	// segments (256 KiB), which is the only file data that must be read to
+	// build a proof.
+	CacheHeight = 12
+
+	chunkSegments = 1 << CacheHeight
+	chunkSize     = chunkSegments * SegmentSize
+)
+
+// A CacheBuilder computes the Merkle root of the data written to it, and
+// records the intermediate nodes of the tree from CacheHeight upwards. The
+// nodes can then be written to a cache file with WriteTo, and used by
+// BuildCachedProof to build storage proofs without re-hashing the whole file.
+//
+// A cache file holds the levels of the tree from the bottom up, each level
+// being a list of hashes. The sizes of the levels are determined by the
+// number of segments in the file, so the cache has no header. A cache for n
+// segments holds roughly 2n/2^CacheHeight hashes.
+type CacheBuilder struct {
+	chunk   *Tree // the chunk currently being written
+	written int   // bytes written to chunk
+	chunks  []Hash
+}
+
+// NewCacheBuilder returns an empty CacheBuilder.
+func NewCacheBuilder() *CacheBuilder {
+	return &CacheBuilder{chunk: NewTree()}
+}
+
+// Write implements io.Writer, adding data to the tree.
+func (cb *CacheBuilder) Write(p []byte) (n int, err error) {
+	n = len(p)
+	for len(p) > 0 {
+		fill := chunkSize - cb.written
+		if fill > len(p) {
+			fill = len(p)
+		}
+		cb.chunk.Write(p[:fill])
+		cb.written += fill
+		p = p[fill:]
+		if cb.written == chunkSize {
+			cb.chunks = append(cb.chunks, cb.chunk.Root())
+			cb.chunk = NewTree()
+			cb.written = 0
+		}
+	}
+	return
+}
+
+// levels returns the cached levels of the tree, starting with the roots of
+// each chunk and ending with the Merkle root.
+func (cb *CacheBuilder) levels() (levels [][]Hash) {
+	level := cb.chunks
+	if cb.chunk.Leaves() != 0 {
+		level = append(level[:len(level):len(level)], cb.chunk.Root())
+	}
+	if len(level) == 0 {
+		return
+	}
+	levels = append(levels, level)
+	for len(level) > 1 {
+		next := make([]Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			// an orphan is carried up to the next level unchanged
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, JoinHash(level[i], level[i+1]))
+			}
+		}
+		level = next
+		levels = append(levels, level)
+	}
+	return
+}
+
+// Root returns the Merkle root of the data written so far.
+func (cb *CacheBuilder) Root() (root Hash) {
+	levels := cb.levels()
+	if len(levels) == 0 {
+		return
+	}
+	return levels[len(levels)-1][0]
+}
+
+// WriteTo writes the cached levels of the tree to w.
+func (cb *CacheBuilder) WriteTo(w io.Writer) (n int64, err error) {
+	for _, level := range cb.levels() {
+		for _, h := range level {
+			var written int
+			written, err = w.Write(h[:])
+			n += int64(written)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// BuildCachedProof constructs the same proof as BuildReaderProof, using a
+// cache that was written by a CacheBuilder. Only the chunk containing the
+// proof segment is read from the file; the remaining hashes are read from the
+// cache.
+func BuildCachedProof(file, cache io.ReaderAt, numSegments, proofIndex uint64) (baseSegment [SegmentSize]byte, hashSet []Hash, err error) {
+	if proofIndex >= numSegments {
+		err = errors.New("proof index is out of range")
+		return
+	}
+
+	// Read the chunk containing the proof segment, and build the part of the
+	// proof that lies within the chunk.
+	chunkIndex := proofIndex / chunkSegments
+	start := chunkIndex * chunkSegments
+	segments := numSegments - start
+	if segments > chunkSegments {
+		segments = chunkSegments
+	}
+	chunk := make([]byte, segments*SegmentSize)
+	n, err := file.ReadAt(chunk, int64(start)*SegmentSize)
+	if err == io.EOF && uint64(n) > (segments-1)*SegmentSize {
+		// the final segment of the file may be partial
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	baseSegment, hashSet, err = BuildReaderProof(bytes.NewReader(chunk), segments, proofIndex-start)
+	if err != nil {
+		return
+	}
+
+	// Add the sister of the proof's ancestor on each cached level. The levels
+	// halve in size, and end with the root.
+	var offset uint64
+	index := chunkIndex
+	for count := (numSegments + chunkSegments - 1) / chunkSegments; count > 1; count = (count + 1) / 2 {
+		// orphans have no sister, as in BuildReaderProof
+		if sister := index ^ 1; sister < count {
+			var h Hash
+			if _, err = cache.ReadAt(h[:], int64(offset+sister)*HashSize); err != nil {
+				return
+			}
+			hashSet = append(hashSet, h)
+		}
+		offset += count
+		index /= 2
+	}
+	return
+}
@@ -0,0 +1,173 @@
+package hash
+
+import (
+	"errors"
+	"io"
+	"sort"
+)
+
+// A diff proof proves the transition from the Merkle root of a file to the
+// root of a modified version of the file. Segments may be modified, appended
+// to the end of the file, or truncated from the end of the file.
+//
+// The proof is a multi-segment proof against the old tree, covering the
+// modified segments and the segments at the edges of the old and new files.
+// Covering the edges guarantees that every subtree in the proof is a perfect
+// subtree lying entirely inside or entirely outside of the new file, so the
+// subtrees that survive the change are also subtrees of the new tree. The
+// verifier first checks the proof against the old root, and then builds the
+// new root from the surviving subtrees and the new segments.
+
+var (
+	errEmptyFile = errors.New("diff proofs cannot be built for empty files")
+)
+
+// A DiffProof holds the hashes needed to prove a change to a file.
+type DiffProof struct {
+	// Leaves holds the hashes of the old segments at the indices returned by
+	// diffIndices.
+	Leaves []Hash
+
+	// Hashes holds the roots of the subtrees of the old tree that contain
+	// none of those segments, ordered from left to right.
+	Hashes []Hash
+}
+
+// checkDiff returns an error if a diff is invalid. Modified segments must be
+// sorted, unique, and present in both the old and the new file.
+func checkDiff(oldSegments, newSegments uint64, modified []uint64) error {
+	if oldSegments == 0 || newSegments == 0 {
+		return errEmptyFile
+	}
+	common := oldSegments
+	if newSegments < common {
+		common = newSegments
+	}
+	for i, index := range modified {
+		if index >= common || (i > 0 && index <= modified[i-1]) {
+			return errBadIndices
+		}
+	}
+	return nil
+}
+
+// diffIndices returns the indices of the old segments that are covered by a
+// diff proof: the modified segments, the final segment of the old file, and,
+// if the file is truncated, the segments on either side of the new end of the
+// file.
+func diffIndices(oldSegments, newSegments uint64, modified []uint64) []uint64 {
+	set := make(map[uint64]struct{})
+	for _, index := range modified {
+		set[index] = struct{}{}
+	}
+	set[oldSegments-1] = struct{}{}
+	if newSegments < oldSegments {
+		set[newSegments-1] = struct{}{}
+		set[newSegments] = struct{}{}
+	}
+	indices := make([]uint64, 0, len(set))
+	for index := range set {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	return indices
+}
+
+// BuildDiffProof constructs a proof for a change to the data in r, which has
+// oldSegments segments. After the change, the file has newSegments segments,
+// and the segments at the indices in modified have new contents.
+func BuildDiffProof(r io.ReaderAt, oldSegments, newSegments uint64, modified []uint64) (proof DiffProof, err error) {
+	if err = checkDiff(oldSegments, newSegments, modified); err != nil {
+		return
+	}
+	indices := diffIndices(oldSegments, newSegments, modified)
+	proof.Hashes, err = buildProof(r, oldSegments, indexCover(indices))
+	if err != nil {
+		return
+	}
+	for _, index := range indices {
+		var h Hash
+		h, err = ReaderMerkleRoot(io.NewSectionReader(r, int64(index)*SegmentSize, SegmentSize), 1)
+		if err != nil {
+			return
+		}
+		proof.Leaves = append(proof.Leaves, h)
+	}
+	return
+}
+
+// VerifyDiffProof checks a diff proof against the root of the old file, and
+// returns the root of the new file. newData holds the new contents of the
+// modified segments, followed by the appended segments, if any. A partial
+// final segment must be padded with zeros.
+func VerifyDiffProof(proof DiffProof, oldSegments, newSegments uint64, modified []uint64, newData [][SegmentSize]byte, oldRoot Hash) (newRoot Hash, ok bool) {
+	if checkDiff(oldSegments, newSegments, modified) != nil {
+		return
+	}
+	var appended uint64
+	if newSegments > oldSegments {
+		appended = newSegments - oldSegments
+	}
+	indices := diffIndices(oldSegments, newSegments, modified)
+	if len(proof.Leaves) != len(indices) || uint64(len(newData)) != uint64(len(modified))+appended {
+		return
+	}
+
+	// Check the proof against the old root, recording the position of each
+	// subtree in the proof.
+	type subtree struct {
+		hi  uint64
+		sum Hash
+	}
+	known := make(map[uint64]subtree)
+	hashes := proof.Hashes
+	outside := func(lo, hi uint64) (h Hash, err error) {
+		if len(hashes) == 0 {
+			err = errors.New("proof is too short")
+			return
+		}
+		h, hashes = hashes[0], hashes[1:]
+		known[lo] = subtree{hi, h}
+		return
+	}
+	inside := func(lo, hi uint64) (Hash, error) {
+		i := sort.Search(len(indices), func(j int) bool { return indices[j] >= lo })
+		return MerkleRoot(proof.Leaves[i : i+int(hi-lo)]), nil
+	}
+	root, err := walkProof(0, oldSegments, indexCover(indices), outside, inside)
+	if err != nil || len(hashes) != 0 || root != oldRoot {
+		return
+	}
+
+	// Add the surviving old segments, the modified segments, and the
+	// appended segments to the known subtrees. Subtrees beyond the end of the
+	// new file are never reached.
+	for i, index := range indices {
+		known[index] = subtree{index + 1, proof.Leaves[i]}
+	}
+	for i, index := range modified {
+		known[index] = subtree{index + 1, HashBytes(newData[i][:])}
+	}
+	for i := uint64(0); i < appended; i++ {
+		index := oldSegments + i
+		known[index] = subtree{index + 1, HashBytes(newData[uint64(len(modified))+i][:])}
+	}
+
+	// Build the new root from the known subtrees.
+	var build func(lo, hi uint64) (Hash, bool)
+	build = func(lo, hi uint64) (Hash, bool) {
+		if st, exists := known[lo]; exists && st.hi == hi {
+			return st.sum, true
+		} else if hi-lo == 1 {
+			return Hash{}, false
+		}
+		mid := lo + splitPoint(hi-lo)
+		left, ok := build(lo, mid)
+		if !ok {
+			return Hash{}, false
+		}
+		right, ok := build(mid, hi)
+		return JoinHash(left, right), ok
+	}
+	return build(0, newSegments)
+}
@@ -30,3 +30,13 @@ func HashAll(data ...[]byte) Hash {
 func HashObject(obj interface{}) Hash {
 	return HashBytes(encoding.Marshal(obj))
 }
+
+// MarshalJSON marshals a hash as a hex string.
+func (h Hash) MarshalJSON() ([]byte, error) {
+	return encoding.MarshalHexJSON(h[:])
+}
+
+// UnmarshalJSON decodes a hash from a hex string.
+func (h *Hash) UnmarshalJSON(b []byte) error {
+	return encoding.UnmarshalHexJSON(b, h[:])
+}
@@ -3,6 +3,7 @@ package hash
 import (
 	"bytes"
 	"crypto/rand"
+	"reflect"
 	"testing"
 )
 
@@ -81,3 +82,253 @@ func TestStorageProof(t *testing.T) {
 		}
 	}
 }
+
+// TestTree checks that the roots produced by a Tree match MerkleRoot and
+// ReaderMerkleRoot for every prefix of the leaves.
+func TestTree(t *testing.T) {
+	var leaves []Hash
+	tree := NewTree()
+	if tree.Root() != (Hash{}) {
+		t.Fatal("empty tree has nonzero root")
+	}
+	for i := 0; i < 40; i++ {
+		var h Hash
+		rand.Read(h[:])
+		leaves = append(leaves, h)
+		tree.PushHash(h)
+		if tree.Root() != MerkleRoot(leaves) {
+			t.Fatal("Tree root does not match MerkleRoot for", i+1, "leaves")
+		}
+		if tree.Leaves() != uint64(i+1) {
+			t.Fatal("wrong number of leaves:", tree.Leaves())
+		}
+	}
+
+	// write data in uneven pieces, including a partial final segment
+	data := make([]byte, 13*SegmentSize+17)
+	rand.Read(data)
+	tree = NewTree()
+	for p := data; len(p) > 0; {
+		n := 23
+		if n > len(p) {
+			n = len(p)
+		}
+		tree.Write(p[:n])
+		p = p[n:]
+	}
+	root, err := BytesMerkleRoot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Root() != root {
+		t.Fatal("Tree root does not match BytesMerkleRoot")
+	}
+	if tree.Leaves() != CalculateSegments(uint64(len(data))) {
+		t.Fatal("wrong number of leaves:", tree.Leaves())
+	}
+}
+
+// TestCachedProof checks that BuildCachedProof produces the same proofs as
+// BuildReaderProof, across multiple chunks and with a partial final segment.
+func TestCachedProof(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	data := make([]byte, 5*chunkSize/2+17)
+	rand.Read(data)
+	numSegments := CalculateSegments(uint64(len(data)))
+
+	cb := NewCacheBuilder()
+	cb.Write(data[:1000])
+	cb.Write(data[1000:])
+	root, err := BytesMerkleRoot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.Root() != root {
+		t.Fatal("CacheBuilder root does not match BytesMerkleRoot")
+	}
+	var cache bytes.Buffer
+	if _, err := cb.WriteTo(&cache); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []uint64{0, 1, chunkSegments - 1, chunkSegments, 2*chunkSegments + 5, numSegments - 1} {
+		base, hashSet, err := BuildCachedProof(bytes.NewReader(data), bytes.NewReader(cache.Bytes()), numSegments, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expBase, expHashSet, err := BuildReaderProof(bytes.NewReader(data), numSegments, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base != expBase || !reflect.DeepEqual(hashSet, expHashSet) {
+			t.Error("cached proof", i, "does not match BuildReaderProof")
+		}
+	}
+}
+
+// TestRangeProof builds and verifies a proof for every range of a number of
+// small trees, and checks that tampered proofs are rejected.
+func TestRangeProof(t *testing.T) {
+	for numSegments := uint64(1); numSegments <= 12; numSegments++ {
+		// the final segment is partial
+		data := make([]byte, numSegments*SegmentSize-5)
+		rand.Read(data)
+		root, err := BytesMerkleRoot(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for start := uint64(0); start < numSegments; start++ {
+			for end := start + 1; end <= numSegments; end++ {
+				proof, err := BuildRangeProof(bytes.NewReader(data), numSegments, start, end)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rangeData := data[start*SegmentSize:]
+				if end < numSegments {
+					rangeData = rangeData[:(end-start)*SegmentSize]
+				}
+				if !VerifyRangeProof(rangeData, proof, numSegments, start, end, root) {
+					t.Fatal("range proof", start, end, "of", numSegments, "did not pass verification")
+				}
+
+				// tamper with the data and the proof
+				bad := append([]byte(nil), rangeData...)
+				bad[0]++
+				if VerifyRangeProof(bad, proof, numSegments, start, end, root) {
+					t.Fatal("range proof verified tampered data")
+				}
+				if len(proof) > 0 {
+					if VerifyRangeProof(rangeData, proof[1:], numSegments, start, end, root) {
+						t.Fatal("range proof verified with missing hash")
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestMultiProof builds and verifies proofs for sets of segments.
+func TestMultiProof(t *testing.T) {
+	numSegments := uint64(13)
+	data := make([]byte, numSegments*SegmentSize)
+	rand.Read(data)
+	root, err := BytesMerkleRoot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, indices := range [][]uint64{{0}, {12}, {0, 12}, {2, 3, 7}, {1, 4, 5, 6, 11}} {
+		proof, err := BuildMultiProof(bytes.NewReader(data), numSegments, indices)
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments := make([][SegmentSize]byte, len(indices))
+		for i, index := range indices {
+			copy(segments[i][:], data[index*SegmentSize:])
+		}
+		if !VerifyMultiProof(segments, proof, numSegments, indices, root) {
+			t.Error("multi proof", indices, "did not pass verification")
+		}
+		segments[0][0]++
+		if VerifyMultiProof(segments, proof, numSegments, indices, root) {
+			t.Error("multi proof", indices, "verified tampered data")
+		}
+	}
+	if _, err := BuildMultiProof(bytes.NewReader(data), numSegments, []uint64{3, 2}); err == nil {
+		t.Error("built proof for unsorted indices")
+	}
+}
+
+// TestDiffProof checks that diff proofs produce the root of the modified file
+// for modifications, appends and truncations.
+func TestDiffProof(t *testing.T) {
+	tests := []struct {
+		oldSegments, newSegments uint64
+		modified                 []uint64
+	}{
+		{8, 8, []uint64{3}},
+		{13, 13, []uint64{0, 5, 6, 12}},
+		{7, 7, nil},
+		{5, 11, nil},
+		{8, 9, []uint64{2, 7}},
+		{13, 4, nil},
+		{13, 6, []uint64{1, 5}},
+		{1, 1, []uint64{0}},
+		{1, 3, nil},
+	}
+	for _, test := range tests {
+		oldData := make([]byte, test.oldSegments*SegmentSize)
+		rand.Read(oldData)
+		oldRoot, err := BytesMerkleRoot(oldData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// apply the change
+		newData := make([]byte, test.newSegments*SegmentSize)
+		copy(newData, oldData)
+		var changed [][SegmentSize]byte
+		for _, index := range test.modified {
+			var segment [SegmentSize]byte
+			rand.Read(segment[:])
+			copy(newData[index*SegmentSize:], segment[:])
+			changed = append(changed, segment)
+		}
+		for i := test.oldSegments; i < test.newSegments; i++ {
+			var segment [SegmentSize]byte
+			rand.Read(segment[:])
+			copy(newData[i*SegmentSize:], segment[:])
+			changed = append(changed, segment)
+		}
+		expRoot, err := BytesMerkleRoot(newData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proof, err := BuildDiffProof(bytes.NewReader(oldData), test.oldSegments, test.newSegments, test.modified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newRoot, ok := VerifyDiffProof(proof, test.oldSegments, test.newSegments, test.modified, changed, oldRoot)
+		if !ok {
+			t.Error("diff proof", test, "did not pass verification")
+		} else if newRoot != expRoot {
+			t.Error("diff proof", test, "produced the wrong root")
+		}
+
+		// a proof with a bad leaf should be rejected
+		proof.Leaves[0][0]++
+		if _, ok := VerifyDiffProof(proof, test.oldSegments, test.newSegments, test.modified, changed, oldRoot); ok {
+			t.Error("diff proof", test, "verified a tampered proof")
+		}
+	}
+}
+
+// TestParallelMerkleRoot checks that ParallelReaderMerkleRoot matches
+// ReaderMerkleRoot.
+func TestParallelMerkleRoot(t *testing.T) {
+	for _, size := range []int{1, SegmentSize + 1, chunkSize, chunkSize + 1, 3*chunkSize + chunkSize/3 + 7} {
+		data := make([]byte, size)
+		rand.Read(data)
+		numSegments := CalculateSegments(uint64(size))
+		root, err := ParallelReaderMerkleRoot(bytes.NewReader(data), numSegments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expRoot, err := ReaderMerkleRoot(bytes.NewReader(data), numSegments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != expRoot {
+			t.Error("parallel root does not match serial root for", size, "bytes")
+		}
+	}
+
+	// a reader that is too short should produce an error
+	data := make([]byte, 2*chunkSize)
+	if _, err := ParallelReaderMerkleRoot(bytes.NewReader(data), 3*chunkSegments); err == nil {
+		t.Error("expected error for short reader")
+	}
+}
@@ -0,0 +1,63 @@
+package hash
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ParallelReaderMerkleRoot returns the same root as ReaderMerkleRoot, hashing
+// the data on runtime.NumCPU() goroutines. The data is read sequentially and
+// split into chunks of 2^CacheHeight segments. Each chunk is the root of an
+// aligned subtree, so the roots of the chunks can be computed independently
+// and then joined with MerkleRoot. Only a few chunks are held in memory at a
+// time.
+func ParallelReaderMerkleRoot(reader io.Reader, numSegments uint64) (hash Hash, err error) {
+	// small files are not worth splitting
+	if numSegments <= chunkSegments {
+		return ReaderMerkleRoot(reader, numSegments)
+	}
+
+	type job struct {
+		index uint64
+		data  []byte
+	}
+	numChunks := (numSegments + chunkSegments - 1) / chunkSegments
+	roots := make([]Hash, numChunks)
+	jobs := make(chan job, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				tree := NewTree()
+				tree.Write(j.data)
+				roots[j.index] = tree.Root()
+			}
+		}()
+	}
+
+	for i := uint64(0); i < numChunks; i++ {
+		segments := numSegments - i*chunkSegments
+		if segments > chunkSegments {
+			segments = chunkSegments
+		}
+		data := make([]byte, segments*SegmentSize)
+		n, _ := io.ReadFull(reader, data)
+		// every segment must contain some data, as in ReaderMerkleRoot
+		if uint64(n) <= (segments-1)*SegmentSize {
+			err = errors.New("no data")
+			break
+		}
+		jobs <- job{i, data[:n]}
+	}
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return
+	}
+	hash = MerkleRoot(roots)
+	return
+}
@@ -0,0 +1,181 @@
+package hash
+
+import (
+	"errors"
+	"io"
+	"sort"
+)
+
+// Range and multi-segment proofs prove that a set of segments belongs to a
+// Merkle tree. Such a proof consists of the roots of every maximal subtree
+// that contains none of the proven segments, ordered from left to right. The
+// verifier rebuilds the root from the proven segments and those subtree
+// roots. A proof for k adjacent segments has at most 2*log(n) hashes, no
+// matter how large k is.
+
+var (
+	errBadRange   = errors.New("invalid segment range")
+	errBadIndices = errors.New("segment indices must be sorted, unique, and less than the number of segments")
+)
+
+// coverFunc reports whether some and whether all of the leaves in [lo, hi) are
+// being proven.
+type coverFunc func(lo, hi uint64) (some, all bool)
+
+// splitPoint returns the number of leaves in the left subtree of a tree with n
+// leaves, which is the largest power of 2 less than n. See MerkleRoot.
+func splitPoint(n uint64) (mid uint64) {
+	mid = 1
+	for mid < n/2+n%2 {
+		mid *= 2
+	}
+	return
+}
+
+// walkProof walks the tree of leaves [lo, hi), calling outside for each
+// maximal subtree that contains no proven leaves and inside for each maximal
+// subtree that contains only proven leaves. It returns the root of the tree,
+// as built from the return values of outside and inside.
+func walkProof(lo, hi uint64, cover coverFunc, outside, inside func(lo, hi uint64) (Hash, error)) (root Hash, err error) {
+	some, all := cover(lo, hi)
+	if !some {
+		return outside(lo, hi)
+	} else if all {
+		return inside(lo, hi)
+	}
+	mid := lo + splitPoint(hi-lo)
+	left, err := walkProof(lo, mid, cover, outside, inside)
+	if err != nil {
+		return
+	}
+	right, err := walkProof(mid, hi, cover, outside, inside)
+	if err != nil {
+		return
+	}
+	root = JoinHash(left, right)
+	return
+}
+
+// buildProof returns the roots of the subtrees that are not covered by cover,
+// reading their data from r.
+func buildProof(r io.ReaderAt, numSegments uint64, cover coverFunc) (proof []Hash, err error) {
+	outside := func(lo, hi uint64) (h Hash, err error) {
+		section := io.NewSectionReader(r, int64(lo)*SegmentSize, int64(hi-lo)*SegmentSize)
+		h, err = ReaderMerkleRoot(section, hi-lo)
+		proof = append(proof, h)
+		return
+	}
+	// the proven segments are sent separately, and the root that is built
+	// here is not needed
+	inside := func(lo, hi uint64) (Hash, error) {
+		return Hash{}, nil
+	}
+	_, err = walkProof(0, numSegments, cover, outside, inside)
+	return
+}
+
+// verifyProof rebuilds the Merkle root from a proof and the hashes of the
+// proven leaves, which are ordered by index. leafIndex returns the position of
+// leaf i within leaves.
+func verifyProof(leaves, proof []Hash, numSegments uint64, cover coverFunc, leafIndex func(i uint64) int, expectedRoot Hash) bool {
+	outside := func(lo, hi uint64) (h Hash, err error) {
+		if len(proof) == 0 {
+			err = errors.New("proof is too short")
+			return
+		}
+		h, proof = proof[0], proof[1:]
+		return
+	}
+	inside := func(lo, hi uint64) (Hash, error) {
+		i := leafIndex(lo)
+		return MerkleRoot(leaves[i : i+int(hi-lo)]), nil
+	}
+	root, err := walkProof(0, numSegments, cover, outside, inside)
+	return err == nil && len(proof) == 0 && root == expectedRoot
+}
+
+// rangeCover returns a coverFunc for the segments [start, end).
+func rangeCover(start, end uint64) coverFunc {
+	return func(lo, hi uint64) (some, all bool) {
+		return lo < end && start < hi, start <= lo && hi <= end
+	}
+}
+
+// indexCover returns a coverFunc for a sorted list of segment indices.
+func indexCover(indices []uint64) coverFunc {
+	return func(lo, hi uint64) (some, all bool) {
+		i := sort.Search(len(indices), func(j int) bool { return indices[j] >= lo })
+		j := sort.Search(len(indices), func(j int) bool { return indices[j] >= hi })
+		return j > i, uint64(j-i) == hi-lo
+	}
+}
+
+// checkIndices returns an error if indices is empty, unsorted, contains
+// duplicates, or contains an index that is out of range.
+func checkIndices(indices []uint64, numSegments uint64) error {
+	if len(indices) == 0 {
+		return errBadIndices
+	}
+	for i, index := range indices {
+		if index >= numSegments || (i > 0 && index <= indices[i-1]) {
+			return errBadIndices
+		}
+	}
+	return nil
+}
+
+// BuildRangeProof constructs a proof that the segments [start, end) belong to
+// the Merkle tree of the data in r, which has numSegments segments. The
+// segments themselves are not part of the proof.
+func BuildRangeProof(r io.ReaderAt, numSegments, start, end uint64) (proof []Hash, err error) {
+	if start >= end || end > numSegments {
+		err = errBadRange
+		return
+	}
+	return buildProof(r, numSegments, rangeCover(start, end))
+}
+
+// VerifyRangeProof checks that data holds the segments [start, end) of a file
+// with numSegments segments and the given Merkle root. If the range includes
+// the final segment of the file, data may end with a partial segment.
+func VerifyRangeProof(data []byte, proof []Hash, numSegments, start, end uint64, expectedRoot Hash) bool {
+	if start >= end || end > numSegments || CalculateSegments(uint64(len(data))) != end-start {
+		return false
+	}
+	leaves := make([]Hash, 0, end-start)
+	for len(data) > SegmentSize {
+		leaves = append(leaves, segmentHash(data[:SegmentSize]))
+		data = data[SegmentSize:]
+	}
+	leaves = append(leaves, segmentHash(data))
+	leafIndex := func(i uint64) int { return int(i - start) }
+	return verifyProof(leaves, proof, numSegments, rangeCover(start, end), leafIndex, expectedRoot)
+}
+
+// BuildMultiProof constructs a proof that the segments at indices belong to
+// the Merkle tree of the data in r, which has numSegments segments. indices
+// must be sorted and unique. The segments share their proof hashes, so the
+// proof is smaller than a separate proof for each segment.
+func BuildMultiProof(r io.ReaderAt, numSegments uint64, indices []uint64) (proof []Hash, err error) {
+	if err = checkIndices(indices, numSegments); err != nil {
+		return
+	}
+	return buildProof(r, numSegments, indexCover(indices))
+}
+
+// VerifyMultiProof checks that segments are the segments at indices of a file
+// with numSegments segments and the given Merkle root. A partial final
+// segment must be padded with zeros.
+func VerifyMultiProof(segments [][SegmentSize]byte, proof []Hash, numSegments uint64, indices []uint64, expectedRoot Hash) bool {
+	if checkIndices(indices, numSegments) != nil || len(segments) != len(indices) {
+		return false
+	}
+	leaves := make([]Hash, len(segments))
+	for i := range segments {
+		leaves[i] = HashBytes(segments[i][:])
+	}
+	leafIndex := func(i uint64) int {
+		return sort.Search(len(indices), func(j int) bool { return indices[j] >= i })
+	}
+	return verifyProof(leaves, proof, numSegments, indexCover(indices), leafIndex, expectedRoot)
+}
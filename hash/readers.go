@@ -12,38 +12,30 @@ import (
 func BytesMerkleRoot(data []byte) (hash Hash, err error) {
 	reader := bytes.NewReader(data)
 	numSegments := CalculateSegments(uint64(len(data)))
-	return ReaderMerkleRoot(reader, numSegments)
+	return ParallelReaderMerkleRoot(reader, numSegments)
 }
 
-// ReaderMerkleRoot splits the provided data into segments. It then recursively
-// transforms these segments into a Merkle tree, and returns the root hash.
-// See MerkleRoot for a diagram of how Merkle trees are constructed.
+// ReaderMerkleRoot splits the provided data into segments, and returns the
+// root hash of the Merkle tree formed by the segments. The data is read once,
+// using a Tree. See MerkleRoot for a diagram of how Merkle trees are
+// constructed.
 func ReaderMerkleRoot(reader io.Reader, numSegments uint64) (hash Hash, err error) {
 	if numSegments == 0 {
 		err = errors.New("no data")
 		return
 	}
-	if numSegments == 1 {
-		data := make([]byte, SegmentSize)
-		n, _ := reader.Read(data)
+
+	tree := NewTree()
+	segment := make([]byte, SegmentSize)
+	for i := uint64(0); i < numSegments; i++ {
+		n, _ := io.ReadFull(reader, segment)
 		if n == 0 {
 			err = errors.New("no data")
-		} else {
-			hash = HashBytes(data)
+			return
 		}
-		return
+		tree.Push(segment[:n])
 	}
-
-	// locate smallest power of 2 < numSegments
-	mid := uint64(1)
-	for mid < numSegments/2+numSegments%2 {
-		mid *= 2
-	}
-
-	// since we always read "left to right", no extra Seeking is necessary
-	left, err := ReaderMerkleRoot(reader, mid)
-	right, err := ReaderMerkleRoot(reader, numSegments-mid)
-	hash = JoinHash(left, right)
+	hash = tree.Root()
 	return
 }
 
@@ -0,0 +1,129 @@
+package hash
+
+// A Tree computes a Merkle root incrementally. Leaves are pushed into the tree
+// one at a time, and the root of the leaves pushed so far can be requested at
+// any point. Only one hash per level of the tree is kept in memory, so a tree
+// of n leaves uses O(log n) memory.
+//
+// The roots produced by a Tree are identical to those of MerkleRoot and
+// ReaderMerkleRoot. Since MerkleRoot always places the largest possible power
+// of 2 on the left, the leaves pushed so far can be represented by a stack of
+// perfect subtrees of strictly decreasing height. Pushing a leaf adds a
+// subtree of height 0, and merges it with the top of the stack for as long as
+// the heights match.
+//
+// A Tree is also an io.Writer, which splits the data written to it into
+// segments of SegmentSize bytes. The final segment may be partial, in which
+// case it is padded with zeros, as in ReaderMerkleRoot.
+type Tree struct {
+	stack []subTree
+
+	// buf holds a partial segment that has been written, but not yet pushed.
+	buf []byte
+}
+
+// A subTree is a perfect Merkle tree with 2^height leaves.
+type subTree struct {
+	height int
+	sum    Hash
+}
+
+// NewTree returns an empty Tree.
+func NewTree() *Tree {
+	return new(Tree)
+}
+
+// PushHash adds a leaf hash to the tree.
+func (t *Tree) PushHash(h Hash) {
+	if len(t.buf) != 0 {
+		panic("leaves cannot be pushed while a partial segment is pending")
+	}
+	st := subTree{0, h}
+	for len(t.stack) > 0 && t.stack[len(t.stack)-1].height == st.height {
+		top := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		st = subTree{st.height + 1, JoinHash(top.sum, st.sum)}
+	}
+	t.stack = append(t.stack, st)
+}
+
+// Push adds a segment of data to the tree. The segment is padded with zeros to
+// SegmentSize bytes before being hashed. Push panics if the segment is larger
+// than SegmentSize.
+func (t *Tree) Push(segment []byte) {
+	t.PushHash(segmentHash(segment))
+}
+
+// Write implements io.Writer, pushing each complete segment of the data into
+// the tree. A trailing partial segment is held until more data is written, or
+// until Root is called.
+func (t *Tree) Write(p []byte) (n int, err error) {
+	n = len(p)
+	// complete the pending segment, if there is one
+	if len(t.buf) != 0 {
+		fill := SegmentSize - len(t.buf)
+		if fill > len(p) {
+			fill = len(p)
+		}
+		t.buf = append(t.buf, p[:fill]...)
+		p = p[fill:]
+		if len(t.buf) < SegmentSize {
+			return
+		}
+		segment := t.buf
+		t.buf = nil
+		t.Push(segment)
+	}
+	for len(p) >= SegmentSize {
+		t.Push(p[:SegmentSize])
+		p = p[SegmentSize:]
+	}
+	t.buf = append(t.buf, p...)
+	return
+}
+
+// Root returns the Merkle root of the leaves pushed so far. If a partial
+// segment has been written, it is included as the final leaf. The root of an
+// empty tree is the zero hash.
+func (t *Tree) Root() (root Hash) {
+	if len(t.stack) == 0 && len(t.buf) == 0 {
+		return
+	}
+
+	// join the subtrees from right to left, starting with the partial
+	// segment
+	i := len(t.stack) - 1
+	if len(t.buf) != 0 {
+		root = segmentHash(t.buf)
+	} else {
+		root = t.stack[i].sum
+		i--
+	}
+	for ; i >= 0; i-- {
+		root = JoinHash(t.stack[i].sum, root)
+	}
+	return
+}
+
+// Leaves returns the number of leaves in the tree, including a pending partial
+// segment.
+func (t *Tree) Leaves() (leaves uint64) {
+	for _, st := range t.stack {
+		leaves += 1 << uint(st.height)
+	}
+	if len(t.buf) != 0 {
+		leaves++
+	}
+	return
+}
+
+// segmentHash returns the leaf hash of a segment, which is padded with zeros
+// to SegmentSize bytes.
+func segmentHash(segment []byte) Hash {
+	if len(segment) > SegmentSize {
+		panic("segment is larger than SegmentSize")
+	}
+	var padded [SegmentSize]byte
+	copy(padded[:], segment)
+	return HashBytes(padded[:])
+}
@@ -0,0 +1,215 @@
+package network
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/hash"
+)
+
+const (
+	discoveryInterval  = time.Second * 30
+	maxAnnouncementLen = 256
+
+	// maxDiscoveryHandlers is the number of announcements that are handled
+	// at once, and maxDiscoverySources is the number of sources whose recent
+	// announcements are remembered. Announcements beyond these limits are
+	// dropped, so that a flood of announcements cannot exhaust the server.
+	maxDiscoveryHandlers = 8
+	maxDiscoverySources  = 256
+)
+
+var (
+	errDiscoveryTransport = errors.New("LAN discovery requires the TCP transport")
+)
+
+var (
+	// DiscoveryGroup is the UDP multicast group used for local peer
+	// discovery. Only nodes on the same broadcast domain will receive
+	// announcements.
+	DiscoveryGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 83, 73), Port: 9987}
+)
+
+// A discoveryAnnouncement is multicast periodically by each server that has
+// discovery enabled. The hostname of the sender is taken from the source of
+// the packet, so only the port of the RPC address is announced. The Nonce is
+// random, and allows a server to recognize its own announcements.
+type discoveryAnnouncement struct {
+	GenesisID hash.Hash
+	Port      uint64
+	Nonce     [8]byte
+}
+
+// StartDiscovery announces the server on the local network and listens for
+// announcements from other servers. Servers that announce the same genesis ID
+// and respond to a ping are added to the address book, after which found is
+// called with their address, unless it is nil. Discovery stops when the server
+// is closed.
+//
+// Discovery uses UDP multicast on the real network, so it cannot be used by a
+// server with a Transport other than the default.
+func (tcps *TCPServer) StartDiscovery(genesisID hash.Hash, found func(Address)) (err error) {
+	if _, ok := tcps.transport.(tcpTransport); !ok {
+		return errDiscoveryTransport
+	}
+	_, portStr, err := net.SplitHostPort(string(tcps.myAddr))
+	if err != nil {
+		return
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return
+	}
+	announcement := discoveryAnnouncement{
+		GenesisID: genesisID,
+		Port:      port,
+	}
+	if _, err = rand.Read(announcement.Nonce[:]); err != nil {
+		return
+	}
+
+	listener, err := net.ListenMulticastUDP("udp4", nil, DiscoveryGroup)
+	if err != nil {
+		return
+	}
+	sender, err := net.DialUDP("udp4", nil, DiscoveryGroup)
+	if err != nil {
+		listener.Close()
+		return
+	}
+	go func() {
+		<-tcps.ctx.Done()
+		listener.Close()
+		sender.Close()
+	}()
+
+	go tcps.announce(sender, announcement)
+	go tcps.listenDiscovery(listener, announcement, found)
+	return
+}
+
+// announce multicasts the announcement every discoveryInterval until the
+// server is closed.
+func (tcps *TCPServer) announce(conn *net.UDPConn, announcement discoveryAnnouncement) {
+	packet := encoding.Marshal(announcement)
+	for {
+		// TODO: log error
+		conn.Write(packet)
+		select {
+		case <-time.After(discoveryInterval):
+		case <-tcps.ctx.Done():
+			return
+		}
+	}
+}
+
+// listenDiscovery reads announcements from the multicast group until the
+// connection is closed. Each announcement is handled in its own goroutine, so
+// that an announcer that is slow to answer its ping does not hold up the
+// others. The number of goroutines is limited by a discoveryLimiter.
+func (tcps *TCPServer) listenDiscovery(conn *net.UDPConn, own discoveryAnnouncement, found func(Address)) {
+	dl := newDiscoveryLimiter()
+	for {
+		buf := make([]byte, maxAnnouncementLen)
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if !dl.acquire(src.String(), time.Now()) {
+			continue
+		}
+		go func() {
+			defer dl.release()
+			// TODO: log error
+			tcps.handleAnnouncement(buf[:n], src, own, found)
+		}()
+	}
+}
+
+// A discoveryLimiter decides which announcements are handled. Each source is
+// handled at most once per half discoveryInterval, which is enough to see
+// each of its periodic announcements, and at most maxDiscoveryHandlers
+// announcements are handled at once. acquire is only called by the goroutine
+// reading announcements.
+type discoveryLimiter struct {
+	handlers chan struct{}
+	recent   map[string]time.Time
+}
+
+// newDiscoveryLimiter returns a discoveryLimiter with no handlers running.
+func newDiscoveryLimiter() *discoveryLimiter {
+	return &discoveryLimiter{
+		handlers: make(chan struct{}, maxDiscoveryHandlers),
+		recent:   make(map[string]time.Time),
+	}
+}
+
+// acquire reports whether an announcement from src, received at now, should
+// be handled. If it returns true, release must be called once the
+// announcement has been handled.
+func (dl *discoveryLimiter) acquire(src string, now time.Time) bool {
+	if last, exists := dl.recent[src]; exists && now.Sub(last) < discoveryInterval/2 {
+		return false
+	}
+	if len(dl.recent) >= maxDiscoverySources {
+		for s, last := range dl.recent {
+			if now.Sub(last) >= discoveryInterval/2 {
+				delete(dl.recent, s)
+			}
+		}
+		if len(dl.recent) >= maxDiscoverySources {
+			return false
+		}
+	}
+	select {
+	case dl.handlers <- struct{}{}:
+	default:
+		return false
+	}
+	dl.recent[src] = now
+	return true
+}
+
+// release marks a handler acquired with acquire as finished.
+func (dl *discoveryLimiter) release() {
+	<-dl.handlers
+}
+
+// handleAnnouncement decodes an announcement and adds the announcing server
+// to the address book, calling found if it is not nil. Announcements from
+// other networks, from this server, and from servers that do not respond to a
+// ping are rejected.
+func (tcps *TCPServer) handleAnnouncement(packet []byte, src *net.UDPAddr, own discoveryAnnouncement, found func(Address)) error {
+	var announcement discoveryAnnouncement
+	if err := encoding.Unmarshal(packet, &announcement); err != nil {
+		return err
+	}
+	if announcement.GenesisID != own.GenesisID {
+		return errors.New("announcement is for a different network")
+	} else if announcement.Nonce == own.Nonce {
+		return errors.New("announcement was sent by this server")
+	}
+
+	addr := Address(net.JoinHostPort(src.IP.String(), strconv.FormatUint(announcement.Port, 10)))
+	tcps.RLock()
+	_, exists := tcps.addressbook[addr]
+	tcps.RUnlock()
+	if exists {
+		return nil
+	}
+	if !tcps.Ping(addr) {
+		return errors.New("announced address did not respond to ping")
+	}
+	// another goroutine may have added the same server while it was pinged
+	if err := tcps.AddPeer(addr); err != nil {
+		return nil
+	}
+	if found != nil {
+		found(addr)
+	}
+	return nil
+}
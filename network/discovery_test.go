@@ -0,0 +1,110 @@
+package network
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/hash"
+)
+
+// TestHandleAnnouncement checks that discovery announcements are only
+// accepted when they come from a different server on the same network.
+func TestHandleAnnouncement(t *testing.T) {
+	mn := NewMemNetwork()
+	foo, err := NewTCPServerWithTransport("127.0.0.1:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer foo.Close()
+	bar, err := NewTCPServerWithTransport("127.0.0.1:2", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bar.Close()
+
+	genesisID := hash.HashBytes([]byte("genesis"))
+	own := discoveryAnnouncement{GenesisID: genesisID, Port: 1, Nonce: [8]byte{1}}
+	src := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+
+	// discovery uses the real network, so it is refused on a MemNetwork
+	if foo.StartDiscovery(genesisID, nil) != errDiscoveryTransport {
+		t.Error("discovery was started on an in-memory transport")
+	}
+
+	// an announcement for a different network should be rejected
+	announcement := discoveryAnnouncement{Port: 2, Nonce: [8]byte{2}}
+	if foo.handleAnnouncement(encoding.Marshal(announcement), src, own, nil) == nil {
+		t.Error("accepted announcement with a different genesis ID")
+	}
+
+	// an announcement from ourselves should be rejected
+	if foo.handleAnnouncement(encoding.Marshal(own), src, own, nil) == nil {
+		t.Error("accepted announcement from self")
+	}
+
+	// an announcement for a server that does not exist should be rejected
+	announcement = discoveryAnnouncement{GenesisID: genesisID, Port: 3, Nonce: [8]byte{3}}
+	if foo.handleAnnouncement(encoding.Marshal(announcement), src, own, nil) == nil {
+		t.Error("accepted announcement from an unreachable server")
+	}
+
+	// a valid announcement should add the server to the address book, and
+	// report it as found
+	var found []Address
+	addFound := func(addr Address) { found = append(found, addr) }
+	announcement = discoveryAnnouncement{GenesisID: genesisID, Port: 2, Nonce: [8]byte{2}}
+	if err := foo.handleAnnouncement(encoding.Marshal(announcement), src, own, addFound); err != nil {
+		t.Fatal(err)
+	}
+	if len(foo.AddressBook()) != 1 || foo.AddressBook()[0] != bar.Address() {
+		t.Fatal("announced server was not added:", foo.AddressBook())
+	}
+	if len(found) != 1 || found[0] != bar.Address() {
+		t.Fatal("announced server was not reported as found:", found)
+	}
+
+	// repeated announcements should be ignored
+	if err := foo.handleAnnouncement(encoding.Marshal(announcement), src, own, addFound); err != nil {
+		t.Error(err)
+	}
+	if len(found) != 1 {
+		t.Error("repeated announcement was reported as found")
+	}
+}
+
+// TestDiscoveryLimiter checks that announcements are dropped when a source
+// announces too often, or when too many announcements are being handled.
+func TestDiscoveryLimiter(t *testing.T) {
+	dl := newDiscoveryLimiter()
+	now := time.Now()
+
+	// a source is only handled once per half interval
+	if !dl.acquire("foo", now) {
+		t.Fatal("first announcement was dropped")
+	}
+	dl.release()
+	if dl.acquire("foo", now.Add(time.Second)) {
+		t.Error("repeated announcement was handled")
+	}
+	if !dl.acquire("foo", now.Add(discoveryInterval/2)) {
+		t.Error("periodic announcement was dropped")
+	}
+	dl.release()
+
+	// at most maxDiscoveryHandlers announcements are handled at once
+	for i := 0; i < maxDiscoveryHandlers; i++ {
+		if !dl.acquire(strconv.Itoa(i), now) {
+			t.Fatal("announcement was dropped before the limit was reached")
+		}
+	}
+	if dl.acquire("bar", now) {
+		t.Error("announcement was handled beyond the limit")
+	}
+	dl.release()
+	if !dl.acquire("bar", now) {
+		t.Error("announcement was dropped after a handler finished")
+	}
+}
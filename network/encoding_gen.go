@@ -0,0 +1,36 @@
+// Code generated by encoding.Generate; DO NOT EDIT.
+
+package network
+
+import (
+	"github.com/NebulousLabs/Sia/encoding"
+)
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x RPCError) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *RPCError) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x RPCError) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *RPCError) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.Message)))
+	b = append(b, x.Message...)
+	b = encoding.AppendUint64(b, x.Code)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *RPCError) ReadSia(r *encoding.SliceReader) {
+	x.Message = r.ReadString()
+	x.Code = r.ReadUint64()
+}
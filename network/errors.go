@@ -0,0 +1,119 @@
+package network
+
+//go:generate go run gen.go
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/encoding"
+)
+
+// Error codes are registered by each package that sends errors over the
+// network. To avoid collisions, each package uses its own range of codes:
+//
+//	network:     1 -  99
+//	sia:       100 - 199
+//	sia/host:  200 - 299
+//
+// Code 0 is reserved for errors that have not been registered.
+var (
+	errorCodes   = make(map[uint64]*RPCError)
+	errorCodesMu sync.Mutex
+)
+
+// An RPCError is an error that keeps its identity when sent over the network.
+// The Code identifies the error, and the Message describes it. Callers can
+// compare a received error against a registered error using errors.Is, even
+// if the message has been extended with additional detail.
+//
+// An RPCError is also the encoded form of a registered error sent at the end
+// of an RPC. The Message comes first, so that nodes which expect only a
+// message fail to decode it, rather than mistaking the Code for a message.
+type RPCError struct {
+	Message string
+	Code    uint64
+}
+
+// RegisterError creates an RPCError with the given code and message. It
+// panics if the code is 0 or has already been registered. RegisterError is
+// intended to be called when initializing package-level error variables.
+func RegisterError(code uint64, message string) *RPCError {
+	errorCodesMu.Lock()
+	defer errorCodesMu.Unlock()
+	if code == 0 {
+		panic("error code 0 is reserved")
+	} else if existing, exists := errorCodes[code]; exists {
+		panic(fmt.Sprintf("error code %v already registered as %q", code, existing.Message))
+	}
+	e := &RPCError{Code: code, Message: message}
+	errorCodes[code] = e
+	return e
+}
+
+// Error implements the error interface.
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is an RPCError with the same code, allowing
+// errors.Is to match errors that were received over the network.
+func (e *RPCError) Is(target error) bool {
+	t, ok := target.(*RPCError)
+	return ok && t.Code == e.Code
+}
+
+// Extend returns a copy of the error with detail appended to its message. The
+// copy has the same code as the original.
+func (e *RPCError) Extend(detail string) *RPCError {
+	return &RPCError{
+		Code:    e.Code,
+		Message: e.Message + ": " + detail,
+	}
+}
+
+// WriteError writes an error to w, such that it can be read with ReadError. A
+// nil error is written to indicate success.
+//
+// Earlier versions sent only the message of the error, as a string. To remain
+// compatible with them, successes and unregistered errors are still sent that
+// way, and only registered errors are sent as an RPCError. A node running an
+// earlier version cannot decode an RPCError, due to the Code that follows the
+// message, so it reports the call as failed, though without its message.
+func WriteError(w io.Writer, err error) (werr error) {
+	var rpcErr *RPCError
+	if err == nil {
+		_, werr = encoding.WriteObject(w, "")
+	} else if errors.As(err, &rpcErr) {
+		_, werr = encoding.WriteObject(w, RPCError{Message: err.Error(), Code: rpcErr.Code})
+	} else {
+		_, werr = encoding.WriteObject(w, err.Error())
+	}
+	return
+}
+
+// ReadError reads an error that was written with WriteError. If the remote
+// error was registered, an *RPCError with the same code is returned. If the
+// remote error was not registered, an error with the same message is
+// returned. If the remote call succeeded, nil is returned. Any failure to read
+// the error is also returned.
+func ReadError(r io.Reader) error {
+	b, err := encoding.ReadPrefix(r, maxMsgLen)
+	if err != nil {
+		return err
+	}
+	var re RPCError
+	if err := encoding.Unmarshal(b, &re.Message); err != nil {
+		if encoding.Unmarshal(b, &re) != nil {
+			return err
+		}
+	}
+	if re.Code != 0 {
+		return &re
+	} else if re.Message != "" {
+		return errors.New(re.Message)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+// +build ignore
+
+// This program generates encoding_gen.go. It is run by go generate.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/network"
+)
+
+func main() {
+	err := encoding.GenerateFile("encoding_gen.go", "github.com/NebulousLabs/Sia/network",
+		network.RPCError{},
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"context"
 	"net"
 
 	"github.com/NebulousLabs/Sia/encoding"
@@ -9,8 +10,18 @@ import (
 // Ping returns whether an Address is reachable and responds correctly to the
 // ping request -- in other words, whether it is a potential peer.
 func Ping(addr Address) bool {
+	return ping(context.Background(), DefaultTransport, addr)
+}
+
+// Ping is like the package-level Ping, but uses the server's Transport.
+func (tcps *TCPServer) Ping(addr Address) bool {
+	return ping(tcps.ctx, tcps.transport, addr)
+}
+
+// ping sends a ping request to addr using the provided Transport.
+func ping(ctx context.Context, t Transport, addr Address) bool {
 	var pong string
-	err := addr.RPC("Ping", nil, &pong)
+	err := rpc(ctx, t, addr, "Ping", nil, &pong)
 	return err == nil && pong == "pong"
 }
 
@@ -18,12 +29,17 @@ func pong() (string, error) {
 	return "pong", nil
 }
 
+var (
+	errHostnameMismatch = RegisterError(1, "supplied hostname does not match connection's hostname")
+	errNoPingResponse   = RegisterError(2, "supplied hostname did not respond to ping")
+)
+
 // sendHostname replies to the sender with the sender's external IP.
 func sendHostname(conn net.Conn) error {
 	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
 	_, err := encoding.WriteObject(conn, host)
 	// write error
-	encoding.WriteObject(conn, "")
+	WriteError(conn, nil)
 	return err
 }
 
@@ -49,16 +65,16 @@ func (tcps *TCPServer) addRemote(conn net.Conn) (err error) {
 	connHost, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
 	addrHost, _, _ := net.SplitHostPort(string(addr))
 	if connHost != addrHost {
-		_, err = encoding.WriteObject(conn, "supplied hostname does not match connection's hostname")
+		err = WriteError(conn, errHostnameMismatch)
 		return
 	}
 	// check that the host is reachable on this port
-	if !Ping(addr) {
-		_, err = encoding.WriteObject(conn, "supplied hostname did not respond to ping")
+	if !tcps.Ping(addr) {
+		err = WriteError(conn, errNoPingResponse)
 		return
 	}
 	tcps.AddPeer(addr)
 	// write error
-	encoding.WriteObject(conn, "")
+	WriteError(conn, nil)
 	return
 }
@@ -0,0 +1,257 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrConnRefused is returned when dialing an address of a MemNetwork
+	// that has no listener.
+	ErrConnRefused = errors.New("connection refused")
+
+	// ErrPacketLoss is returned when a connection attempt is dropped by the
+	// simulated packet loss of a MemNetwork.
+	ErrPacketLoss = errors.New("connection lost")
+
+	// ErrPartitioned is returned when dialing an address that is in a
+	// different partition of a MemNetwork.
+	ErrPartitioned = errors.New("destination is unreachable from this partition")
+)
+
+// A MemNetwork is an in-memory network that connects any number of servers
+// within a single process. Connections are built on net.Pipe, and the network
+// can be configured to add latency, drop connections, or split its members
+// into partitions. This makes it possible to run entire clusters of nodes
+// inside of a test without relying on real ports.
+type MemNetwork struct {
+	listeners  map[Address]*memListener
+	partitions map[Address]int
+	latency    time.Duration
+	loss       float64
+
+	mu sync.RWMutex
+}
+
+// memTransport is a Transport that routes connections through a MemNetwork.
+// The address of a memTransport is set when it starts listening, after which
+// it is used as the source address of every connection that it dials.
+type memTransport struct {
+	network *MemNetwork
+	addr    Address
+
+	// protects addr, which is read by Dial from other goroutines
+	mu sync.RWMutex
+}
+
+// memAddr is the net.Addr of an endpoint in a MemNetwork.
+type memAddr Address
+
+// memConn wraps one end of a net.Pipe, reporting the MemNetwork addresses of
+// each endpoint and delaying each write by the latency of the network.
+type memConn struct {
+	net.Conn
+	network    *MemNetwork
+	localAddr  memAddr
+	remoteAddr memAddr
+}
+
+// memListener is a net.Listener that accepts connections from a MemNetwork.
+type memListener struct {
+	network *MemNetwork
+	addr    memAddr
+	conns   chan net.Conn
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewMemNetwork returns an empty MemNetwork with no latency, no packet loss,
+// and no partitions.
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{
+		listeners:  make(map[Address]*memListener),
+		partitions: make(map[Address]int),
+	}
+}
+
+// Transport returns a new Transport that is connected to the MemNetwork. Each
+// server should be given its own Transport.
+func (mn *MemNetwork) Transport() Transport {
+	return &memTransport{network: mn}
+}
+
+// SetLatency sets the delay that is added to every connection attempt and to
+// every write.
+func (mn *MemNetwork) SetLatency(latency time.Duration) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.latency = latency
+}
+
+// SetLoss sets the probability, between 0 and 1, that a connection attempt
+// will be dropped.
+func (mn *MemNetwork) SetLoss(loss float64) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.loss = loss
+}
+
+// Partition splits the network into the provided groups of addresses.
+// Addresses in different groups cannot connect to each other. Addresses that
+// do not appear in any group form an additional group of their own. Any
+// previous partitioning is discarded.
+func (mn *MemNetwork) Partition(groups ...[]Address) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.partitions = make(map[Address]int)
+	for i, group := range groups {
+		for _, addr := range group {
+			mn.partitions[addr] = i + 1
+		}
+	}
+}
+
+// Heal removes all partitions from the network.
+func (mn *MemNetwork) Heal() {
+	mn.Partition()
+}
+
+// dial connects src to dest, returning the client end of the connection.
+func (mn *MemNetwork) dial(ctx context.Context, src, dest Address) (net.Conn, error) {
+	mn.mu.RLock()
+	l, exists := mn.listeners[dest]
+	latency, loss := mn.latency, mn.loss
+	partitioned := mn.partitions[src] != mn.partitions[dest]
+	mn.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrConnRefused
+	} else if partitioned {
+		return nil, ErrPartitioned
+	} else if loss > 0 && rand.Float64() < loss {
+		return nil, ErrPacketLoss
+	}
+	select {
+	case <-time.After(latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	client, server := net.Pipe()
+	clientConn := &memConn{client, mn, memAddr(src), memAddr(dest)}
+	serverConn := &memConn{server, mn, memAddr(dest), memAddr(src)}
+	select {
+	case l.conns <- serverConn:
+		return clientConn, nil
+	case <-l.closed:
+		client.Close()
+		server.Close()
+		return nil, ErrConnRefused
+	case <-ctx.Done():
+		client.Close()
+		server.Close()
+		return nil, ctx.Err()
+	case <-time.After(timeout):
+		client.Close()
+		server.Close()
+		return nil, errors.New("connection timed out")
+	}
+}
+
+// listen registers a listener for the given address.
+func (mn *MemNetwork) listen(addr Address) (*memListener, error) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	if _, exists := mn.listeners[addr]; exists {
+		return nil, errors.New("address already in use")
+	}
+	l := &memListener{
+		network: mn,
+		addr:    memAddr(addr),
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+	mn.listeners[addr] = l
+	return l, nil
+}
+
+// Dial implements the Transport interface.
+func (mt *memTransport) Dial(ctx context.Context, addr Address) (net.Conn, error) {
+	mt.mu.RLock()
+	src := mt.addr
+	mt.mu.RUnlock()
+	return mt.network.dial(ctx, src, addr)
+}
+
+// Listen implements the Transport interface.
+func (mt *memTransport) Listen(addr string) (net.Listener, error) {
+	l, err := mt.network.listen(Address(addr))
+	if err != nil {
+		return nil, err
+	}
+	mt.mu.Lock()
+	mt.addr = Address(addr)
+	mt.mu.Unlock()
+	return l, nil
+}
+
+// Network implements the net.Addr interface.
+func (ma memAddr) Network() string {
+	return "mem"
+}
+
+// String implements the net.Addr interface.
+func (ma memAddr) String() string {
+	return string(ma)
+}
+
+// LocalAddr implements the net.Conn interface.
+func (mc *memConn) LocalAddr() net.Addr {
+	return mc.localAddr
+}
+
+// RemoteAddr implements the net.Conn interface.
+func (mc *memConn) RemoteAddr() net.Addr {
+	return mc.remoteAddr
+}
+
+// Write implements the net.Conn interface, delaying the write according to
+// the latency of the network.
+func (mc *memConn) Write(b []byte) (int, error) {
+	mc.network.mu.RLock()
+	latency := mc.network.latency
+	mc.network.mu.RUnlock()
+	time.Sleep(latency)
+	return mc.Conn.Write(b)
+}
+
+// Accept implements the net.Listener interface.
+func (ml *memListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-ml.conns:
+		return conn, nil
+	case <-ml.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+// Close implements the net.Listener interface. The address of the listener is
+// released, so that another listener may take its place.
+func (ml *memListener) Close() error {
+	ml.once.Do(func() {
+		ml.network.mu.Lock()
+		delete(ml.network.listeners, Address(ml.addr))
+		ml.network.mu.Unlock()
+		close(ml.closed)
+	})
+	return nil
+}
+
+// Addr implements the net.Listener interface.
+func (ml *memListener) Addr() net.Addr {
+	return ml.addr
+}
@@ -0,0 +1,140 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemNetwork creates two servers on an in-memory network and checks that
+// they can make RPCs to each other.
+func TestMemNetwork(t *testing.T) {
+	mn := NewMemNetwork()
+	foo, err := NewTCPServerWithTransport("foo:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer foo.Close()
+	bar, err := NewTCPServerWithTransport("bar:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bar.Close()
+
+	// the address should not be reusable while foo is listening
+	if _, err := NewTCPServerWithTransport("foo:1", mn.Transport()); err == nil {
+		t.Fatal("two servers were able to listen on the same address")
+	}
+
+	if !foo.Ping(bar.Address()) || !bar.Ping(foo.Address()) {
+		t.Fatal("servers could not ping each other")
+	}
+
+	// the server should see the correct remote hostname
+	var hostname string
+	err = foo.RPC(bar.Address(), "SendHostname", nil, &hostname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostname != "foo" {
+		t.Fatal("expected hostname foo, got", hostname)
+	}
+
+	// addRemote should succeed, since the hostname matches
+	err = foo.RPC(bar.Address(), "AddMe", foo.Address(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bar.AddressBook()) != 1 || bar.AddressBook()[0] != foo.Address() {
+		t.Fatal("bar did not add foo as a peer:", bar.AddressBook())
+	}
+
+	// connections to unknown addresses should be refused
+	if foo.Ping("baz:1") {
+		t.Fatal("ping to nonexistent server succeeded")
+	}
+}
+
+// TestMemNetworkPartition checks that servers in different partitions cannot
+// communicate, and that communication resumes after the partition is healed.
+func TestMemNetworkPartition(t *testing.T) {
+	mn := NewMemNetwork()
+	foo, err := NewTCPServerWithTransport("foo:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer foo.Close()
+	bar, err := NewTCPServerWithTransport("bar:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bar.Close()
+	baz, err := NewTCPServerWithTransport("baz:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer baz.Close()
+
+	mn.Partition([]Address{foo.Address(), bar.Address()})
+	if !foo.Ping(bar.Address()) {
+		t.Error("servers in the same partition could not communicate")
+	}
+	if foo.Ping(baz.Address()) || baz.Ping(bar.Address()) {
+		t.Error("servers in different partitions were able to communicate")
+	}
+
+	mn.Heal()
+	if !foo.Ping(baz.Address()) || !baz.Ping(bar.Address()) {
+		t.Error("servers could not communicate after partition was healed")
+	}
+}
+
+// TestMemNetworkConditions checks the latency and packet loss settings of the
+// MemNetwork.
+func TestMemNetworkConditions(t *testing.T) {
+	mn := NewMemNetwork()
+	foo, err := NewTCPServerWithTransport("foo:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer foo.Close()
+
+	// a ping involves a dial and at least two writes
+	mn.SetLatency(10 * time.Millisecond)
+	start := time.Now()
+	if !foo.Ping(foo.Address()) {
+		t.Fatal("ping failed with latency enabled")
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Error("latency was not applied")
+	}
+	mn.SetLatency(0)
+
+	mn.SetLoss(1)
+	if foo.Ping(foo.Address()) {
+		t.Error("ping succeeded despite total packet loss")
+	}
+	mn.SetLoss(0)
+	if !foo.Ping(foo.Address()) {
+		t.Error("ping failed after packet loss was disabled")
+	}
+}
+
+// TestMemTransportListen checks that a Transport can dial while another
+// goroutine starts it listening, as a node does when it dials during startup.
+// It is most useful when run with the race detector.
+func TestMemTransportListen(t *testing.T) {
+	mn := NewMemNetwork()
+	mt := mn.Transport()
+	done := make(chan struct{})
+	go func() {
+		mt.Dial(context.Background(), "foo:1")
+		close(done)
+	}()
+	l, err := mt.Listen("bar:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	<-done
+}
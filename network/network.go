@@ -1,6 +1,7 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"net"
@@ -27,12 +28,17 @@ var (
 type Address string
 
 // A TCPServer sends and receives messages. It also maintains an address book
-// of peers to broadcast to and make requests of.
+// of peers to broadcast to and make requests of. Connections are made through
+// the server's Transport, which uses TCP unless otherwise specified.
 type TCPServer struct {
 	net.Listener
+	transport   Transport
 	myAddr      Address
 	addressbook map[Address]struct{}
 	handlerMap  map[string]func(net.Conn) error
+	// cancelled when the server is closed, aborting any outgoing calls
+	ctx    context.Context
+	cancel context.CancelFunc
 	// used to protect addressbook and handlerMap
 	sync.RWMutex
 }
@@ -60,7 +66,7 @@ func (tcps *TCPServer) setHostname(host string) bool {
 	_, port, _ := net.SplitHostPort(string(tcps.myAddr))
 	newAddr := Address(net.JoinHostPort(host, port))
 	// try to ping ourselves
-	if !Ping(newAddr) {
+	if !tcps.Ping(newAddr) {
 		return false
 	}
 	tcps.myAddr = newAddr
@@ -89,6 +95,13 @@ func (tcps *TCPServer) RemovePeer(addr Address) error {
 	return nil
 }
 
+// Close stops the server from accepting connections and aborts any calls that
+// the server is currently making.
+func (tcps *TCPServer) Close() error {
+	tcps.cancel()
+	return tcps.Listener.Close()
+}
+
 // RandomPeer selects and returns a random peer from the address book.
 func (tcps *TCPServer) RandomPeer() Address {
 	addrs := tcps.AddressBook()
@@ -160,7 +173,7 @@ func (tcps *TCPServer) getExternalIP() (err error) {
 func (tcps *TCPServer) Bootstrap() (err error) {
 	// populate initial peer list
 	for _, addr := range BootstrapPeers {
-		if Ping(addr) {
+		if tcps.Ping(addr) {
 			tcps.AddPeer(addr)
 		}
 	}
@@ -175,7 +188,7 @@ func (tcps *TCPServer) Bootstrap() (err error) {
 	var set bool
 	for _, addr := range tcps.AddressBook() {
 		var hostname string
-		if err := addr.RPC("SendHostname", nil, &hostname); err == nil {
+		if err := tcps.RPC(addr, "SendHostname", nil, &hostname); err == nil {
 			tcps.setHostname(hostname)
 			set = true
 			break
@@ -191,11 +204,11 @@ func (tcps *TCPServer) Bootstrap() (err error) {
 	var peers []Address
 	for _, addr := range tcps.AddressBook() {
 		var resp []Address
-		addr.RPC("SharePeers", nil, &resp)
+		tcps.RPC(addr, "SharePeers", nil, &resp)
 		peers = append(peers, resp...)
 	}
 	for _, addr := range peers {
-		if addr != tcps.myAddr && Ping(addr) {
+		if addr != tcps.myAddr && tcps.Ping(addr) {
 			tcps.AddPeer(addr)
 		}
 	}
@@ -206,18 +219,27 @@ func (tcps *TCPServer) Bootstrap() (err error) {
 	return
 }
 
-// NewTCPServer creates a TCPServer that listens on the specified address.
-func NewTCPServer(addr string) (tcps *TCPServer, err error) {
-	tcpServ, err := net.Listen("tcp", addr)
+// NewTCPServer creates a TCPServer that listens on the specified address,
+// using the DefaultTransport.
+func NewTCPServer(addr string) (*TCPServer, error) {
+	return NewTCPServerWithTransport(addr, DefaultTransport)
+}
+
+// NewTCPServerWithTransport creates a TCPServer that listens on the specified
+// address, using t for all incoming and outgoing connections.
+func NewTCPServerWithTransport(addr string, t Transport) (tcps *TCPServer, err error) {
+	listener, err := t.Listen(addr)
 	if err != nil {
 		return
 	}
 	tcps = &TCPServer{
-		Listener:    tcpServ,
+		Listener:    listener,
+		transport:   t,
 		myAddr:      Address(addr),
 		addressbook: make(map[Address]struct{}),
 		handlerMap:  make(map[string]func(net.Conn) error),
 	}
+	tcps.ctx, tcps.cancel = context.WithCancel(context.Background())
 	// default handlers (defined in handlers.go)
 	tcps.RegisterRPC("Ping", pong)
 	tcps.RegisterRPC("SendHostname", sendHostname)
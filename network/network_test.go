@@ -1,8 +1,14 @@
 package network
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"net"
 	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/encoding"
 )
 
 type Foo struct{}
@@ -59,6 +65,129 @@ func TestRegisterRPC(t *testing.T) {
 	}
 }
 
+// TestRPCContext checks that cancelling the context of an RPC aborts the
+// connection, and that closing a server aborts the server's calls.
+func TestRPCContext(t *testing.T) {
+	mn := NewMemNetwork()
+	tcps, err := NewTCPServerWithTransport("foo:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcps.Close()
+
+	// register a handler that never responds
+	block := make(chan struct{})
+	defer close(block)
+	tcps.RegisterRPC("Stall", func(conn net.Conn) error {
+		<-block
+		return nil
+	})
+
+	// cancel the call after a short delay
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	var resp string
+	err = tcps.RPCContext(ctx, tcps.Address(), "Stall", nil, &resp)
+	if err != context.Canceled {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("RPC was not aborted promptly")
+	}
+
+	// closing the server should abort its outgoing calls
+	client, err := NewTCPServerWithTransport("bar:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		client.Close()
+	}()
+	err = client.RPC(tcps.Address(), "Stall", nil, &resp)
+	if err != context.Canceled {
+		t.Fatal("expected context.Canceled after close, got", err)
+	}
+}
+
+var errTest = RegisterError(99, "test error")
+
+// TestRPCError checks that registered errors keep their identity when sent
+// over the network.
+func TestRPCError(t *testing.T) {
+	tcps, err := NewTCPServerWithTransport("foo:1", NewMemNetwork().Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcps.Close()
+	tcps.RegisterRPC("Fail", func() (string, error) {
+		return "", errTest.Extend("with detail")
+	})
+
+	var resp string
+	err = tcps.RPC(tcps.Address(), "Fail", nil, &resp)
+	if !errors.Is(err, errTest) {
+		t.Fatal("expected errTest, got", err)
+	}
+	if err.Error() != "test error: with detail" {
+		t.Fatal("error message was not preserved:", err)
+	}
+	if errors.Is(err, errHostnameMismatch) {
+		t.Fatal("error matched an error with a different code")
+	}
+
+	// errors sent by earlier versions contain only a message
+	tcps.RegisterRPC("LegacyFail", func(conn net.Conn) error {
+		_, err := encoding.WriteObject(conn, "legacy error")
+		return err
+	})
+	tcps.RegisterRPC("LegacySucceed", func(conn net.Conn) error {
+		_, err := encoding.WriteObject(conn, "")
+		return err
+	})
+	err = tcps.RPC(tcps.Address(), "LegacyFail", nil, nil)
+	if err == nil || err.Error() != "legacy error" {
+		t.Fatal("expected legacy error, got", err)
+	}
+	if err = tcps.RPC(tcps.Address(), "LegacySucceed", nil, nil); err != nil {
+		t.Fatal("expected legacy success, got", err)
+	}
+
+	// earlier versions read successes and unregistered errors as before, and
+	// fail to decode registered errors
+	for _, test := range []struct {
+		err     error
+		decodes bool
+		message string
+	}{
+		{nil, true, ""},
+		{errors.New("unregistered"), true, "unregistered"},
+		{errTest, false, ""},
+	} {
+		var buf bytes.Buffer
+		if err = WriteError(&buf, test.err); err != nil {
+			t.Fatal(err)
+		}
+		var message string
+		err = encoding.ReadObject(&buf, &message, maxMsgLen)
+		if (err == nil) != test.decodes || (err == nil && message != test.message) {
+			t.Errorf("earlier version read %v as %q, %v", test.err, message, err)
+		}
+	}
+
+	// registering the same code twice should panic
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate registration did not panic")
+		}
+	}()
+	RegisterError(99, "duplicate")
+}
+
 func TestTableTennis(t *testing.T) {
 	// create server
 	tcps, err := NewTCPServer(":9001")
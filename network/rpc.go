@@ -1,7 +1,7 @@
 package network
 
 import (
-	"errors"
+	"context"
 	"net"
 	"reflect"
 	"time"
@@ -24,30 +24,47 @@ func handlerName(name string) []byte {
 	return b
 }
 
-// Call establishes a TCP connection to the Address, calls the provided
-// function on it, and closes the connection.
-func (na Address) Call(name string, fn func(net.Conn) error) error {
-	conn, err := net.DialTimeout("tcp", string(na), timeout)
+// call establishes a connection to addr using the provided Transport, calls
+// the provided function on it, and closes the connection. If the context is
+// cancelled before fn returns, the connection is closed and the context's
+// error is returned.
+func call(ctx context.Context, t Transport, addr Address, name string, fn func(net.Conn) error) (err error) {
+	conn, err := t.Dial(ctx, addr)
 	if err != nil {
-		return err
+		return
 	}
 	defer conn.Close()
 	// set default deadline
 	// note: fn can extend this deadline as needed
 	conn.SetDeadline(time.Now().Add(timeout))
+
+	// close the connection if the context is cancelled, which will cause any
+	// blocked reads or writes to fail immediately
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
 	// write header
-	if _, err := conn.Write(handlerName(name)); err != nil {
-		return err
+	if _, err = conn.Write(handlerName(name)); err == nil {
+		err = fn(conn)
+	}
+	// report the cancellation instead of the I/O error it caused
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
 	}
-	return fn(conn)
+	return
 }
 
-// RPC performs a Remote Procedure Call by sending the procedure name and
-// encoded argument, and decoding the response into the supplied object.
-// 'resp' must be a pointer. If arg is nil, no object is sent. If 'resp' is
-// nil, no response is read.
-func (na *Address) RPC(name string, arg, resp interface{}) error {
-	return na.Call(name, func(conn net.Conn) error {
+// rpc performs a Remote Procedure Call using the provided Transport. See
+// Address.RPC for a description of the arguments.
+func rpc(ctx context.Context, t Transport, addr Address, name string, arg, resp interface{}) error {
+	return call(ctx, t, addr, name, func(conn net.Conn) error {
 		// write arg
 		if arg != nil {
 			if _, err := encoding.WriteObject(conn, arg); err != nil {
@@ -61,21 +78,66 @@ func (na *Address) RPC(name string, arg, resp interface{}) error {
 			}
 		}
 		// read err
-		var errStr string
-		if err := encoding.ReadObject(conn, &errStr, maxMsgLen); err != nil {
-			return err
-		} else if errStr != "" {
-			return errors.New(errStr)
-		}
-		return nil
+		return ReadError(conn)
 	})
 }
 
-// Broadcast calls the RPC on each peer in the address book.
+// Call establishes a connection to the Address using the DefaultTransport,
+// calls the provided function on it, and closes the connection.
+func (na Address) Call(name string, fn func(net.Conn) error) error {
+	return na.CallContext(context.Background(), name, fn)
+}
+
+// CallContext is like Call, but aborts the connection if ctx is cancelled
+// before fn returns.
+func (na Address) CallContext(ctx context.Context, name string, fn func(net.Conn) error) error {
+	return call(ctx, DefaultTransport, na, name, fn)
+}
+
+// RPC performs a Remote Procedure Call by sending the procedure name and
+// encoded argument, and decoding the response into the supplied object.
+// 'resp' must be a pointer. If arg is nil, no object is sent. If 'resp' is
+// nil, no response is read.
+func (na *Address) RPC(name string, arg, resp interface{}) error {
+	return na.RPCContext(context.Background(), name, arg, resp)
+}
+
+// RPCContext is like RPC, but aborts the connection if ctx is cancelled
+// before the response has been read.
+func (na *Address) RPCContext(ctx context.Context, name string, arg, resp interface{}) error {
+	return rpc(ctx, DefaultTransport, *na, name, arg, resp)
+}
+
+// Call is like Address.Call, but uses the server's Transport. The call is
+// aborted if the server is closed.
+func (tcps *TCPServer) Call(addr Address, name string, fn func(net.Conn) error) error {
+	return tcps.CallContext(tcps.ctx, addr, name, fn)
+}
+
+// CallContext is like Address.CallContext, but uses the server's Transport.
+func (tcps *TCPServer) CallContext(ctx context.Context, addr Address, name string, fn func(net.Conn) error) error {
+	return call(ctx, tcps.transport, addr, name, fn)
+}
+
+// RPC is like Address.RPC, but uses the server's Transport. The call is
+// aborted if the server is closed.
+func (tcps *TCPServer) RPC(addr Address, name string, arg, resp interface{}) error {
+	return tcps.RPCContext(tcps.ctx, addr, name, arg, resp)
+}
+
+// RPCContext is like Address.RPCContext, but uses the server's Transport.
+func (tcps *TCPServer) RPCContext(ctx context.Context, addr Address, name string, arg, resp interface{}) error {
+	return rpc(ctx, tcps.transport, addr, name, arg, resp)
+}
+
+// Broadcast calls the RPC on each peer in the address book. The broadcast is
+// aborted if the server is closed.
 func (tcps *TCPServer) Broadcast(name string, arg, resp interface{}) {
 	for _, addr := range tcps.AddressBook() {
 		// TODO: remove unresponsive peers
-		_ = addr.RPC(name, arg, resp)
+		if err := tcps.RPC(addr, name, arg, resp); err == context.Canceled {
+			return
+		}
 	}
 }
 
@@ -142,12 +204,8 @@ func registerRPC(fn reflect.Value, typ reflect.Type) func(net.Conn) error {
 			return err
 		}
 		// write err
-		var errStr string
-		if errInter != nil {
-			errStr = errInter.(error).Error()
-		}
-		_, err := encoding.WriteObject(conn, errStr)
-		return err
+		err, _ := errInter.(error)
+		return WriteError(conn, err)
 	}
 }
 
@@ -162,12 +220,8 @@ func registerArg(fn reflect.Value, typ reflect.Type) func(net.Conn) error {
 		// call fn on object
 		errInter := fn.Call([]reflect.Value{arg.Elem()})[0].Interface()
 		// write err
-		var errStr string
-		if errInter != nil {
-			errStr = errInter.(error).Error()
-		}
-		_, err := encoding.WriteObject(conn, errStr)
-		return err
+		err, _ := errInter.(error)
+		return WriteError(conn, err)
 	}
 }
 
@@ -182,11 +236,7 @@ func registerResp(fn reflect.Value, typ reflect.Type) func(net.Conn) error {
 			return err
 		}
 		// write err
-		var errStr string
-		if errInter != nil {
-			errStr = errInter.(error).Error()
-		}
-		_, err := encoding.WriteObject(conn, errStr)
-		return err
+		err, _ := errInter.(error)
+		return WriteError(conn, err)
 	}
 }
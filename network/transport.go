@@ -0,0 +1,38 @@
+package network
+
+import (
+	"context"
+	"net"
+)
+
+// A Transport creates the connections and listeners used by the network
+// package. The default transport uses TCP, but other implementations (such as
+// the in-memory MemNetwork) can be substituted, which allows multiple nodes to
+// communicate within a single process.
+type Transport interface {
+	// Dial opens a connection to the given address. The dial is aborted if
+	// the context is cancelled before the connection is established.
+	Dial(ctx context.Context, addr Address) (net.Conn, error)
+
+	// Listen creates a listener that accepts connections on the given
+	// address.
+	Listen(addr string) (net.Listener, error)
+}
+
+// DefaultTransport is the Transport used by Address.Call and Address.RPC, and
+// by servers created with NewTCPServer.
+var DefaultTransport Transport = tcpTransport{}
+
+// tcpTransport is a Transport that uses real TCP connections.
+type tcpTransport struct{}
+
+// Dial implements the Transport interface.
+func (tcpTransport) Dial(ctx context.Context, addr Address) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return dialer.DialContext(ctx, "tcp", string(addr))
+}
+
+// Listen implements the Transport interface.
+func (tcpTransport) Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
@@ -51,7 +51,7 @@ func (c *Core) processBlock(b consensus.Block) (err error) {
 	if err != nil {
 		return
 	}
-	err = c.wallet.Update(outputDiffs)
+	err = c.wallet.Update(initialStateHeight, rewoundBlocks, appliedBlocks, outputDiffs)
 	if err != nil {
 		return
 	}
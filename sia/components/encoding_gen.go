@@ -0,0 +1,512 @@
+// Code generated by encoding.Generate; DO NOT EDIT.
+
+package components
+
+import (
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/network"
+)
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x HostAnnouncement) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *HostAnnouncement) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x HostAnnouncement) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *HostAnnouncement) appendSia(b []byte) []byte {
+	s1 := len(b)
+	b = encoding.AppendVersionHeader(b, 1)
+	b = encoding.AppendUint64(b, uint64(len(x.IPAddress)))
+	b = append(b, x.IPAddress...)
+	b = encoding.AppendUint64(b, uint64(x.TotalStorage))
+	b = encoding.AppendUint64(b, x.MinFilesize)
+	b = encoding.AppendUint64(b, x.MaxFilesize)
+	b = encoding.AppendUint64(b, uint64(x.MinDuration))
+	b = encoding.AppendUint64(b, uint64(x.MaxDuration))
+	b = encoding.AppendUint64(b, uint64(x.MinChallengeWindow))
+	b = encoding.AppendUint64(b, uint64(x.MaxChallengeWindow))
+	b = encoding.AppendUint64(b, x.MinTolerance)
+	b = encoding.AppendUint64(b, uint64(x.Price))
+	b = encoding.AppendUint64(b, uint64(x.Burn))
+	b = append(b, x.CoinAddress[:]...)
+	b = append(b, x.SpendConditions.MarshalSia()...)
+	b = encoding.AppendUint64(b, x.FreezeIndex)
+	encoding.SetVersionLength(b, s1)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *HostAnnouncement) ReadSia(r *encoding.SliceReader) {
+	v1, r2 := r.ReadVersioned()
+	x.IPAddress = network.Address(r2.ReadString())
+	x.TotalStorage = int64(r2.ReadUint64())
+	x.MinFilesize = r2.ReadUint64()
+	x.MaxFilesize = r2.ReadUint64()
+	x.MinDuration = consensus.BlockHeight(r2.ReadUint64())
+	x.MaxDuration = consensus.BlockHeight(r2.ReadUint64())
+	x.MinChallengeWindow = consensus.BlockHeight(r2.ReadUint64())
+	x.MaxChallengeWindow = consensus.BlockHeight(r2.ReadUint64())
+	x.MinTolerance = r2.ReadUint64()
+	x.Price = consensus.Currency(r2.ReadUint64())
+	x.Burn = consensus.Currency(r2.ReadUint64())
+	copy(x.CoinAddress[:], r2.ReadBytes(32))
+	x.SpendConditions.ReadSia(r2)
+	x.FreezeIndex = r2.ReadUint64()
+	r2.FinishVersioned(v1, 1)
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x HostEntry) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *HostEntry) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x HostEntry) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *HostEntry) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.ID)))
+	b = append(b, x.ID...)
+	b = encoding.AppendUint64(b, uint64(len(x.IPAddress)))
+	b = append(b, x.IPAddress...)
+	b = encoding.AppendUint64(b, x.MinFilesize)
+	b = encoding.AppendUint64(b, x.MaxFilesize)
+	b = encoding.AppendUint64(b, uint64(x.MinDuration))
+	b = encoding.AppendUint64(b, uint64(x.MaxDuration))
+	b = encoding.AppendUint64(b, uint64(x.Window))
+	b = encoding.AppendUint64(b, x.Tolerance)
+	b = encoding.AppendUint64(b, uint64(x.Price))
+	b = encoding.AppendUint64(b, uint64(x.Burn))
+	b = encoding.AppendUint64(b, uint64(x.Freeze))
+	b = append(b, x.CoinAddress[:]...)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *HostEntry) ReadSia(r *encoding.SliceReader) {
+	x.ID = r.ReadString()
+	x.IPAddress = network.Address(r.ReadString())
+	x.MinFilesize = r.ReadUint64()
+	x.MaxFilesize = r.ReadUint64()
+	x.MinDuration = consensus.BlockHeight(r.ReadUint64())
+	x.MaxDuration = consensus.BlockHeight(r.ReadUint64())
+	x.Window = consensus.BlockHeight(r.ReadUint64())
+	x.Tolerance = r.ReadUint64()
+	x.Price = consensus.Currency(r.ReadUint64())
+	x.Burn = consensus.Currency(r.ReadUint64())
+	x.Freeze = consensus.Currency(r.ReadUint64())
+	copy(x.CoinAddress[:], r.ReadBytes(32))
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x HostInfo) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *HostInfo) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x HostInfo) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *HostInfo) appendSia(b []byte) []byte {
+	b = x.Announcement.appendSia(b)
+	b = encoding.AppendUint64(b, uint64(x.StorageRemaining))
+	b = encoding.AppendUint64(b, uint64(x.ContractCount))
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *HostInfo) ReadSia(r *encoding.SliceReader) {
+	x.Announcement.ReadSia(r)
+	x.StorageRemaining = int(r.ReadUint64())
+	x.ContractCount = int(r.ReadUint64())
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x MinerInfo) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *MinerInfo) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x MinerInfo) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *MinerInfo) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.State)))
+	b = append(b, x.State...)
+	b = encoding.AppendUint64(b, uint64(x.Threads))
+	b = encoding.AppendUint64(b, uint64(x.RunningThreads))
+	b = append(b, x.Address[:]...)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *MinerInfo) ReadSia(r *encoding.SliceReader) {
+	x.State = r.ReadString()
+	x.Threads = int(r.ReadUint64())
+	x.RunningThreads = int(r.ReadUint64())
+	copy(x.Address[:], r.ReadBytes(32))
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x RentFileParameters) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *RentFileParameters) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x RentFileParameters) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *RentFileParameters) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.Filepath)))
+	b = append(b, x.Filepath...)
+	b = encoding.AppendUint64(b, uint64(len(x.Nickname)))
+	b = append(b, x.Nickname...)
+	b = encoding.AppendUint64(b, uint64(x.TotalPieces))
+	b = encoding.AppendUint64(b, uint64(x.RequiredPieces))
+	b = encoding.AppendUint64(b, uint64(x.OptimalPieces))
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *RentFileParameters) ReadSia(r *encoding.SliceReader) {
+	x.Filepath = r.ReadString()
+	x.Nickname = r.ReadString()
+	x.TotalPieces = int(r.ReadUint64())
+	x.RequiredPieces = int(r.ReadUint64())
+	x.OptimalPieces = int(r.ReadUint64())
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x RentSmallFileParameters) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *RentSmallFileParameters) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x RentSmallFileParameters) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *RentSmallFileParameters) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.FullFile)))
+	b = append(b, x.FullFile...)
+	b = encoding.AppendUint64(b, uint64(len(x.Nickname)))
+	b = append(b, x.Nickname...)
+	b = encoding.AppendUint64(b, uint64(x.TotalPieces))
+	b = encoding.AppendUint64(b, uint64(x.RequiredPieces))
+	b = encoding.AppendUint64(b, uint64(x.OptimalPieces))
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *RentSmallFileParameters) ReadSia(r *encoding.SliceReader) {
+	x.FullFile = r.ReadPrefixedBytes()
+	x.Nickname = r.ReadString()
+	x.TotalPieces = int(r.ReadUint64())
+	x.RequiredPieces = int(r.ReadUint64())
+	x.OptimalPieces = int(r.ReadUint64())
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x RentInfo) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *RentInfo) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x RentInfo) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *RentInfo) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(len(x.Files)))
+	for i0 := range x.Files {
+		b = encoding.AppendUint64(b, uint64(len(x.Files[i0])))
+		b = append(b, x.Files[i0]...)
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *RentInfo) ReadSia(r *encoding.SliceReader) {
+	x.Files = make([]string, r.ReadLen(8, 16))
+	for i0 := range x.Files {
+		x.Files[i0] = r.ReadString()
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x MultisigAddressInfo) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *MultisigAddressInfo) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x MultisigAddressInfo) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *MultisigAddressInfo) appendSia(b []byte) []byte {
+	b = append(b, x.Address[:]...)
+	b = encoding.AppendUint64(b, x.NumSignatures)
+	b = encoding.AppendUint64(b, uint64(x.NumKeys))
+	b = encoding.AppendUint64(b, uint64(x.Balance))
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *MultisigAddressInfo) ReadSia(r *encoding.SliceReader) {
+	copy(x.Address[:], r.ReadBytes(32))
+	x.NumSignatures = r.ReadUint64()
+	x.NumKeys = int(r.ReadUint64())
+	x.Balance = consensus.Currency(r.ReadUint64())
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x WalletInfo) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *WalletInfo) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x WalletInfo) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *WalletInfo) appendSia(b []byte) []byte {
+	b = encoding.AppendUint64(b, uint64(x.Balance))
+	b = encoding.AppendUint64(b, uint64(x.FullBalance))
+	b = encoding.AppendUint64(b, uint64(x.NumAddresses))
+	if x.Encrypted {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	if x.Locked {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	if x.Rescanning {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = encoding.AppendUint64(b, uint64(x.RescanHeight))
+	b = encoding.AppendUint64(b, uint64(x.RescanTarget))
+	b = encoding.AppendUint64(b, uint64(x.NumWatchAddresses))
+	b = encoding.AppendUint64(b, uint64(x.WatchBalance))
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *WalletInfo) ReadSia(r *encoding.SliceReader) {
+	x.Balance = consensus.Currency(r.ReadUint64())
+	x.FullBalance = consensus.Currency(r.ReadUint64())
+	x.NumAddresses = int(r.ReadUint64())
+	x.Encrypted = r.ReadBool()
+	x.Locked = r.ReadBool()
+	x.Rescanning = r.ReadBool()
+	x.RescanHeight = consensus.BlockHeight(r.ReadUint64())
+	x.RescanTarget = consensus.BlockHeight(r.ReadUint64())
+	x.NumWatchAddresses = int(r.ReadUint64())
+	x.WatchBalance = consensus.Currency(r.ReadUint64())
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x UnsignedTransaction) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *UnsignedTransaction) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x UnsignedTransaction) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *UnsignedTransaction) appendSia(b []byte) []byte {
+	b = append(b, x.Transaction.MarshalSia()...)
+	b = encoding.AppendUint64(b, uint64(len(x.Inputs)))
+	for i0 := range x.Inputs {
+		b = encoding.AppendUint64(b, x.Inputs[i0])
+	}
+	b = append(b, x.CoveredFields.MarshalSia()...)
+	b = encoding.AppendUint64(b, uint64(len(x.Values)))
+	for i0 := range x.Values {
+		b = encoding.AppendUint64(b, uint64(x.Values[i0]))
+	}
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *UnsignedTransaction) ReadSia(r *encoding.SliceReader) {
+	x.Transaction.ReadSia(r)
+	x.Inputs = make([]uint64, r.ReadLen(8, 8))
+	for i0 := range x.Inputs {
+		x.Inputs[i0] = r.ReadUint64()
+	}
+	x.CoveredFields.ReadSia(r)
+	x.Values = make([]consensus.Currency, r.ReadLen(8, 8))
+	for i0 := range x.Values {
+		x.Values[i0] = consensus.Currency(r.ReadUint64())
+	}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x WatchAddressInfo) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *WatchAddressInfo) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x WatchAddressInfo) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *WatchAddressInfo) appendSia(b []byte) []byte {
+	b = append(b, x.Address[:]...)
+	if x.HasSpendConditions {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = encoding.AppendUint64(b, uint64(x.Balance))
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *WatchAddressInfo) ReadSia(r *encoding.SliceReader) {
+	copy(x.Address[:], r.ReadBytes(32))
+	x.HasSpendConditions = r.ReadBool()
+	x.Balance = consensus.Currency(r.ReadUint64())
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (x WalletTransaction) MarshalSia() []byte {
+	return x.appendSia(nil)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (x *WalletTransaction) UnmarshalSia(b []byte) int {
+	r := encoding.NewSliceReader(b)
+	x.ReadSia(r)
+	return r.Consumed()
+}
+
+// SiaGenerated implements the encoding.GeneratedMarshaler interface.
+func (x WalletTransaction) SiaGenerated() {}
+
+// appendSia appends the encoding of x to b.
+func (x *WalletTransaction) appendSia(b []byte) []byte {
+	b = append(b, x.ID[:]...)
+	b = append(b, x.BlockID[:]...)
+	b = encoding.AppendUint64(b, uint64(x.ConfirmationHeight))
+	if x.MinerPayout {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = encoding.AppendUint64(b, uint64(x.Inflow))
+	b = encoding.AppendUint64(b, uint64(x.Outflow))
+	b = encoding.AppendUint64(b, uint64(x.Fees))
+	b = encoding.AppendUint64(b, uint64(len(x.Addresses)))
+	for i0 := range x.Addresses {
+		b = append(b, x.Addresses[i0][:]...)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Counterparties)))
+	for i0 := range x.Counterparties {
+		b = append(b, x.Counterparties[i0][:]...)
+	}
+	b = encoding.AppendUint64(b, uint64(len(x.Label)))
+	b = append(b, x.Label...)
+	return b
+}
+
+// ReadSia implements the encoding.SiaReader interface.
+func (x *WalletTransaction) ReadSia(r *encoding.SliceReader) {
+	copy(x.ID[:], r.ReadBytes(32))
+	copy(x.BlockID[:], r.ReadBytes(32))
+	x.ConfirmationHeight = consensus.BlockHeight(r.ReadUint64())
+	x.MinerPayout = r.ReadBool()
+	x.Inflow = consensus.Currency(r.ReadUint64())
+	x.Outflow = consensus.Currency(r.ReadUint64())
+	x.Fees = consensus.Currency(r.ReadUint64())
+	x.Addresses = make([]consensus.CoinAddress, r.ReadLen(32, 32))
+	for i0 := range x.Addresses {
+		copy(x.Addresses[i0][:], r.ReadBytes(32))
+	}
+	x.Counterparties = make([]consensus.CoinAddress, r.ReadLen(32, 32))
+	for i0 := range x.Counterparties {
+		copy(x.Counterparties[i0][:], r.ReadBytes(32))
+	}
+	x.Label = r.ReadString()
+}
@@ -0,0 +1,89 @@
+package components
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/network"
+)
+
+// TestGeneratedMarshalling checks that the generated marshalling methods of
+// the component types, and of the network types they are sent alongside,
+// agree with the reflective encoding.
+func TestGeneratedMarshalling(t *testing.T) {
+	addr := consensus.CoinAddress{1, 2, 3}
+	sc := consensus.SpendConditions{
+		TimeLock:      4,
+		NumSignatures: 1,
+		PublicKeys:    make([]crypto.PublicKey, 2),
+	}
+	ha := HostAnnouncement{
+		IPAddress:       "localhost:9988",
+		TotalStorage:    -1,
+		MaxFilesize:     5,
+		MaxDuration:     6,
+		Price:           7,
+		CoinAddress:     addr,
+		SpendConditions: sc,
+		FreezeIndex:     8,
+	}
+	objects := []interface{}{
+		ha,
+		HostEntry{ID: "foo", IPAddress: ha.IPAddress, Price: 9, CoinAddress: addr},
+		HostInfo{Announcement: ha, StorageRemaining: 10, ContractCount: 11},
+		MinerInfo{State: "On", Threads: 2, RunningThreads: 1, Address: addr},
+		RentFileParameters{Filepath: "/tmp/foo", Nickname: "foo", TotalPieces: 3},
+		RentSmallFileParameters{FullFile: []byte("bar"), Nickname: "bar"},
+		RentInfo{Files: []string{"foo", "bar"}},
+		MultisigAddressInfo{Address: addr, NumSignatures: 2, NumKeys: 3, Balance: 12},
+		WalletInfo{Balance: 13, NumAddresses: 4, Locked: true, NumWatchAddresses: 1},
+		UnsignedTransaction{
+			Transaction:   consensus.Transaction{Inputs: []consensus.Input{{SpendConditions: sc}}},
+			Inputs:        []uint64{0},
+			CoveredFields: consensus.CoveredFields{WholeTransaction: true},
+			Values:        []consensus.Currency{17},
+		},
+		WatchAddressInfo{Address: addr, HasSpendConditions: true, Balance: 14},
+		WalletTransaction{Inflow: 15, Addresses: []consensus.CoinAddress{addr}, Label: "baz"},
+		network.RPCError{Code: 16, Message: "qux"},
+	}
+	for _, obj := range objects {
+		generated := encoding.Marshal(obj)
+		if !bytes.Equal(generated, encoding.MarshalReflect(obj)) {
+			t.Fatalf("generated encoding of %T does not match reflective encoding", obj)
+		}
+		fromGenerated := reflect.New(reflect.TypeOf(obj))
+		if err := encoding.Unmarshal(generated, fromGenerated.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		fromReflected := reflect.New(reflect.TypeOf(obj))
+		if err := encoding.UnmarshalReflect(generated, fromReflected.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromGenerated.Interface(), fromReflected.Interface()) {
+			t.Errorf("generated decoding of %T does not match reflective decoding", obj)
+		}
+	}
+
+	// errors in nested generated types should be reported at the correct
+	// offset, and the allocation budget should be shared with them
+	b := encoding.Marshal(HostInfo{Announcement: ha})
+	var de *encoding.DecodeError
+	err := encoding.Unmarshal(b[:40], new(HostInfo))
+	if !errors.As(err, &de) || de.Offset != 16 || de.Path != "components.HostInfo.Announcement" {
+		t.Error("expected truncation of Announcement at offset 16, got", err)
+	}
+	err = encoding.Unmarshal(b[:len(b)-1], new(HostInfo))
+	if !errors.As(err, &de) || de.Offset != len(b)-8 || de.Path != "components.HostInfo.ContractCount" {
+		t.Errorf("expected truncation of ContractCount at offset %d, got %v", len(b)-8, err)
+	}
+	err = encoding.UnmarshalLimit(b, new(HostInfo), 8)
+	if !errors.Is(err, encoding.ErrAllocLimit) {
+		t.Error("expected ErrAllocLimit, got", err)
+	}
+}
@@ -0,0 +1,35 @@
+// +build ignore
+
+// This program generates encoding_gen.go. It is run by go generate.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+// Types that contain channels, interfaces or servers (e.g. HostUpdate and
+// MinerUpdate) are never encoded, and are omitted.
+func main() {
+	err := encoding.GenerateFile("encoding_gen.go", "github.com/NebulousLabs/Sia/sia/components",
+		components.HostAnnouncement{},
+		components.HostEntry{},
+		components.HostInfo{},
+		components.MinerInfo{},
+		components.RentFileParameters{},
+		components.RentSmallFileParameters{},
+		components.RentInfo{},
+		components.MultisigAddressInfo{},
+		components.WalletInfo{},
+		components.UnsignedTransaction{},
+		components.WatchAddressInfo{},
+		components.WalletTransaction{},
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -7,10 +7,6 @@ import (
 	"github.com/NebulousLabs/Sia/network"
 )
 
-const (
-	AcceptContractResponse = "accept"
-)
-
 type HostUpdate struct {
 	Announcement    HostAnnouncement
 	Height          consensus.BlockHeight
@@ -1,12 +1,20 @@
 package components
 
+//go:generate go run gen.go
+
 import (
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/network"
 )
 
 const (
-	HostAnnouncementPrefix = 1
+	// HostAnnouncementPrefix precedes a HostAnnouncement in the arbitrary
+	// data of a transaction.
+	HostAnnouncementPrefix = 2
+
+	// LegacyHostAnnouncementPrefix precedes a HostAnnouncement that was
+	// encoded before HostAnnouncement became a versioned struct.
+	LegacyHostAnnouncementPrefix = 1
 )
 
 type HostDB interface {
@@ -40,7 +48,9 @@ type HostDB interface {
 }
 
 // A HostAnnouncement is a struct that can appear in the arbitrary data field.
-// It is preceded by 8 bytes that decode to the integer 1.
+// It is preceded by 8 bytes that decode to HostAnnouncementPrefix.
+// HostAnnouncement is a versioned struct, so new fields must be appended and
+// tagged with the version that introduced them.
 type HostAnnouncement struct {
 	IPAddress          network.Address
 	TotalStorage       int64 // Can go negative.
@@ -59,6 +69,11 @@ type HostAnnouncement struct {
 	FreezeIndex     uint64 // The index of the output that froze coins.
 }
 
+// SiaVersion implements the encoding.VersionedStruct interface.
+func (HostAnnouncement) SiaVersion() uint64 {
+	return 1
+}
+
 // the Host struct is kept in the client package because it's what the client
 // uses to weigh hosts and pick them out when storing files.
 type HostEntry struct {
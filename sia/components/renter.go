@@ -1,5 +1,9 @@
 package components
 
+import (
+	"github.com/NebulousLabs/Sia/network"
+)
+
 type RentFileParameters struct {
 	Filepath       string
 	Nickname       string
@@ -21,10 +25,18 @@ type RentInfo struct {
 }
 
 type Renter interface {
+	// Close aborts any uploads or downloads that are in progress.
+	Close() error
+
 	Download(nickname, filepath string) error
 	RentInfo() (RentInfo, error)
 	RenameFile(currentName, newName string) error
 	RentFile(RentFileParameters) error
 
 	RentSmallFile(RentSmallFileParameters) error
+
+	// SetNetwork sets the server through which hosts are contacted, so that
+	// the renter uses the same Transport as the rest of the node. Until it is
+	// called, hosts are contacted using network.DefaultTransport.
+	SetNetwork(*network.TCPServer)
 }
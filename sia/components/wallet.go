@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
 )
 
 var (
@@ -14,6 +15,96 @@ type WalletInfo struct {
 	Balance      consensus.Currency
 	FullBalance  consensus.Currency
 	NumAddresses int
+	Encrypted    bool
+	Locked       bool
+	Rescanning   bool
+	RescanHeight consensus.BlockHeight
+	RescanTarget consensus.BlockHeight
+
+	// WatchBalance is the value of the unspent outputs of the watch-only
+	// addresses, which is not included in Balance.
+	NumWatchAddresses int
+	WatchBalance      consensus.Currency
+}
+
+// A SelectionStrategy determines which outputs are used to fund a
+// transaction.
+type SelectionStrategy string
+
+const (
+	// LargestFirst spends the largest outputs first, using as few inputs as
+	// possible.
+	LargestFirst SelectionStrategy = "largest"
+
+	// SmallestFirst spends the smallest outputs first, which reduces the
+	// number of small outputs held by the wallet.
+	SmallestFirst SelectionStrategy = "smallest"
+
+	// BranchAndBound searches for a set of outputs that matches the amount
+	// exactly, so that no change output is created, and falls back to
+	// LargestFirst.
+	BranchAndBound SelectionStrategy = "branchandbound"
+
+	// PrivacyPreserving spends from as few addresses as possible, so that
+	// fewer of the wallet's addresses are linked together.
+	PrivacyPreserving SelectionStrategy = "privacy"
+)
+
+// MultisigAddressInfo describes an M-of-N address tracked by the wallet.
+// Balance is the value of its unspent outputs.
+type MultisigAddressInfo struct {
+	Address       consensus.CoinAddress
+	NumSignatures uint64
+	NumKeys       int
+	Balance       consensus.Currency
+}
+
+// WatchAddressInfo describes a watch-only address tracked by the wallet.
+// HasSpendConditions reports whether the spend conditions of the address are
+// known, which is needed to export transactions that spend from it. Balance is
+// the value of its unspent outputs.
+type WatchAddressInfo struct {
+	Address            consensus.CoinAddress
+	HasSpendConditions bool
+	Balance            consensus.Currency
+}
+
+// An UnsignedTransaction is a transaction funded by a wallet that does not
+// hold the secret keys of its inputs, so that it can be signed by a wallet that
+// does, such as one kept offline. Inputs holds the indices of the inputs that
+// need to be signed, and each of those inputs carries the spend conditions of
+// the address it spends from. CoveredFields are the fields that the signatures
+// will cover. Values holds the value of the output spent by each input of the
+// transaction, so that the signing wallet can check the fee and change without
+// knowing those outputs.
+type UnsignedTransaction struct {
+	Transaction   consensus.Transaction
+	Inputs        []uint64
+	CoveredFields consensus.CoveredFields
+	Values        []consensus.Currency
+}
+
+// A WalletTransaction is an entry in the wallet's transaction history. It
+// records a confirmed transaction that spends from or pays to the wallet, or a
+// block subsidy paid to the wallet. The net change in the wallet's balance is
+// Inflow - Outflow. Fees are only recorded for transactions funded by the
+// wallet, and are included in Outflow.
+type WalletTransaction struct {
+	ID                 consensus.TransactionID
+	BlockID            consensus.BlockID
+	ConfirmationHeight consensus.BlockHeight
+	MinerPayout        bool
+	Inflow             consensus.Currency
+	Outflow            consensus.Currency
+	Fees               consensus.Currency
+
+	// Addresses holds the wallet's addresses that the transaction spends
+	// from or pays to. Counterparties holds the other addresses: the
+	// recipients of a send, or the senders of a receipt.
+	Addresses      []consensus.CoinAddress
+	Counterparties []consensus.CoinAddress
+
+	Label string
 }
 
 // Wallet in an interface that helps to build and sign transactions. The user
@@ -31,8 +122,9 @@ type Wallet interface {
 
 	// Update takes two sets of blocks. The first is the set of blocks that
 	// have been rewound since the previous call to update, and the second set
-	// is the blocks that were applied after rewinding.
-	Update([]consensus.OutputDiff) error
+	// is the blocks that were applied after rewinding. The output diffs are
+	// the changes to the unspent outputs caused by those blocks.
+	Update(initialStateHeight consensus.BlockHeight, rewoundBlocks []consensus.Block, appliedBlocks []consensus.Block, diffs []consensus.OutputDiff) error
 
 	// Reset will clear the list of spent transactions, which is nice if you've
 	// accidentally made transactions that aren't spreading on the network for
@@ -45,6 +137,76 @@ type Wallet interface {
 	// have been spent in unconfirmed transactions.
 	Balance(full bool) consensus.Currency
 
+	// Unlock decrypts the secret keys of the wallet using password. If the
+	// wallet is not encrypted, it is encrypted with password.
+	Unlock(password string) error
+
+	// Lock erases the secret keys of the wallet from memory. Coins cannot be
+	// spent while the wallet is locked.
+	Lock() error
+
+	// Seed returns the mnemonic form of the seed from which the wallet's keys
+	// are derived, so that it can be backed up.
+	Seed() (mnemonic string, err error)
+
+	// Restore replaces the wallet's seed with the seed encoded by mnemonic,
+	// adds the addresses derived from it, and rescans the blockchain for
+	// their outputs.
+	Restore(mnemonic string) error
+
+	// MultisigKey returns a new public key of the wallet, which can be shared
+	// with cosigners to create a multisig address.
+	MultisigKey() (crypto.PublicKey, error)
+
+	// CreateMultisigAddress creates an address that requires `numSignatures`
+	// signatures from `publicKeys` to spend, and tracks its outputs. At least
+	// one of the keys must belong to the wallet. Every cosigner that creates
+	// the address from the same keys gets the same address.
+	CreateMultisigAddress(numSignatures uint64, publicKeys []crypto.PublicKey) (consensus.CoinAddress, consensus.SpendConditions, error)
+
+	// MultisigAddresses returns the multisig addresses tracked by the wallet.
+	MultisigAddresses() ([]MultisigAddressInfo, error)
+
+	// MultisigSend creates a transaction sending `amount` from a multisig
+	// address to `dest`, and signs it with the wallet's keys. The transaction
+	// must be signed by enough cosigners before it is valid.
+	MultisigSend(address consensus.CoinAddress, amount consensus.Currency, dest consensus.CoinAddress, fee consensus.Currency) (consensus.Transaction, error)
+
+	// SignMultisig adds the wallet's signatures to a multisig transaction.
+	SignMultisig(consensus.Transaction) (consensus.Transaction, error)
+
+	// MergeMultisig combines the signatures of copies of a multisig
+	// transaction that were signed by different cosigners.
+	MergeMultisig([]consensus.Transaction) (consensus.Transaction, error)
+
+	// MarkSpent marks the outputs of the wallet that a transaction spends as
+	// spent, so that they are not spent again. It is called when a
+	// transaction signed by several parties is submitted.
+	MarkSpent(consensus.Transaction)
+
+	// WatchAddress starts tracking the outputs of an address without holding
+	// its secret keys. If `spendConditions` is not nil, they must be the
+	// spend conditions of `address`, and transactions spending from the
+	// address can be exported for signing elsewhere. The wallet never spends
+	// from a watch-only address itself.
+	WatchAddress(address consensus.CoinAddress, spendConditions *consensus.SpendConditions) error
+
+	// WatchAddresses returns the watch-only addresses tracked by the wallet.
+	WatchAddresses() ([]WatchAddressInfo, error)
+
+	// History returns up to `limit` entries of the wallet's transaction
+	// history, most recent first, skipping the first `offset` entries. If
+	// address is not nil, only transactions involving that address are
+	// returned. The total number of matching entries is also returned.
+	History(address *consensus.CoinAddress, offset, limit int) (txns []WalletTransaction, total int, err error)
+
+	// LabelTransaction sets the label of an entry in the transaction history.
+	LabelTransaction(id consensus.TransactionID, label string) error
+
+	// Rescan finds the outputs of the wallet's addresses in the blocks from
+	// height `fromHeight` onwards, for example after importing addresses.
+	Rescan(fromHeight consensus.BlockHeight) error
+
 	// CoinAddress return an address into which coins can be paid.
 	CoinAddress() (consensus.CoinAddress, consensus.SpendConditions, error)
 
@@ -56,8 +218,26 @@ type Wallet interface {
 	// reference the transaction.
 	RegisterTransaction(consensus.Transaction) (id string, err error)
 
-	// FundTransaction will add `amount` to a transaction's inputs.
-	FundTransaction(id string, amount consensus.Currency) error
+	// DiscardTransaction deletes a transaction-in-progress without signing or
+	// exporting it. The outputs added to it as inputs have not been marked as
+	// spent, so they remain available to other transactions.
+	DiscardTransaction(id string) error
+
+	// FundTransaction will add `amount` to a transaction's inputs. `amount`
+	// must include any miner fees. If the inputs are worth more than
+	// `amount`, an output holding the difference is added, paying to a new
+	// wallet address, and its value and index are returned. If `change` is 0,
+	// no change output was added.
+	FundTransaction(id string, amount consensus.Currency) (change consensus.Currency, changeIndex uint64, err error)
+
+	// FundTransactionWithStrategy is FundTransaction, using `strategy` to
+	// choose the outputs that are spent.
+	FundTransactionWithStrategy(id string, amount consensus.Currency, strategy SelectionStrategy) (change consensus.Currency, changeIndex uint64, err error)
+
+	// Consolidate creates and signs a transaction that sweeps up to
+	// `maxInputs` of the wallet's smallest outputs worth less than
+	// `threshold` into a single output, paying `fee` to the miners.
+	Consolidate(threshold consensus.Currency, maxInputs int, fee consensus.Currency) (consensus.Transaction, error)
 
 	// AddMinerFee adds a single miner fee of value `fee`.
 	AddMinerFee(id string, fee consensus.Currency) error
@@ -95,4 +275,25 @@ type Wallet interface {
 	// Upon being signed and returned, the transaction-in-progress is deleted
 	// from the wallet.
 	SignTransaction(id string, wholeTransaction bool) (consensus.Transaction, error)
+
+	// FundUnsignedTransaction is FundTransaction for a transaction that will
+	// be exported and signed elsewhere. It does not need the secret keys of
+	// the wallet, so it can be used while the wallet is locked. Change is
+	// sent to a new address that the signing wallet can spend from.
+	FundUnsignedTransaction(id string, amount consensus.Currency) (change consensus.Currency, changeIndex uint64, err error)
+
+	// ExportTransaction returns the transaction associated with the id
+	// without signing it, so that it can be signed by another wallet holding
+	// the secret keys. Every input must spend an output known to the wallet.
+	// The outputs it spends are marked as spent, and the
+	// transaction-in-progress is deleted from the wallet.
+	ExportTransaction(id string, wholeTransaction bool) (UnsignedTransaction, error)
+
+	// SignUnsignedTransaction signs the inputs of a transaction exported by
+	// another wallet, and returns the signed transaction. Every input to be
+	// signed must spend from an address of the wallet, the signatures must
+	// cover the whole transaction, and the miner fees must not exceed
+	// maxFee. sent is the value that the transaction pays to addresses
+	// other than the wallet's.
+	SignUnsignedTransaction(ut UnsignedTransaction, maxFee consensus.Currency) (t consensus.Transaction, sent consensus.Currency, err error)
 }
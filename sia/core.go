@@ -31,10 +31,16 @@ type Config struct {
 	//
 	// TODO: Most of these should be deprecated as inputs to the core - each
 	// component should manage its own settings.
-	HostDir     string
-	WalletFile  string
-	ServerAddr  string
-	Nobootstrap bool
+	HostDir      string
+	WalletFile   string
+	ServerAddr   string
+	Nobootstrap  bool
+	LANDiscovery bool
+
+	// The Transport used by the server. If nil, network.DefaultTransport is
+	// used. Tests can supply an in-memory transport to run several cores
+	// within one process.
+	Transport network.Transport
 }
 
 // Core is the struct that serves as the state for siad. It contains a
@@ -140,18 +146,23 @@ func CreateCore(config Config) (c *Core, err error) {
 	if err != nil {
 		return
 	}
-	err = c.wallet.Update(genesisOutputDiffs)
+	err = c.wallet.Update(0, nil, []consensus.Block{genesisBlock}, genesisOutputDiffs)
 	if err != nil {
 		return
 	}
 
 	// Bootstrap to the network (may take a few seconds).
-	err = c.initializeNetwork(config.ServerAddr, config.Nobootstrap)
+	transport := config.Transport
+	if transport == nil {
+		transport = network.DefaultTransport
+	}
+	err = c.initializeNetwork(config.ServerAddr, transport, config.Nobootstrap, config.LANDiscovery)
 	if err == network.ErrNoPeers {
 		fmt.Println("Warning: no peers responded to bootstrap request. Add peers manually to enable bootstrapping.")
 	} else if err != nil {
 		return
 	}
+	c.renter.SetNetwork(c.server)
 
 	// TODO: Move this back up or something. The defaults are all being set in
 	// weird hacky places.
@@ -204,7 +215,9 @@ func (c *Core) ScanMutexes() {
 }
 
 // Close does any finishing maintenence before the environment can be garbage
-// collected. Right now that just means closing the server.
+// collected. Closing the renter and the server aborts any network calls that
+// are in progress.
 func (c *Core) Close() {
+	c.renter.Close()
 	c.server.Close()
 }
@@ -75,6 +75,8 @@ func TestEverything(t *testing.T) {
 	testTransactionBlock(t, c)
 	testSendToSelf(t, c)
 	testWalletInfo(t, c)
+	testWalletRescan(t, c)
+	testWalletHistory(t, c)
 	testHostAnnouncement(t, c)
 	testUploadFile(t, c)
 	sendManyTransactions(t, c)
@@ -20,7 +20,7 @@ func (h *Host) AnnounceHost(freezeVolume consensus.Currency, freezeUnlockHeight
 	if err != nil {
 		return
 	}
-	err = h.wallet.FundTransaction(id, freezeVolume)
+	_, _, err = h.wallet.FundTransaction(id, freezeVolume)
 	if err != nil {
 		return
 	}
@@ -12,7 +12,25 @@ import (
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/hash"
-	"github.com/NebulousLabs/Sia/sia/components"
+	"github.com/NebulousLabs/Sia/network"
+)
+
+// Errors returned to renters during contract negotiation. Renters can identify
+// them with errors.Is.
+var (
+	ErrContractCount    = network.RegisterError(200, "transaction must have exactly one contract")
+	ErrFileSize         = network.RegisterError(201, "file is of incorrect size")
+	ErrHostFull         = network.RegisterError(202, "host is at capacity and can not take more files")
+	ErrDuration         = network.RegisterError(203, "contract duration is out of bounds")
+	ErrChallengeWindow  = network.RegisterError(204, "challenge frequency is out of bounds")
+	ErrTolerance        = network.RegisterError(205, "tolerance is too low")
+	ErrPayoutAddress    = network.RegisterError(206, "coins are not paying out to correct address")
+	ErrPayoutTooLow     = network.RegisterError(207, "valid proof payout is too low")
+	ErrBurnAddress      = network.RegisterError(208, "burn payout needs to go to the empty address")
+	ErrBurnTooHigh      = network.RegisterError(209, "burn payout is too high for a missed proof")
+	ErrInsufficientFund = network.RegisterError(210, "ContractFund does not cover the entire duration of the contract")
+	ErrNoFile           = network.RegisterError(211, "no record of that file")
+	ErrFileUnavailable  = network.RegisterError(212, "file could not be read by the host")
 )
 
 // ContractEntry houses a single contract with its id - you cannot derive the
@@ -44,62 +62,62 @@ func (h *Host) considerContract(t consensus.Transaction) (updatedTransaction con
 
 	// Check that there is only one file contract.
 	if len(t.FileContracts) != 1 {
-		err = errors.New("transaction must have exactly one contract")
+		err = ErrContractCount
 		return
 	}
 	// Check that the file size listed in the contract is in bounds.
 	if fileSize < h.announcement.MinFilesize || fileSize > h.announcement.MaxFilesize {
-		err = fmt.Errorf("file is of incorrect size - filesize %v, min %v, max %v", fileSize, h.announcement.MinFilesize, h.announcement.MaxFilesize)
+		err = ErrFileSize.Extend(fmt.Sprintf("filesize %v, min %v, max %v", fileSize, h.announcement.MinFilesize, h.announcement.MaxFilesize))
 		return
 	}
 	// Check that there is space for the file.
 	if fileSize > uint64(h.spaceRemaining) {
-		err = errors.New("host is at capacity and can not take more files.")
+		err = ErrHostFull
 		return
 	}
 	// Check that the duration of the contract is in bounds.
 	if fullDuration < h.announcement.MinDuration || fullDuration > h.announcement.MaxDuration {
-		err = errors.New("contract duration is out of bounds")
+		err = ErrDuration
 		return
 	}
 	// Check that challenges will not be happening too frequently or infrequently.
 	if t.FileContracts[0].ChallengeWindow < h.announcement.MinChallengeWindow || t.FileContracts[0].ChallengeWindow > h.announcement.MaxChallengeWindow {
-		err = errors.New("challenges frequency is too often")
+		err = ErrChallengeWindow
 		return
 	}
 	// Check that tolerance is acceptible.
 	if t.FileContracts[0].Tolerance < h.announcement.MinTolerance {
-		err = errors.New("tolerance is too low")
+		err = ErrTolerance
 		return
 	}
 	// Outputs for successful proofs need to go to the correct address.
 	if t.FileContracts[0].ValidProofAddress != h.announcement.CoinAddress {
-		err = errors.New("coins are not paying out to correct address")
+		err = ErrPayoutAddress
 		return
 	}
 	// Outputs for successful proofs need to match the price.
 	requiredSize := h.announcement.Price * consensus.Currency(fileSize) * consensus.Currency(t.FileContracts[0].ChallengeWindow)
 	if t.FileContracts[0].ValidProofPayout < requiredSize {
-		err = errors.New("valid proof payout is too low")
+		err = ErrPayoutTooLow
 		return
 	}
 	// Output for failed proofs needs to be the 0 address.
 	emptyAddress := consensus.CoinAddress{}
 	if t.FileContracts[0].MissedProofAddress != emptyAddress {
-		err = errors.New("burn payout needs to go to the empty address")
+		err = ErrBurnAddress
 		return
 	}
 	// Verify that output for failed proofs matches burn.
 	maxBurn := h.announcement.Burn * consensus.Currency(fileSize) * consensus.Currency(t.FileContracts[0].ChallengeWindow)
 	if t.FileContracts[0].MissedProofPayout > maxBurn {
-		err = errors.New("burn payout is too high for a missed proof.")
+		err = ErrBurnTooHigh
 		return
 	}
 	// Verify that the contract fund covers the payout and burn for the whole
 	// duration.
 	requiredFund := (h.announcement.Price*consensus.Currency(fullDuration) + h.announcement.Burn*consensus.Currency(contractDuration)) * consensus.Currency(fileSize)
 	if t.FileContracts[0].ContractFund < requiredFund {
-		err = errors.New("ContractFund does not cover the entire duration of the contract.")
+		err = ErrInsufficientFund
 		return
 	}
 
@@ -110,7 +128,7 @@ func (h *Host) considerContract(t consensus.Transaction) (updatedTransaction con
 	if err != nil {
 		return
 	}
-	err = h.wallet.FundTransaction(id, penalty)
+	_, _, err = h.wallet.FundTransaction(id, penalty)
 	if err != nil {
 		// TODO: This leaks that the host is out of money.
 		return
@@ -144,10 +162,10 @@ func (h *Host) NegotiateContract(conn net.Conn) (err error) {
 	t, err = h.considerContract(t)
 	h.mu.Unlock()
 	if err != nil {
-		_, err = encoding.WriteObject(conn, err.Error())
+		err = network.WriteError(conn, err)
 		return
 	}
-	_, err = encoding.WriteObject(conn, components.AcceptContractResponse)
+	err = network.WriteError(conn, nil)
 	if err != nil {
 		return
 	}
@@ -171,23 +189,29 @@ func (h *Host) NegotiateContract(conn net.Conn) (err error) {
 		}
 	}()
 
-	// Download file contents.
-	_, err = io.CopyN(file, conn, int64(t.FileContracts[0].FileSize))
+	// Download file contents, building the merkle tree as the data arrives.
+	cb := hash.NewCacheBuilder()
+	_, err = io.CopyN(io.MultiWriter(file, cb), conn, int64(t.FileContracts[0].FileSize))
 	if err != nil {
 		return
 	}
 
 	// Check that the file matches the merkle root in the contract.
-	_, err = file.Seek(0, 0)
-	if err != nil {
+	if cb.Root() != t.FileContracts[0].FileMerkleRoot {
+		err = errors.New("uploaded file has wrong merkle root")
 		return
 	}
-	merkleRoot, err := hash.ReaderMerkleRoot(file, hash.CalculateSegments(t.FileContracts[0].FileSize))
+
+	// Save the intermediate nodes of the merkle tree, so that storage proofs
+	// don't require re-hashing the file.
+	cacheFile, err := os.Create(cacheName(fullname))
 	if err != nil {
 		return
 	}
-	if merkleRoot != t.FileContracts[0].FileMerkleRoot {
-		err = errors.New("uploaded file has wrong merkle root")
+	_, err = cb.WriteTo(cacheFile)
+	cacheFile.Close()
+	if err != nil {
+		os.Remove(cacheName(fullname))
 		return
 	}
 
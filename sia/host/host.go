@@ -11,6 +11,7 @@ import (
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/hash"
+	"github.com/NebulousLabs/Sia/network"
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
@@ -123,18 +124,27 @@ func (h *Host) RetrieveFile(conn net.Conn) (err error) {
 	contractObligation, exists := h.contracts[contractID]
 	if !exists {
 		h.mu.RUnlock()
-		return errors.New("no record of that file")
+		return network.WriteError(conn, ErrNoFile)
 	}
 	h.mu.RUnlock()
 
-	// Open the file.
+	// Open the file. The error from os.Open is not sent to the renter, since
+	// it contains the path of the file on the host.
 	fullname := filepath.Join(h.hostDir, contractObligation.filename)
 	file, err := os.Open(fullname)
 	if err != nil {
-		return
+		return network.WriteError(conn, ErrFileUnavailable)
 	}
 	defer file.Close()
 
+	// Inform the renter that the file is on its way. Hosts running earlier
+	// versions send the file without this status, so renters and hosts must
+	// be upgraded together.
+	err = network.WriteError(conn, nil)
+	if err != nil {
+		return
+	}
+
 	// Transmit the file.
 	_, err = io.Copy(conn, file)
 	if err != nil {
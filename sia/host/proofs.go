@@ -72,7 +72,7 @@ func (h *Host) consensusListen(updateChan chan consensus.ConsensusChange) {
 				fmt.Println("High Priority Error: RegisterTransaction failed:", err)
 				continue
 			}
-			err = h.wallet.FundTransaction(id, minerFee)
+			_, _, err = h.wallet.FundTransaction(id, minerFee)
 			if err != nil {
 				fmt.Println("High Priority Error: FundTransaction failed:", err)
 				continue
@@ -111,6 +111,7 @@ func (h *Host) consensusListen(updateChan chan consensus.ConsensusChange) {
 			if err != nil {
 				fmt.Println(err)
 			}
+			os.Remove(cacheName(fullpath))
 			delete(h.contracts, contractID)
 		}
 
@@ -118,6 +119,12 @@ func (h *Host) consensusListen(updateChan chan consensus.ConsensusChange) {
 	}
 }
 
+// cacheName returns the name of the file holding the merkle cache of the file
+// at path.
+func cacheName(path string) string {
+	return path + ".cache"
+}
+
 // Create a proof of storage for a contract, using the state height to
 // determine the random seed. Create proof must be under a host and state lock.
 func (h *Host) createStorageProof(entry ContractEntry, heightForProof consensus.BlockHeight) (sp consensus.StorageProof, err error) {
@@ -146,7 +153,18 @@ func (h *Host) createStorageProof(entry ContractEntry, heightForProof consensus.
 	if err != nil {
 		return
 	}
-	base, hashSet, err := hash.BuildReaderProof(file, numSegments, segmentIndex)
+
+	// Use the merkle cache if there is one. Files that were uploaded before
+	// caches were introduced are re-hashed.
+	var base [hash.SegmentSize]byte
+	var hashSet []hash.Hash
+	cache, err := os.Open(cacheName(fullname))
+	if err == nil {
+		defer cache.Close()
+		base, hashSet, err = hash.BuildCachedProof(file, cache, numSegments, segmentIndex)
+	} else {
+		base, hashSet, err = hash.BuildReaderProof(file, numSegments, segmentIndex)
+	}
 	if err != nil {
 		return
 	}
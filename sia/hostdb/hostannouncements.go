@@ -6,6 +6,14 @@ import (
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
+// A legacyHostAnnouncement has the same fields as a HostAnnouncement, but is
+// not versioned, and so can decode announcements that were made before
+// HostAnnouncement became versioned.
+//
+// TODO: once fields are added to HostAnnouncement, this will need to list
+// the original fields explicitly.
+type legacyHostAnnouncement components.HostAnnouncement
+
 // findHostAnnouncements scans a block and pulls out every host announcement
 // that appears in the block, returning a list of entries that correspond with
 // the announcements.
@@ -20,9 +28,13 @@ func findHostAnnouncements(height consensus.BlockHeight, b consensus.Block) (ent
 		}
 
 		dataIndicator := encoding.DecUint64([]byte(t.ArbitraryData[0][0:8]))
-		if dataIndicator == 1 {
+		if dataIndicator == components.HostAnnouncementPrefix || dataIndicator == components.LegacyHostAnnouncementPrefix {
 			var ha components.HostAnnouncement
-			err = encoding.Unmarshal([]byte(t.ArbitraryData[0][8:]), &ha)
+			if dataIndicator == components.HostAnnouncementPrefix {
+				err = encoding.Unmarshal([]byte(t.ArbitraryData[0][8:]), &ha)
+			} else {
+				err = encoding.Unmarshal([]byte(t.ArbitraryData[0][8:]), (*legacyHostAnnouncement)(&ha))
+			}
 			if err != nil {
 				return
 			}
@@ -1,15 +1,19 @@
 package sia
 
 import (
+	"fmt"
+	"math/rand"
 	"time"
 
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/hash"
 	"github.com/NebulousLabs/Sia/network"
 )
 
 // initializeNetwork registers the rpcs and bootstraps to the network,
 // downlading all of the blocks and establishing a peer list.
-func (c *Core) initializeNetwork(addr string, nobootstrap bool) (err error) {
-	c.server, err = network.NewTCPServer(addr)
+func (c *Core) initializeNetwork(addr string, transport network.Transport, nobootstrap, lanDiscovery bool) (err error) {
+	c.server, err = network.NewTCPServerWithTransport(addr, transport)
 	if err != nil {
 		return
 	}
@@ -38,19 +42,28 @@ func (c *Core) initializeNetwork(addr string, nobootstrap bool) (err error) {
 	// Start listener thread
 	go c.listen()
 
-	// If we aren't bootstrapping, then we're done.
-	// TODO: this means the CatchUp thread isn't spawned.
-	// It should probably be spawned after the first peer connects.
-	if nobootstrap {
-		return
+	// Find peers on the local network that share our genesis block, and
+	// synchronize with each one as it is found. Discovery is not essential,
+	// so the node keeps running if it cannot be started.
+	if lanDiscovery {
+		var genesis consensus.Block
+		genesis, err = c.state.BlockAtHeight(0)
+		if err != nil {
+			return
+		}
+		err = c.server.StartDiscovery(hash.Hash(genesis.ID()), c.CatchUp)
+		if err != nil {
+			fmt.Println("Warning: could not start LAN discovery:", err)
+			err = nil
+		}
 	}
 
-	// Bootstrapping may take a while.
 	go func() {
-		// Establish an initial peer list.
-		if err = c.server.Bootstrap(); err != nil {
-			// log error
-			return
+		// Establish an initial peer list. Without one, the node relies on
+		// peers that are discovered or added manually.
+		if !nobootstrap {
+			// TODO: log error
+			c.server.Bootstrap()
 		}
 
 		// Every 2 minutes, call CatchUp() on a random peer. This will help to
@@ -58,7 +71,9 @@ func (c *Core) initializeNetwork(addr string, nobootstrap bool) (err error) {
 		// with regards to the longest chain. It's a bit of a hack but will
 		// make the network substantially more robust.
 		for {
-			go c.CatchUp(c.RandomPeer())
+			if peers := c.server.AddressBook(); len(peers) != 0 {
+				go c.CatchUp(peers[rand.Intn(len(peers))])
+			}
 			time.Sleep(time.Minute * 2)
 		}
 	}()
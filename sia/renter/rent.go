@@ -11,6 +11,7 @@ import (
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/hash"
+	"github.com/NebulousLabs/Sia/network"
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
@@ -39,7 +40,7 @@ func (r *Renter) proposeContract(filename string, duration consensus.BlockHeight
 		if err != nil {
 			return
 		}
-		merkle, err := hash.ReaderMerkleRoot(file, hash.CalculateSegments(uint64(info.Size())))
+		merkle, err := hash.ParallelReaderMerkleRoot(file, hash.CalculateSegments(uint64(info.Size())))
 		if err != nil {
 			return
 		}
@@ -86,7 +87,7 @@ func (r *Renter) proposeContract(filename string, duration consensus.BlockHeight
 			if err != nil {
 				return
 			}
-			err = r.wallet.FundTransaction(id, renterPortion+minerFee)
+			_, _, err = r.wallet.FundTransaction(id, renterPortion+minerFee)
 			if err != nil {
 				return
 			}
@@ -105,26 +106,25 @@ func (r *Renter) proposeContract(filename string, duration consensus.BlockHeight
 			}
 
 			// Negotiate the contract to the host.
-			err = host.IPAddress.Call("NegotiateContract", func(conn net.Conn) error {
+			err = r.call(host.IPAddress, "NegotiateContract", func(conn net.Conn) error {
 				// send contract
 				if _, err := encoding.WriteObject(conn, transaction); err != nil {
 					return err
 				}
 				// read response
-				var response string
-				if err := encoding.ReadObject(conn, &response, 128); err != nil {
+				if err := network.ReadError(conn); err != nil {
 					return err
 				}
-				if response != components.AcceptContractResponse {
-					return errors.New(response)
-				}
 				// host accepted, so transmit file data
 				// (no prefix needed, since FileSize is included in the metadata)
-				_, err = io.CopyN(conn, file, info.Size())
+				_, err := io.CopyN(conn, file, info.Size())
 				return err
 			})
 			if err == nil {
 				break
+			} else if err == r.ctx.Err() {
+				// The renter was closed, so the host is not at fault.
+				return
 			}
 
 			fmt.Println("Problem from NegotiateContract:", err)
@@ -247,7 +247,7 @@ func (r *Renter) proposeSmallContract(fullFile []byte, duration consensus.BlockH
 		}
 
 		// Try to fund the transaction, and wait if there isn't enough money.
-		err = r.wallet.FundTransaction(id, renterPortion+minerFee)
+		_, _, err = r.wallet.FundTransaction(id, renterPortion+minerFee)
 		if err != nil && err != components.LowBalanceErr {
 			return
 		}
@@ -257,8 +257,13 @@ func (r *Renter) proposeSmallContract(fullFile []byte, duration consensus.BlockH
 			// through. Significant problem :(
 
 			// There should be no locks at this point.
-			time.Sleep(time.Second * 30)
-			err = r.wallet.FundTransaction(id, renterPortion+minerFee)
+			select {
+			case <-time.After(time.Second * 30):
+			case <-r.ctx.Done():
+				err = r.ctx.Err()
+				return
+			}
+			_, _, err = r.wallet.FundTransaction(id, renterPortion+minerFee)
 		}
 
 		err = r.wallet.AddMinerFee(id, minerFee)
@@ -277,26 +282,25 @@ func (r *Renter) proposeSmallContract(fullFile []byte, duration consensus.BlockH
 		contractID = transaction.FileContractID(0)
 
 		// Negotiate the contract to the host.
-		err = host.IPAddress.Call("NegotiateContract", func(conn net.Conn) error {
+		err = r.call(host.IPAddress, "NegotiateContract", func(conn net.Conn) error {
 			// send contract
 			if _, err := encoding.WriteObject(conn, transaction); err != nil {
 				return err
 			}
 			// read response
-			var response string
-			if err := encoding.ReadObject(conn, &response, 128); err != nil {
+			if err := network.ReadError(conn); err != nil {
 				return err
 			}
-			if response != components.AcceptContractResponse {
-				return errors.New(response)
-			}
 			// host accepted, so transmit file data
 			// (no prefix needed, since FileSize is included in the metadata)
-			_, err = conn.Write(fullFile)
+			_, err := conn.Write(fullFile)
 			return err
 		})
 		if err == nil {
 			break
+		} else if err == r.ctx.Err() {
+			// The renter was closed, so the host is not at fault.
+			return
 		}
 
 		fmt.Println("Problem from NegotiateContract:", err)
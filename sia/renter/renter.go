@@ -1,15 +1,18 @@
 package renter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/network"
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
@@ -29,6 +32,15 @@ type Renter struct {
 	hostDB components.HostDB
 	wallet components.Wallet
 
+	// ctx is cancelled when the renter is closed, aborting any negotiations
+	// or downloads that are in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// server holds the *network.TCPServer used to contact hosts. It is not
+	// guarded by mu, since hosts are contacted while mu is held.
+	server atomic.Value
+
 	mu sync.RWMutex
 }
 
@@ -62,9 +74,31 @@ func New(state *consensus.State, hdb components.HostDB, wallet components.Wallet
 		wallet: wallet,
 		files:  make(map[string]FileEntry),
 	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
 	return
 }
 
+// SetNetwork implements the components.Renter interface.
+func (r *Renter) SetNetwork(server *network.TCPServer) {
+	r.server.Store(server)
+}
+
+// call calls the named function on a host through the renter's server,
+// aborting if the renter is closed. If no server has been set, the
+// DefaultTransport is used.
+func (r *Renter) call(addr network.Address, name string, fn func(net.Conn) error) error {
+	if server, _ := r.server.Load().(*network.TCPServer); server != nil {
+		return server.CallContext(r.ctx, addr, name, fn)
+	}
+	return addr.CallContext(r.ctx, name, fn)
+}
+
+// Close aborts all network operations that the renter has in progress.
+func (r *Renter) Close() error {
+	r.cancel()
+	return nil
+}
+
 func (r *Renter) RenameFile(currentName, newName string) error {
 	// Check that the currentName exists and the newName doesn't.
 	entry, exists := r.files[currentName]
@@ -83,12 +117,15 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 }
 
 func (r *Renter) downloadPiece(piece FilePiece, destination string) (err error) {
-	return piece.Host.IPAddress.Call("RetrieveFile", func(conn net.Conn) error {
+	return r.call(piece.Host.IPAddress, "RetrieveFile", func(conn net.Conn) error {
 		// send filehash
 		if _, err := encoding.WriteObject(conn, piece.ContractID); err != nil {
 			return err
 		}
-		// TODO: read error
+		// read error
+		if err := network.ReadError(conn); err != nil {
+			return err
+		}
 		// copy response into file
 		file, err := os.Create(destination)
 		if err != nil {
@@ -115,7 +152,7 @@ func (r *Renter) Download(nickname, filename string) (err error) {
 	// doesn't return an error.
 	for _, piece := range entry.Pieces {
 		err = r.downloadPiece(piece, filename)
-		if err == nil {
+		if err == nil || err == r.ctx.Err() {
 			return
 		} else {
 			fmt.Println("Renter got error:", err)
@@ -11,7 +11,10 @@ const (
 	MaxCatchUpBlocks = 100
 )
 
-var moreBlocksErr = errors.New("more blocks are available")
+var (
+	moreBlocksErr      = network.RegisterError(100, "more blocks are available")
+	noMatchingBlockErr = network.RegisterError(101, "no matching block found")
+)
 
 // SendBlocks takes a list of block ids as input, and sends all blocks from
 func (c *Core) SendBlocks(knownBlocks [32]consensus.BlockID) (blocks []consensus.Block, err error) {
@@ -31,7 +34,7 @@ func (c *Core) SendBlocks(knownBlocks [32]consensus.BlockID) (blocks []consensus
 		// The genesis block should be included in knownBlocks - if no matching
 		// blocks are found the caller is probably on a different blockchain
 		// altogether.
-		err = errors.New("no matching block found")
+		err = noMatchingBlockErr
 		return
 	}
 
@@ -86,8 +89,9 @@ func (c *Core) CatchUp(peer network.Address) {
 	copy(blockArray[:], knownBlocks)
 
 	// unlock state during network I/O
-	err := peer.RPC("SendBlocks", blockArray, &newBlocks)
-	if err != nil && err.Error() != moreBlocksErr.Error() {
+	// note: the RPC is aborted if the server is closed
+	err := c.server.RPC(peer, "SendBlocks", blockArray, &newBlocks)
+	if err != nil && !errors.Is(err, moreBlocksErr) {
 		// log error
 		// TODO: try a different peer?
 		return
@@ -99,7 +103,7 @@ func (c *Core) CatchUp(peer network.Address) {
 	// TODO: There is probably a better approach than to call CatchUp
 	// recursively. Furthermore, if there is a reorg that's greater than 100
 	// blocks, CatchUp is going to fail outright.
-	if err != nil && err.Error() == moreBlocksErr.Error() {
+	if errors.Is(err, moreBlocksErr) {
 		go c.CatchUp(peer)
 	}
 }
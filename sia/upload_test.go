@@ -2,10 +2,21 @@ package sia
 
 import (
 	"crypto/rand"
+	"errors"
+	"net"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/network"
 	"github.com/NebulousLabs/Sia/sia/components"
+	"github.com/NebulousLabs/Sia/sia/host"
+	"github.com/NebulousLabs/Sia/sia/hostdb"
+	"github.com/NebulousLabs/Sia/sia/miner"
+	"github.com/NebulousLabs/Sia/sia/renter"
+	"github.com/NebulousLabs/Sia/sia/wallet"
 )
 
 func testUploadFile(t *testing.T, c *Core) {
@@ -63,3 +74,103 @@ func testUploadFile(t *testing.T, c *Core) {
 		t.Error(err)
 	}
 }
+
+// TestRenterTransport creates a Core on an in-memory network, and checks that
+// the renter contacts hosts through the Core's Transport. The host is only
+// reachable on the in-memory network, so the contract could not be proposed
+// using the DefaultTransport.
+func TestRenterTransport(t *testing.T) {
+	mn := network.NewMemNetwork()
+
+	// Create a host that rejects every contract it is offered.
+	proposed := make(chan consensus.Transaction, 1)
+	hostServer, err := network.NewTCPServerWithTransport("host:1", mn.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hostServer.Close()
+	hostServer.RegisterRPC("NegotiateContract", func(conn net.Conn) error {
+		var txn consensus.Transaction
+		if err := encoding.ReadObject(conn, &txn, 1<<20); err != nil {
+			return err
+		}
+		proposed <- txn
+		return network.WriteError(conn, errors.New("contract rejected"))
+	})
+
+	// Create the renter's Core.
+	dir := t.TempDir()
+	state, _ := consensus.CreateGenesisState()
+	w, err := wallet.New(state, filepath.Join(dir, "renter.wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdb, err := hostdb.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := host.New(state, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := renter.New(state, hdb, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CreateCore(Config{
+		HostDir:     filepath.Join(dir, "hostdir"),
+		WalletFile:  filepath.Join(dir, "renter.wallet"),
+		ServerAddr:  "renter:1",
+		Nobootstrap: true,
+		Transport:   mn.Transport(),
+
+		State: state,
+
+		Host:   h,
+		HostDB: hdb,
+		Miner:  miner.New(),
+		Renter: r,
+		Wallet: w,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Give the renter enough coins to pay the miner fee, and tell it about
+	// the host.
+	address, _, err := c.wallet.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs := []consensus.OutputDiff{{
+		New:    true,
+		ID:     consensus.OutputID{1},
+		Output: consensus.Output{Value: 100, SpendHash: address},
+	}}
+	if err = c.wallet.Update(c.Height(), nil, nil, diffs); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.hostDB.Insert(components.HostEntry{ID: "host", IPAddress: hostServer.Address()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The host rejects the contract, after which the renter runs out of
+	// hosts.
+	err = c.RentSmallFile(components.RentSmallFileParameters{
+		FullFile:    []byte("foo"),
+		Nickname:    "foo",
+		TotalPieces: 1,
+	})
+	if err == nil {
+		t.Fatal("expected the upload to fail once the host was flagged")
+	}
+	select {
+	case txn := <-proposed:
+		if len(txn.FileContracts) != 1 {
+			t.Error("expected a file contract, got", txn.FileContracts)
+		}
+	default:
+		t.Fatal("the contract was not proposed through the in-memory network")
+	}
+}
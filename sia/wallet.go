@@ -2,6 +2,7 @@ package sia
 
 import (
 	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
@@ -9,6 +10,12 @@ import (
 // allocateding 'minerFee' as a miner fee. The transaction is submitted to the
 // miner pool, but is also returned.
 func (c *Core) SpendCoins(amount consensus.Currency, dest consensus.CoinAddress) (t consensus.Transaction, err error) {
+	return c.SpendCoinsWithStrategy(amount, dest, components.LargestFirst)
+}
+
+// SpendCoinsWithStrategy is SpendCoins, using 'strategy' to choose the outputs
+// that are spent.
+func (c *Core) SpendCoinsWithStrategy(amount consensus.Currency, dest consensus.CoinAddress, strategy components.SelectionStrategy) (t consensus.Transaction, err error) {
 	// Create and send the transaction.
 	minerFee := consensus.Currency(10) // TODO: wallet supplied miner fee
 	output := consensus.Output{
@@ -19,7 +26,7 @@ func (c *Core) SpendCoins(amount consensus.Currency, dest consensus.CoinAddress)
 	if err != nil {
 		return
 	}
-	err = c.wallet.FundTransaction(id, amount+minerFee)
+	_, _, err = c.wallet.FundTransactionWithStrategy(id, amount+minerFee, strategy)
 	if err != nil {
 		return
 	}
@@ -39,6 +46,17 @@ func (c *Core) SpendCoins(amount consensus.Currency, dest consensus.CoinAddress)
 	return
 }
 
+// ConsolidateWallet sweeps up to maxInputs of the wallet's outputs worth less
+// than threshold into a single output, and submits the transaction.
+func (c *Core) ConsolidateWallet(threshold consensus.Currency, maxInputs int, fee consensus.Currency) (t consensus.Transaction, err error) {
+	t, err = c.wallet.Consolidate(threshold, maxInputs, fee)
+	if err != nil {
+		return
+	}
+	err = c.AcceptTransaction(t)
+	return
+}
+
 // WalletBalance counts up the total number of coins that the wallet knows how
 // to spend, according to the State. WalletBalance will ignore all unconfirmed
 // transactions that have been created.
@@ -53,6 +71,169 @@ func (c *Core) CoinAddress() (address consensus.CoinAddress, err error) {
 	return
 }
 
+// UnlockWallet decrypts the wallet's secret keys using password, allowing coins
+// to be spent. If the wallet is not encrypted, it is encrypted with password.
+func (c *Core) UnlockWallet(password string) error {
+	return c.wallet.Unlock(password)
+}
+
+// LockWallet erases the wallet's secret keys from memory.
+func (c *Core) LockWallet() error {
+	return c.wallet.Lock()
+}
+
+// WalletSeed returns the mnemonic form of the wallet's seed.
+func (c *Core) WalletSeed() (string, error) {
+	return c.wallet.Seed()
+}
+
+// RestoreWallet restores the wallet's keys from the seed encoded by mnemonic.
+func (c *Core) RestoreWallet(mnemonic string) error {
+	return c.wallet.Restore(mnemonic)
+}
+
+// WalletHistory returns a page of the wallet's transaction history, most
+// recent first, optionally limited to the transactions involving address.
+func (c *Core) WalletHistory(address *consensus.CoinAddress, offset, limit int) ([]components.WalletTransaction, int, error) {
+	return c.wallet.History(address, offset, limit)
+}
+
+// LabelWalletTransaction sets the label of an entry in the wallet's
+// transaction history.
+func (c *Core) LabelWalletTransaction(id consensus.TransactionID, label string) error {
+	return c.wallet.LabelTransaction(id, label)
+}
+
+// RescanWallet finds the outputs of the wallet's addresses in the blocks from
+// fromHeight onwards.
+func (c *Core) RescanWallet(fromHeight consensus.BlockHeight) error {
+	return c.wallet.Rescan(fromHeight)
+}
+
+// MultisigKey returns a public key of the wallet that can be shared with
+// cosigners.
+func (c *Core) MultisigKey() (crypto.PublicKey, error) {
+	return c.wallet.MultisigKey()
+}
+
+// CreateMultisigAddress creates an address that requires numSignatures of
+// publicKeys to spend, and starts tracking its outputs.
+func (c *Core) CreateMultisigAddress(numSignatures uint64, publicKeys []crypto.PublicKey) (address consensus.CoinAddress, err error) {
+	address, _, err = c.wallet.CreateMultisigAddress(numSignatures, publicKeys)
+	return
+}
+
+// MultisigAddresses returns the multisig addresses tracked by the wallet.
+func (c *Core) MultisigAddresses() ([]components.MultisigAddressInfo, error) {
+	return c.wallet.MultisigAddresses()
+}
+
+// MultisigSend creates a transaction sending 'amount' from a multisig address
+// to 'dest', signed by the wallet's keys. If the wallet's signatures are
+// enough to spend from the address, the transaction is submitted. complete
+// reports whether the transaction was submitted.
+func (c *Core) MultisigSend(address consensus.CoinAddress, amount consensus.Currency, dest consensus.CoinAddress, fee consensus.Currency) (t consensus.Transaction, complete bool, err error) {
+	t, err = c.wallet.MultisigSend(address, amount, dest, fee)
+	if err != nil {
+		return
+	}
+	complete = c.state.ValidTransaction(t) == nil
+	if complete {
+		c.wallet.MarkSpent(t)
+		err = c.AcceptTransaction(t)
+	}
+	return
+}
+
+// SignMultisig adds the wallet's signatures to a multisig transaction. complete
+// reports whether the transaction now has enough signatures to be valid.
+func (c *Core) SignMultisig(t consensus.Transaction) (signed consensus.Transaction, complete bool, err error) {
+	signed, err = c.wallet.SignMultisig(t)
+	if err != nil {
+		return
+	}
+	complete = c.state.ValidTransaction(signed) == nil
+	return
+}
+
+// MergeMultisig combines the signatures of copies of a multisig transaction
+// signed by different cosigners. complete reports whether the merged
+// transaction has enough signatures to be valid.
+func (c *Core) MergeMultisig(ts []consensus.Transaction) (merged consensus.Transaction, complete bool, err error) {
+	merged, err = c.wallet.MergeMultisig(ts)
+	if err != nil {
+		return
+	}
+	complete = c.state.ValidTransaction(merged) == nil
+	return
+}
+
+// BroadcastTransaction submits a transaction that was signed elsewhere, such
+// as a multisig transaction or one signed offline, returning an error if it is
+// not valid.
+func (c *Core) BroadcastTransaction(t consensus.Transaction) (err error) {
+	err = c.state.ValidTransaction(t)
+	if err != nil {
+		return
+	}
+	c.wallet.MarkSpent(t)
+	return c.AcceptTransaction(t)
+}
+
+// ExportTransaction creates a transaction sending 'amount' to 'dest' and
+// paying 'fee' to the miners, without signing it, so that it can be signed by
+// a wallet holding the secret keys. The wallet does not need to be unlocked.
+func (c *Core) ExportTransaction(amount consensus.Currency, dest consensus.CoinAddress, fee consensus.Currency) (ut components.UnsignedTransaction, err error) {
+	id, err := c.wallet.RegisterTransaction(consensus.Transaction{})
+	if err != nil {
+		return
+	}
+	// Discard the transaction if it cannot be exported, so that it is not
+	// left open in the wallet.
+	defer func() {
+		if err != nil {
+			c.wallet.DiscardTransaction(id)
+		}
+	}()
+	_, _, err = c.wallet.FundUnsignedTransaction(id, amount+fee)
+	if err != nil {
+		return
+	}
+	err = c.wallet.AddMinerFee(id, fee)
+	if err != nil {
+		return
+	}
+	err = c.wallet.AddOutput(id, consensus.Output{Value: amount, SpendHash: dest})
+	if err != nil {
+		return
+	}
+	return c.wallet.ExportTransaction(id, true)
+}
+
+// SignExportedTransaction signs a transaction exported by another wallet if it
+// pays no more than 'maxFee' to the miners. The signed transaction is
+// returned, and is not submitted. 'sent' is the value that leaves the wallet,
+// not counting the fee.
+func (c *Core) SignExportedTransaction(ut components.UnsignedTransaction, maxFee consensus.Currency) (t consensus.Transaction, sent consensus.Currency, err error) {
+	return c.wallet.SignUnsignedTransaction(ut, maxFee)
+}
+
+// WatchAddress starts tracking the outputs of an address without holding its
+// secret keys. If rescan is set, the blockchain is rescanned for outputs that
+// were sent to the address before it was added.
+func (c *Core) WatchAddress(address consensus.CoinAddress, spendConditions *consensus.SpendConditions, rescan bool) (err error) {
+	err = c.wallet.WatchAddress(address, spendConditions)
+	if err != nil || !rescan {
+		return
+	}
+	return c.wallet.Rescan(0)
+}
+
+// WatchAddresses returns the watch-only addresses tracked by the wallet.
+func (c *Core) WatchAddresses() ([]components.WatchAddressInfo, error) {
+	return c.wallet.WatchAddresses()
+}
+
 // Returns a []byte that's supposed to be json of some struct.
 func (c *Core) WalletInfo() (components.WalletInfo, error) {
 	return c.wallet.WalletInfo()
@@ -5,66 +5,114 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 )
 
-// TimelockedCoinAddress returns an address that can only be spent after block
-// `unlockHeight`.
-func (w *Wallet) timelockedCoinAddress(unlockHeight consensus.BlockHeight) (coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions, err error) {
-	// Create the address + spend conditions.
-	sk, pk, err := crypto.GenerateSignatureKeys()
-	if err != nil {
-		return
-	}
-	spendConditions = consensus.SpendConditions{
-		TimeLock:      unlockHeight,
-		NumSignatures: 1,
-		PublicKeys:    []crypto.PublicKey{pk},
+// deriveKey derives the next key from the seed and adds it to the wallet. The
+// wallet must be unlocked.
+func (w *Wallet) deriveKey() {
+	sk, pk := crypto.DeriveSignatureKeys(*w.seed, w.seedIndex)
+	w.seedIndex++
+	w.publicKeys = append(w.publicKeys, pk)
+	w.secretKeys = append(w.secretKeys, sk)
+}
+
+// newKey returns the index of an unused key. While the wallet is unlocked, a
+// key is derived if the key pool is empty. While the wallet is locked, keys
+// can only be taken from the key pool.
+func (w *Wallet) newKey() (index int, err error) {
+	if w.nextKey == len(w.publicKeys) {
+		if w.locked() {
+			err = ErrLocked
+			return
+		}
+		w.deriveKey()
 	}
-	coinAddress = spendConditions.CoinAddress()
+	index = w.nextKey
+	w.nextKey++
+	return
+}
 
-	// Create a spendableAddress for the keys and add it to the
-	// timelockedSpendableAddresses map. If the address has already been
-	// unlocked, also add it to the list of currently spendable addresses. It
-	// needs to go in both though in case there is a reorganization of the
-	// blockchain.
+// addAddress adds an address to the wallet. Timelocked addresses are only
+// spendable once the current height reaches their timelock.
+func (w *Wallet) addAddress(spendConditions consensus.SpendConditions, keyIndex int) {
 	newSpendableAddress := &spendableAddress{
 		spendableOutputs: make(map[consensus.OutputID]*spendableOutput),
 		spendConditions:  spendConditions,
-		secretKey:        sk,
+		keyIndex:         keyIndex,
+	}
+
+	// Timelocked addresses are added to the timelockedSpendableAddresses
+	// map. If the address has already been unlocked, it is also added to the
+	// list of currently spendable addresses. It needs to go in both though in
+	// case there is a reorganization of the blockchain.
+	unlockHeight := spendConditions.TimeLock
+	if unlockHeight != 0 {
+		w.timelockedSpendableAddresses[unlockHeight] = append(w.timelockedSpendableAddresses[unlockHeight], newSpendableAddress)
 	}
-	spendableAddressSlice := w.timelockedSpendableAddresses[unlockHeight]
-	spendableAddressSlice = append(spendableAddressSlice, newSpendableAddress)
-	w.timelockedSpendableAddresses[unlockHeight] = spendableAddressSlice
 	if unlockHeight <= w.state.Height() {
-		w.spendableAddresses[coinAddress] = newSpendableAddress
+		w.spendableAddresses[spendConditions.CoinAddress()] = newSpendableAddress
 	}
+}
 
-	err = w.save()
-	if err != nil {
-		return
+// addressSet returns the set of every address known to the wallet, including
+// multisig and watch-only addresses.
+func (w *Wallet) addressSet() (addresses map[consensus.CoinAddress]*spendableAddress) {
+	addresses = make(map[consensus.CoinAddress]*spendableAddress)
+	for _, sa := range w.allAddresses() {
+		addresses[sa.spendConditions.CoinAddress()] = sa
+	}
+	for coinAddress, sa := range w.multisigAddresses {
+		addresses[coinAddress] = sa
+	}
+	for coinAddress, sa := range w.watchAddresses {
+		addresses[coinAddress] = sa
+	}
+	return
+}
+
+// usedAddresses returns the set of tracked addresses that have received coins,
+// according to the outputs and history of the wallet.
+func (w *Wallet) usedAddresses() (used map[consensus.CoinAddress]struct{}) {
+	used = make(map[consensus.CoinAddress]struct{})
+	for _, entry := range w.history {
+		for _, address := range entry.Addresses {
+			used[address] = struct{}{}
+		}
 	}
+	for address, sa := range w.addressSet() {
+		if len(sa.spendableOutputs) != 0 {
+			used[address] = struct{}{}
+		}
+	}
+	return
+}
 
+// trackedAddress returns the address whose outputs are tracked by the wallet,
+// which is either a spendable, multisig, or watch-only address.
+func (w *Wallet) trackedAddress(coinAddress consensus.CoinAddress) (sa *spendableAddress, exists bool) {
+	if sa, exists = w.spendableAddresses[coinAddress]; exists {
+		return
+	}
+	if sa, exists = w.multisigAddresses[coinAddress]; exists {
+		return
+	}
+	sa, exists = w.watchAddresses[coinAddress]
 	return
 }
 
-// coinAddress implements the core.Wallet interface.
-func (w *Wallet) coinAddress() (coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions, err error) {
-	// Create the keys and address.
-	sk, pk, err := crypto.GenerateSignatureKeys()
+// TimelockedCoinAddress returns an address that can only be spent after block
+// `unlockHeight`.
+func (w *Wallet) timelockedCoinAddress(unlockHeight consensus.BlockHeight) (coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions, err error) {
+	// Create the address + spend conditions.
+	keyIndex, err := w.newKey()
 	if err != nil {
 		return
 	}
 	spendConditions = consensus.SpendConditions{
+		TimeLock:      unlockHeight,
 		NumSignatures: 1,
-		PublicKeys:    []crypto.PublicKey{pk},
+		PublicKeys:    []crypto.PublicKey{w.publicKeys[keyIndex]},
 	}
 	coinAddress = spendConditions.CoinAddress()
-
-	// Add the address to the set of spendable addresses.
-	newSpendableAddress := &spendableAddress{
-		spendableOutputs: make(map[consensus.OutputID]*spendableOutput),
-		spendConditions:  spendConditions,
-		secretKey:        sk,
-	}
-	w.spendableAddresses[coinAddress] = newSpendableAddress
+	w.addAddress(spendConditions, keyIndex)
 
 	err = w.save()
 	if err != nil {
@@ -74,6 +122,11 @@ func (w *Wallet) coinAddress() (coinAddress consensus.CoinAddress, spendConditio
 	return
 }
 
+// coinAddress implements the core.Wallet interface.
+func (w *Wallet) coinAddress() (coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions, err error) {
+	return w.timelockedCoinAddress(0)
+}
+
 // TimelockedCoinAddress returns an address that can only be spent after block
 // `unlockHeight`.
 func (w *Wallet) TimelockedCoinAddress(unlockHeight consensus.BlockHeight) (coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions, err error) {
@@ -1,55 +1,242 @@
 package wallet
 
 import (
+	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+var (
+	// encryptedWalletHeader begins every encrypted wallet file, and
+	// plaintextWalletHeader begins every unencrypted wallet file that holds a
+	// seed. Older unencrypted wallet files have no header.
+	encryptedWalletHeader = []byte("Sia Encrypted Wallet\n")
+	plaintextWalletHeader = []byte("Sia Wallet\n")
 )
 
 // AddressKey is how we serialize and store spendable addresses on
-// disk.
+// disk. AddressKey is a versioned struct, so new fields must be appended and
+// tagged with the version that introduced them.
 type AddressKey struct {
 	SpendConditions consensus.SpendConditions
 	SecretKey       crypto.SecretKey
 }
 
-func (w *Wallet) save() (err error) {
-	// Add every known spendable address + secret key.
-	var i int
-	keys := make([]AddressKey, len(w.spendableAddresses))
-	for _, spendableAddress := range w.spendableAddresses {
-		key := AddressKey{
-			SpendConditions: spendableAddress.spendConditions,
-			SecretKey:       spendableAddress.secretKey,
-		}
-		keys[i] = key
-		i++
-	}
+// legacyAddressKey is the unversioned form of AddressKey, used by wallet
+// files that were written before AddressKey became versioned.
+type legacyAddressKey AddressKey
+
+// SiaVersion implements the encoding.VersionedStruct interface.
+func (AddressKey) SiaVersion() uint64 {
+	return 1
+}
+
+// walletAddress is how an address is stored in an encrypted wallet file. The
+// secret key of the address is stored separately, at index KeyIndex.
+type walletAddress struct {
+	SpendConditions consensus.SpendConditions
+	KeyIndex        uint64
+}
+
+// watchedAddress is how a watch-only address is stored. SpendConditions is
+// the zero value if the spend conditions of the address are not known.
+type watchedAddress struct {
+	Address         consensus.CoinAddress
+	SpendConditions consensus.SpendConditions
+}
+
+// plaintextWallet is the format of an unencrypted wallet file.
+type plaintextWallet struct {
+	Seed      crypto.Seed
+	SeedIndex uint64
+	Keys      []AddressKey
+	History   []components.WalletTransaction `sia:"v2"`
+	Multisig  []consensus.SpendConditions    `sia:"v3"`
+	Watch     []watchedAddress               `sia:"v4"`
+}
+
+// SiaVersion implements the encoding.VersionedStruct interface.
+func (plaintextWallet) SiaVersion() uint64 {
+	return 4
+}
+
+// encryptedWallet is the format of an encrypted wallet file. Only the secret
+// keys and the seed are encrypted, so that a locked wallet can still hand out
+// addresses from its key pool and save them. SealedKeys holds the secret
+// keys, in the same order as PublicKeys, and SealedSeed holds the seed, both
+// encrypted with a key derived from the password.
+type encryptedWallet struct {
+	KDF        crypto.KDFParams
+	PublicKeys []crypto.PublicKey
+	NextKey    uint64
+	Addresses  []walletAddress
+	SealedKeys []byte
+	SeedIndex  uint64                         `sia:"v2"`
+	SealedSeed []byte                         `sia:"v2"`
+	History    []components.WalletTransaction `sia:"v3"`
+	Multisig   []consensus.SpendConditions    `sia:"v4"`
+	Watch      []watchedAddress               `sia:"v5"`
+}
+
+// SiaVersion implements the encoding.VersionedStruct interface.
+func (encryptedWallet) SiaVersion() uint64 {
+	return 5
+}
 
-	//  write the file
-	fileData := encoding.Marshal(keys)
+// seal encrypts the encoding of v with key.
+func seal(key crypto.EncryptionKey, v interface{}) (sealed []byte, err error) {
+	var buf bytes.Buffer
+	ew, err := crypto.NewEncryptWriter(key, &buf)
 	if err != nil {
 		return
 	}
-	err = ioutil.WriteFile(w.saveFilename, fileData, 0666)
+	if _, err = ew.Write(encoding.Marshal(v)); err != nil {
+		return
+	}
+	if err = ew.Close(); err != nil {
+		return
+	}
+	sealed = buf.Bytes()
+	return
+}
+
+// open decrypts data that was encrypted by seal, and decodes it into v. An
+// error is returned if key is incorrect.
+func open(key crypto.EncryptionKey, sealed []byte, v interface{}) (err error) {
+	dr, err := crypto.NewDecryptReader(key, bytes.NewReader(sealed))
 	if err != nil {
 		return
 	}
+	plaintext, err := ioutil.ReadAll(dr)
+	if err == crypto.ErrStreamCorrupted {
+		return ErrBadPassword
+	} else if err != nil {
+		return
+	}
+	return encoding.Unmarshal(plaintext, v)
+}
 
+// allAddresses returns every address known to the wallet, including
+// timelocked addresses that cannot be spent yet.
+func (w *Wallet) allAddresses() (addresses []*spendableAddress) {
+	seen := make(map[consensus.CoinAddress]struct{})
+	add := func(sa *spendableAddress) {
+		coinAddress := sa.spendConditions.CoinAddress()
+		if _, exists := seen[coinAddress]; !exists {
+			seen[coinAddress] = struct{}{}
+			addresses = append(addresses, sa)
+		}
+	}
+	for _, sa := range w.spendableAddresses {
+		add(sa)
+	}
+	for _, sas := range w.timelockedSpendableAddresses {
+		for _, sa := range sas {
+			add(sa)
+		}
+	}
 	return
 }
 
+func (w *Wallet) save() (err error) {
+	var fileData []byte
+	if w.encrypted {
+		// Re-encrypt the secret keys if they have changed. While the wallet
+		// is locked, the keys cannot have changed.
+		if !w.locked() {
+			w.sealedKeys, err = seal(w.fileKey, w.secretKeys)
+			if err != nil {
+				return
+			}
+			w.sealedSeed, err = seal(w.fileKey, *w.seed)
+			if err != nil {
+				return
+			}
+		}
+		ew := encryptedWallet{
+			KDF:        w.kdf,
+			PublicKeys: w.publicKeys,
+			NextKey:    uint64(w.nextKey),
+			SealedKeys: w.sealedKeys,
+			SeedIndex:  w.seedIndex,
+			SealedSeed: w.sealedSeed,
+			History:    w.history,
+			Multisig:   w.multisigConditions(),
+			Watch:      w.watchedAddresses(),
+		}
+		for _, sa := range w.allAddresses() {
+			ew.Addresses = append(ew.Addresses, walletAddress{sa.spendConditions, uint64(sa.keyIndex)})
+		}
+		fileData = append(encryptedWalletHeader, encoding.Marshal(ew)...)
+	} else {
+		// Add every known spendable address + secret key.
+		pw := plaintextWallet{
+			Seed:      *w.seed,
+			SeedIndex: w.seedIndex,
+			History:   w.history,
+			Multisig:  w.multisigConditions(),
+			Watch:     w.watchedAddresses(),
+		}
+		for _, sa := range w.allAddresses() {
+			pw.Keys = append(pw.Keys, AddressKey{
+				SpendConditions: sa.spendConditions,
+				SecretKey:       w.secretKeys[sa.keyIndex],
+			})
+		}
+		fileData = append(plaintextWalletHeader, encoding.Marshal(pw)...)
+	}
+
+	return writeFileAtomic(w.saveFilename, fileData)
+}
+
+// writeFileAtomic replaces the contents of filename with data. The data is
+// written to a temporary file, which is synced and then renamed over the
+// original, so that a crash cannot leave a partially written wallet behind.
+// The new file is only readable by its owner, even if the original was not.
+func writeFileAtomic(filename string, data []byte) (err error) {
+	file, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+	if err = file.Chmod(0600); err != nil {
+		file.Close()
+		return
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return
+	}
+	if err = file.Close(); err != nil {
+		return
+	}
+	return os.Rename(file.Name(), filename)
+}
+
 // Save implements the core.Wallet interface.
 func (w *Wallet) Save() (err error) {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.save()
 }
 
-// Load implements the core.Wallet interface.
+// Load implements the core.Wallet interface. Encrypted wallets are locked
+// after being loaded.
 func (w *Wallet) Load(filename string) (err error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -72,19 +259,91 @@ func (w *Wallet) Load(filename string) (err error) {
 		return
 	}
 
-	// Unmarshal the spendable addresses and put them into the wallet.
+	if bytes.HasPrefix(contents, encryptedWalletHeader) {
+		return w.loadEncrypted(contents[len(encryptedWalletHeader):])
+	}
+
+	// Unmarshal the spendable addresses and put them into the wallet. Wallet
+	// files without a header predate seeds, and are given a new seed by New.
 	var keys []AddressKey
-	err = encoding.Unmarshal(contents, &keys)
-	if err != nil {
-		return
+	var multisig []consensus.SpendConditions
+	var watch []watchedAddress
+	if bytes.HasPrefix(contents, plaintextWalletHeader) {
+		var pw plaintextWallet
+		if err = encoding.Unmarshal(contents[len(plaintextWalletHeader):], &pw); err != nil {
+			return
+		}
+		w.seed = &pw.Seed
+		w.seedIndex = pw.SeedIndex
+		w.history = pw.History
+		w.indexHistory()
+		keys = pw.Keys
+		multisig = pw.Multisig
+		watch = pw.Watch
+	} else if err = encoding.Unmarshal(contents, &keys); err != nil {
+		// Fall back to the unversioned format.
+		var legacyKeys []legacyAddressKey
+		if encoding.Unmarshal(contents, &legacyKeys) != nil {
+			return
+		}
+		err = nil
+		keys = make([]AddressKey, len(legacyKeys))
+		for i := range legacyKeys {
+			keys[i] = AddressKey(legacyKeys[i])
+		}
 	}
 	for _, key := range keys {
-		newSpendableAddress := &spendableAddress{
-			spendableOutputs: make(map[consensus.OutputID]*spendableOutput),
-			spendConditions:  key.SpendConditions,
-			secretKey:        key.SecretKey,
+		if len(key.SpendConditions.PublicKeys) == 0 {
+			return errors.New("wallet file contains an address without a public key")
 		}
-		w.spendableAddresses[key.SpendConditions.CoinAddress()] = newSpendableAddress
+		w.publicKeys = append(w.publicKeys, key.SpendConditions.PublicKeys[0])
+		w.secretKeys = append(w.secretKeys, key.SecretKey)
+		w.addAddress(key.SpendConditions, w.nextKey)
+		w.nextKey++
+	}
+	for _, spendConditions := range multisig {
+		w.addMultisig(spendConditions)
+	}
+	for _, wa := range watch {
+		w.addWatch(wa.Address, wa.SpendConditions)
+	}
+	return
+}
+
+// loadEncrypted loads an encrypted wallet file, leaving the wallet locked.
+func (w *Wallet) loadEncrypted(contents []byte) (err error) {
+	var ew encryptedWallet
+	if err = encoding.Unmarshal(contents, &ew); err != nil {
+		return
+	}
+	if ew.NextKey > uint64(len(ew.PublicKeys)) {
+		return errors.New("wallet file is corrupted")
+	}
+	if err = crypto.CheckKDFParams(ew.KDF); err != nil {
+		return
+	}
+	w.encrypted = true
+	w.kdf = ew.KDF
+	w.publicKeys = ew.PublicKeys
+	w.secretKeys = nil
+	w.seed = nil
+	w.nextKey = int(ew.NextKey)
+	w.sealedKeys = ew.SealedKeys
+	w.seedIndex = ew.SeedIndex
+	w.sealedSeed = ew.SealedSeed
+	w.history = ew.History
+	w.indexHistory()
+	for _, wa := range ew.Addresses {
+		if wa.KeyIndex >= ew.NextKey {
+			return errors.New("wallet file is corrupted")
+		}
+		w.addAddress(wa.SpendConditions, int(wa.KeyIndex))
+	}
+	for _, spendConditions := range ew.Multisig {
+		w.addMultisig(spendConditions)
+	}
+	for _, wa := range ew.Watch {
+		w.addWatch(wa.Address, wa.SpendConditions)
 	}
 	return
 }
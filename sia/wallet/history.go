@@ -0,0 +1,192 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+var (
+	ErrUnknownTransaction = errors.New("transaction is not in the wallet's history")
+)
+
+// appendUnique appends an address to a list of addresses if it is not already
+// present.
+func appendUnique(list []consensus.CoinAddress, address consensus.CoinAddress) []consensus.CoinAddress {
+	for _, a := range list {
+		if a == address {
+			return list
+		}
+	}
+	return append(list, address)
+}
+
+// blockHistory returns the history entries for the transactions in a block
+// that involve one of the addresses. The value of each output that the
+// transactions spend from the addresses is looked up with outputValue, and is
+// only counted if it is known.
+func blockHistory(b consensus.Block, height consensus.BlockHeight, addresses map[consensus.CoinAddress]*spendableAddress, outputValue func(consensus.CoinAddress, consensus.OutputID) (consensus.Currency, bool)) (entries []components.WalletTransaction) {
+	bid := b.ID()
+
+	var blockFees consensus.Currency
+	for _, txn := range b.Transactions {
+		entry := components.WalletTransaction{
+			ID:                 txn.ID(),
+			BlockID:            bid,
+			ConfirmationHeight: height,
+		}
+		// If the wallet funded the transaction, the counterparties are the
+		// recipients of the other outputs. Otherwise, they are the senders.
+		var senders, recipients []consensus.CoinAddress
+		for _, input := range txn.Inputs {
+			address := input.SpendConditions.CoinAddress()
+			if _, exists := addresses[address]; !exists {
+				senders = appendUnique(senders, address)
+				continue
+			}
+			entry.Addresses = appendUnique(entry.Addresses, address)
+			if value, exists := outputValue(address, input.OutputID); exists {
+				entry.Outflow += value
+			}
+		}
+		funded := len(entry.Addresses) != 0
+		for _, output := range txn.Outputs {
+			if _, exists := addresses[output.SpendHash]; exists {
+				entry.Addresses = appendUnique(entry.Addresses, output.SpendHash)
+				entry.Inflow += output.Value
+			} else {
+				recipients = appendUnique(recipients, output.SpendHash)
+			}
+		}
+		entry.Counterparties = senders
+		if funded {
+			entry.Counterparties = recipients
+		}
+		for _, fee := range txn.MinerFees {
+			blockFees += fee
+			if funded {
+				entry.Fees += fee
+			}
+		}
+		if len(entry.Addresses) != 0 {
+			entries = append(entries, entry)
+		}
+	}
+
+	// The block subsidy is recorded as its own entry.
+	if _, exists := addresses[b.MinerAddress]; exists {
+		entries = append(entries, components.WalletTransaction{
+			ID:                 consensus.TransactionID(b.SubsidyID()),
+			BlockID:            bid,
+			ConfirmationHeight: height,
+			MinerPayout:        true,
+			Inflow:             consensus.CalculateCoinbase(height) + blockFees,
+			Addresses:          []consensus.CoinAddress{b.MinerAddress},
+		})
+	}
+	return
+}
+
+// updateHistory removes the entries of rewound blocks from the transaction
+// history, and adds the entries of applied blocks. A transaction that is
+// confirmed again, for example after the blockchain is downloaded again, keeps
+// its label.
+func (w *Wallet) updateHistory(rewoundBlocks []consensus.Block, appliedBlocks []consensus.Block) (err error) {
+	changed := false
+	if len(rewoundBlocks) != 0 {
+		rewound := make(map[consensus.BlockID]struct{})
+		for _, b := range rewoundBlocks {
+			rewound[b.ID()] = struct{}{}
+		}
+		var kept []components.WalletTransaction
+		for _, entry := range w.history {
+			if _, exists := rewound[entry.BlockID]; !exists {
+				kept = append(kept, entry)
+			}
+		}
+		changed = len(kept) != len(w.history)
+		w.history = kept
+		w.indexHistory()
+	}
+
+	// outputs spent by the applied blocks are known to the wallet, unless
+	// they were sent before it tracked their address
+	addresses := w.addressSet()
+	outputValue := func(address consensus.CoinAddress, id consensus.OutputID) (consensus.Currency, bool) {
+		if so, exists := addresses[address].spendableOutputs[id]; exists {
+			return so.output.Value, true
+		}
+		return 0, false
+	}
+	for _, b := range appliedBlocks {
+		height, err := w.state.HeightOfBlock(b.ID())
+		if err != nil {
+			return err
+		}
+		for _, entry := range blockHistory(b, height, addresses, outputValue) {
+			if i, exists := w.historyIndex[entry.ID]; exists {
+				entry.Label = w.history[i].Label
+				w.history[i] = entry
+			} else {
+				w.historyIndex[entry.ID] = len(w.history)
+				w.history = append(w.history, entry)
+			}
+			changed = true
+		}
+	}
+
+	if changed {
+		return w.save()
+	}
+	return nil
+}
+
+// indexHistory rebuilds the map from transaction ids to history entries.
+func (w *Wallet) indexHistory() {
+	w.historyIndex = make(map[consensus.TransactionID]int)
+	for i, entry := range w.history {
+		w.historyIndex[entry.ID] = i
+	}
+}
+
+// History implements the core.Wallet interface.
+func (w *Wallet) History(address *consensus.CoinAddress, offset, limit int) (txns []components.WalletTransaction, total int, err error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if offset < 0 || limit < 0 {
+		err = errors.New("offset and limit must not be negative")
+		return
+	}
+	for i := len(w.history) - 1; i >= 0; i-- {
+		entry := w.history[i]
+		if address != nil {
+			found := false
+			for _, a := range entry.Addresses {
+				found = found || a == *address
+			}
+			if !found {
+				continue
+			}
+		}
+		if total >= offset && len(txns) < limit {
+			txns = append(txns, entry)
+		}
+		total++
+	}
+	return
+}
+
+// LabelTransaction implements the core.Wallet interface.
+func (w *Wallet) LabelTransaction(id consensus.TransactionID, label string) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	i, exists := w.historyIndex[id]
+	if !exists {
+		return ErrUnknownTransaction
+	}
+	w.history[i].Label = label
+	return w.save()
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+)
+
+const (
+	// keyPoolSize is the number of unused keys that are generated before the
+	// wallet is locked, so that a locked wallet can still hand out addresses.
+	keyPoolSize = 25
+)
+
+var (
+	ErrLocked        = errors.New("wallet is locked")
+	ErrBadPassword   = errors.New("incorrect wallet password")
+	ErrEmptyPassword = errors.New("wallet password cannot be empty")
+	ErrNotEncrypted  = errors.New("wallet is not encrypted; set a password by unlocking it first")
+)
+
+// locked returns whether the secret keys of the wallet are unavailable. The
+// seed is checked rather than secretKeys, since a wallet that has no keys yet
+// has no secret keys even while it is unlocked.
+func (w *Wallet) locked() bool {
+	return w.encrypted && w.seed == nil
+}
+
+// Unlock implements the core.Wallet interface. It decrypts the secret keys of
+// the wallet, allowing coins to be spent. If the wallet is not yet encrypted,
+// the password is used to encrypt it.
+func (w *Wallet) Unlock(password string) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if password == "" {
+		return ErrEmptyPassword
+	}
+
+	// Encrypt a plaintext wallet, replacing the wallet file.
+	if !w.encrypted {
+		w.kdf, err = crypto.NewKDFParams()
+		if err != nil {
+			return
+		}
+		w.fileKey = crypto.DeriveKey([]byte(password), w.kdf)
+		w.encrypted = true
+		return w.save()
+	}
+
+	key := crypto.DeriveKey([]byte(password), w.kdf)
+	if !w.locked() {
+		if key != w.fileKey {
+			return ErrBadPassword
+		}
+		return nil
+	}
+	var secretKeys []crypto.SecretKey
+	if err = open(key, w.sealedKeys, &secretKeys); err != nil {
+		return
+	}
+	if len(secretKeys) != len(w.publicKeys) {
+		return errors.New("wallet file is corrupted")
+	}
+
+	// Wallets that were encrypted before seeds were introduced are given a
+	// new seed.
+	var seed crypto.Seed
+	if w.sealedSeed == nil {
+		if seed, err = crypto.GenerateSeed(); err != nil {
+			return
+		}
+	} else if err = open(key, w.sealedSeed, &seed); err != nil {
+		return
+	}
+	w.secretKeys = secretKeys
+	w.seed = &seed
+	w.fileKey = key
+	if w.sealedSeed == nil {
+		return w.save()
+	}
+	return nil
+}
+
+// Lock implements the core.Wallet interface. It erases the secret keys and
+// seed of the wallet from memory, after which coins cannot be spent until the wallet is
+// unlocked again. New addresses are taken from the key pool.
+func (w *Wallet) Lock() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.encrypted {
+		return ErrNotEncrypted
+	} else if w.locked() {
+		return nil
+	}
+
+	// Fill the key pool, and save the new keys.
+	for len(w.publicKeys)-w.nextKey < keyPoolSize {
+		w.deriveKey()
+	}
+	if err = w.save(); err != nil {
+		return
+	}
+
+	// Erase the keys. Open transactions are discarded, because they cannot
+	// be signed.
+	for _, sk := range w.secretKeys {
+		if sk != nil {
+			for i := range sk {
+				sk[i] = 0
+			}
+		}
+	}
+	w.secretKeys = nil
+	*w.seed = crypto.Seed{}
+	w.seed = nil
+	w.fileKey = crypto.EncryptionKey{}
+	w.transactions = make(map[string]*openTransaction)
+	return nil
+}
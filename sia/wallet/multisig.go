@@ -0,0 +1,289 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+var (
+	ErrNoLocalKey       = errors.New("none of the public keys belong to the wallet")
+	ErrUnknownMultisig  = errors.New("address is not a multisig address of the wallet")
+	ErrMismatchedMerge  = errors.New("transactions being merged are not the same transaction")
+	errBadNumSignatures = errors.New("a multisig address needs at least one signature and no more signatures than keys")
+)
+
+// localKey returns the index of the wallet's key that matches pk.
+func (w *Wallet) localKey(pk crypto.PublicKey) (index int, exists bool) {
+	for i, key := range w.publicKeys[:w.nextKey] {
+		if *key == *pk {
+			return i, true
+		}
+	}
+	return
+}
+
+// addMultisig starts tracking a multisig address.
+func (w *Wallet) addMultisig(spendConditions consensus.SpendConditions) {
+	coinAddress := spendConditions.CoinAddress()
+	if _, exists := w.multisigAddresses[coinAddress]; exists {
+		return
+	}
+	w.multisigAddresses[coinAddress] = &spendableAddress{
+		spendableOutputs: make(map[consensus.OutputID]*spendableOutput),
+		spendConditions:  spendConditions,
+		keyIndex:         -1,
+	}
+}
+
+// multisigConditions returns the spend conditions of every multisig address.
+func (w *Wallet) multisigConditions() (conditions []consensus.SpendConditions) {
+	for _, sa := range w.multisigAddresses {
+		conditions = append(conditions, sa.spendConditions)
+	}
+	sort.Slice(conditions, func(i, j int) bool {
+		ci, cj := conditions[i].CoinAddress(), conditions[j].CoinAddress()
+		return bytes.Compare(ci[:], cj[:]) < 0
+	})
+	return
+}
+
+// MultisigKey implements the core.Wallet interface. It returns a new public
+// key of the wallet, to be shared with cosigners. The key also has a regular
+// address, which is how the key is stored.
+func (w *Wallet) MultisigKey() (pk crypto.PublicKey, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, spendConditions, err := w.coinAddress()
+	if err != nil {
+		return
+	}
+	pk = spendConditions.PublicKeys[0]
+	return
+}
+
+// CreateMultisigAddress implements the core.Wallet interface. It creates an
+// address that requires numSignatures of publicKeys to spend, at least one of
+// which must belong to the wallet. Every cosigner creates the address from the
+// same set of keys. The keys are sorted, so that every cosigner derives the
+// same spend conditions regardless of the order in which the keys were
+// exchanged.
+func (w *Wallet) CreateMultisigAddress(numSignatures uint64, publicKeys []crypto.PublicKey) (coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if numSignatures == 0 || numSignatures > uint64(len(publicKeys)) {
+		err = errBadNumSignatures
+		return
+	}
+	keys := make([]crypto.PublicKey, len(publicKeys))
+	copy(keys, publicKeys)
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	local := false
+	for i, pk := range keys {
+		if i > 0 && *pk == *keys[i-1] {
+			err = errors.New("multisig address contains the same public key twice")
+			return
+		}
+		_, exists := w.localKey(pk)
+		local = local || exists
+	}
+	if !local {
+		err = ErrNoLocalKey
+		return
+	}
+
+	spendConditions = consensus.SpendConditions{
+		NumSignatures: numSignatures,
+		PublicKeys:    keys,
+	}
+	coinAddress = spendConditions.CoinAddress()
+	w.addMultisig(spendConditions)
+	err = w.save()
+	return
+}
+
+// MultisigAddresses implements the core.Wallet interface.
+func (w *Wallet) MultisigAddresses() (addresses []components.MultisigAddressInfo, err error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, spendConditions := range w.multisigConditions() {
+		info := components.MultisigAddressInfo{
+			Address:       spendConditions.CoinAddress(),
+			NumSignatures: spendConditions.NumSignatures,
+			NumKeys:       len(spendConditions.PublicKeys),
+		}
+		for _, so := range w.unspentOutputs(w.multisigAddresses[info.Address]) {
+			info.Balance += so.output.Value
+		}
+		addresses = append(addresses, info)
+	}
+	return
+}
+
+// MultisigSend implements the core.Wallet interface. It creates a transaction
+// that sends amount from a multisig address to dest, paying fee to the
+// miners, and signs it with the wallet's keys. Change is returned to the
+// multisig address. The spent outputs are not marked as spent until the
+// transaction is submitted; see MarkSpent.
+func (w *Wallet) MultisigSend(address consensus.CoinAddress, amount consensus.Currency, dest consensus.CoinAddress, fee consensus.Currency) (t consensus.Transaction, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+	sa, exists := w.multisigAddresses[address]
+	if !exists {
+		err = ErrUnknownMultisig
+		return
+	}
+
+	// Select the outputs of the address, largest first.
+	outputs := w.unspentOutputs(sa)
+	sortOutputs(outputs)
+	selected, total, err := takeUntil(outputs, amount+fee)
+	if err != nil {
+		return
+	}
+
+	t.MinerFees = []consensus.Currency{fee}
+	t.Outputs = []consensus.Output{{Value: amount, SpendHash: dest}}
+	if total > amount+fee {
+		t.Outputs = append(t.Outputs, consensus.Output{Value: total - amount - fee, SpendHash: address})
+	}
+	for _, so := range selected {
+		t.Inputs = append(t.Inputs, consensus.Input{
+			OutputID:        so.id,
+			SpendConditions: sa.spendConditions,
+		})
+	}
+	return w.signMultisig(t)
+}
+
+// signMultisig adds the wallet's signatures to the inputs of t that spend
+// from the wallet's multisig addresses. Inputs that already have enough
+// signatures are not signed. Each signature covers the whole transaction,
+// excluding the other signatures, so cosigners can sign independently and
+// their signatures can be merged in any order.
+func (w *Wallet) signMultisig(t consensus.Transaction) (signed consensus.Transaction, err error) {
+	signed = t
+	signed.Signatures = append([]consensus.TransactionSignature(nil), t.Signatures...)
+	for _, input := range t.Inputs {
+		if _, exists := w.multisigAddresses[input.SpendConditions.CoinAddress()]; !exists {
+			continue
+		}
+
+		// Find the keys that have already signed the input.
+		used := make(map[uint64]struct{})
+		for _, sig := range signed.Signatures {
+			if sig.InputID == input.OutputID {
+				used[sig.PublicKeyIndex] = struct{}{}
+			}
+		}
+
+		for i, pk := range input.SpendConditions.PublicKeys {
+			if uint64(len(used)) >= input.SpendConditions.NumSignatures {
+				break
+			}
+			keyIndex, local := w.localKey(pk)
+			if _, signedBy := used[uint64(i)]; !local || signedBy {
+				continue
+			}
+			signed.Signatures = append(signed.Signatures, consensus.TransactionSignature{
+				InputID:        input.OutputID,
+				CoveredFields:  consensus.CoveredFields{WholeTransaction: true},
+				PublicKeyIndex: uint64(i),
+			})
+			sigIndex := len(signed.Signatures) - 1
+			sigHash := signed.SigHash(sigIndex)
+			signed.Signatures[sigIndex].Signature, err = crypto.SignBytes(sigHash[:], w.secretKeys[keyIndex])
+			if err != nil {
+				return
+			}
+			used[uint64(i)] = struct{}{}
+		}
+	}
+	return
+}
+
+// SignMultisig implements the core.Wallet interface. It adds the wallet's
+// signatures to a multisig transaction created by a cosigner.
+func (w *Wallet) SignMultisig(t consensus.Transaction) (signed consensus.Transaction, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+	return w.signMultisig(t)
+}
+
+// MergeMultisig implements the core.Wallet interface. It combines the
+// signatures of copies of the same transaction that were signed by different
+// cosigners. Signatures that are invalid, or beyond the number an input
+// requires, are dropped.
+func (w *Wallet) MergeMultisig(ts []consensus.Transaction) (merged consensus.Transaction, err error) {
+	if len(ts) == 0 {
+		err = errors.New("no transactions to merge")
+		return
+	}
+	unsigned := func(t consensus.Transaction) []byte {
+		t.Signatures = nil
+		return encoding.Marshal(t)
+	}
+	body := unsigned(ts[0])
+	for _, t := range ts[1:] {
+		if !bytes.Equal(unsigned(t), body) {
+			err = ErrMismatchedMerge
+			return
+		}
+	}
+
+	merged = ts[0]
+	merged.Signatures = nil
+	required := make(map[consensus.OutputID]uint64)
+	conditions := make(map[consensus.OutputID]consensus.SpendConditions)
+	for _, input := range merged.Inputs {
+		required[input.OutputID] = input.SpendConditions.NumSignatures
+		conditions[input.OutputID] = input.SpendConditions
+	}
+	type sigKey struct {
+		id    consensus.OutputID
+		index uint64
+	}
+	seen := make(map[sigKey]struct{})
+	for _, t := range ts {
+		for _, sig := range t.Signatures {
+			key := sigKey{sig.InputID, sig.PublicKeyIndex}
+			if _, exists := seen[key]; exists || required[sig.InputID] == 0 {
+				continue
+			}
+			publicKeys := conditions[sig.InputID].PublicKeys
+			if sig.PublicKeyIndex >= uint64(len(publicKeys)) || validCoveredFields(merged, sig.CoveredFields) != nil {
+				continue
+			}
+
+			// Check the signature in its place in the merged transaction,
+			// since it may cover other signatures.
+			merged.Signatures = append(merged.Signatures, sig)
+			sigHash := merged.SigHash(len(merged.Signatures) - 1)
+			if !crypto.VerifyBytes(sigHash[:], publicKeys[sig.PublicKeyIndex], sig.Signature) {
+				merged.Signatures = merged.Signatures[:len(merged.Signatures)-1]
+				continue
+			}
+			seen[key] = struct{}{}
+			required[sig.InputID]--
+		}
+	}
+	return
+}
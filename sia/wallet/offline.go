@@ -0,0 +1,289 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+// Offline signing splits a spend between two wallets. A wallet without the
+// secret keys, such as a locked copy of the wallet file on an online machine,
+// funds and exports the transaction. A wallet with the secret keys signs it,
+// and the signed transaction is broadcast from the online machine.
+
+var (
+	ErrFeeTooHigh      = errors.New("unsigned transaction pays more than the maximum fee")
+	ErrNoChangeAddress = errors.New("no unused watch-only address with known spend conditions to send change to")
+	ErrPartialCover    = errors.New("unsigned transaction must be signed in whole")
+	ErrUnknownInput    = errors.New("input does not spend from an address of the wallet")
+)
+
+// FundUnsignedTransaction implements the core.Wallet interface. Outputs are
+// selected largest first. Besides the wallet's own outputs, the outputs of
+// watch-only addresses whose spend conditions are known can be spent, since
+// the transaction will be signed by a wallet that holds their keys.
+//
+// Change is sent to a new address rather than back to an address that is
+// spent from, so that addresses are not reused. See unsignedChangeAddress.
+func (w *Wallet) FundUnsignedTransaction(id string, amount consensus.Currency) (change consensus.Currency, changeIndex uint64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ot, exists := w.transactions[id]
+	if !exists {
+		err = errors.New("no transaction of given id found")
+		return
+	}
+	if amount == 0 {
+		err = errors.New("cannot fund 0 coins")
+		return
+	}
+	outputs := append(w.availableOutputs(), w.watchedOutputs()...)
+	sortOutputs(outputs)
+	selected, total, err := takeUntil(outputs, amount)
+	if err != nil {
+		return
+	}
+	var changeAddress consensus.CoinAddress
+	if total > amount {
+		changeAddress, err = w.unsignedChangeAddress(selected)
+		if err != nil {
+			return
+		}
+	}
+	change, changeIndex = w.fundTransaction(ot, amount, selected, total, changeAddress)
+	return
+}
+
+// unsignedChangeAddress returns the address that the change of an unsigned
+// transaction spending selected is sent to. If any of the wallet's own outputs
+// are spent, the change goes to an address from the key pool. The wallet that
+// signs the transaction is the one this wallet was copied from, so it holds
+// the same key pool and picks up the address when it signs. If only
+// watch-only outputs are spent, the change goes to a watch-only address with
+// known spend conditions that has never been used, as the signing wallet
+// knows nothing of this wallet's keys.
+func (w *Wallet) unsignedChangeAddress(selected []*spendableOutput) (changeAddress consensus.CoinAddress, err error) {
+	for _, so := range selected {
+		if _, exists := w.spendableAddresses[so.output.SpendHash]; exists {
+			changeAddress, _, err = w.coinAddress()
+			return
+		}
+	}
+	used := w.usedAddresses()
+	for _, wa := range w.watchedAddresses() {
+		if _, exists := used[wa.Address]; !exists && hasSpendConditions(wa.Address, w.watchAddresses[wa.Address]) {
+			changeAddress = wa.Address
+			return
+		}
+	}
+	err = ErrNoChangeAddress
+	return
+}
+
+// ExportTransaction implements the core.Wallet interface.
+func (w *Wallet) ExportTransaction(id string, wholeTransaction bool) (ut components.UnsignedTransaction, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ot, exists := w.transactions[id]
+	if !exists {
+		err = errors.New("no transaction found for given id")
+		return
+	}
+	ut.Transaction = *ot.transaction
+	ut.CoveredFields = coveredFields(ut.Transaction, wholeTransaction)
+
+	// The signing wallet may not know the outputs that are spent, so their
+	// values are exported with the transaction.
+	var spent []*spendableOutput
+	for _, input := range ut.Transaction.Inputs {
+		var so *spendableOutput
+		sa, exists := w.trackedAddress(input.SpendConditions.CoinAddress())
+		if exists {
+			so, exists = sa.spendableOutputs[input.OutputID]
+		}
+		if !exists {
+			err = errors.New("transaction spends an output that the wallet does not know")
+			return
+		}
+		ut.Values = append(ut.Values, so.output.Value)
+		spent = append(spent, so)
+	}
+	for _, inputIndex := range ot.inputs {
+		ut.Inputs = append(ut.Inputs, uint64(inputIndex))
+		spent[inputIndex].spentCounter = w.spentCounter
+	}
+	delete(w.transactions, id)
+	return
+}
+
+// validCoveredFields returns an error if coveredFields refers to a field that t
+// does not have.
+func validCoveredFields(t consensus.Transaction, coveredFields consensus.CoveredFields) error {
+	for _, field := range []struct {
+		indices []uint64
+		length  int
+	}{
+		{coveredFields.MinerFees, len(t.MinerFees)},
+		{coveredFields.Inputs, len(t.Inputs)},
+		{coveredFields.Outputs, len(t.Outputs)},
+		{coveredFields.Contracts, len(t.FileContracts)},
+		{coveredFields.StorageProofs, len(t.StorageProofs)},
+		{coveredFields.ArbitraryData, len(t.ArbitraryData)},
+		{coveredFields.Signatures, len(t.Signatures)},
+	} {
+		for _, i := range field.indices {
+			if i >= uint64(field.length) {
+				return errors.New("covered fields refer to a field that the transaction does not have")
+			}
+		}
+	}
+	return nil
+}
+
+// adoptKeyPool adds the addresses of the key pool that t spends from or pays
+// to, along with any addresses of the pool before them. A locked copy of the
+// wallet hands out addresses from the same key pool, such as the change
+// addresses of the transactions it exports, and the wallet needs to track
+// them to spend from them later.
+func (w *Wallet) adoptKeyPool(t consensus.Transaction) error {
+	pool := make(map[consensus.CoinAddress]int)
+	for i := w.nextKey; i < len(w.publicKeys); i++ {
+		spendConditions := consensus.SpendConditions{
+			NumSignatures: 1,
+			PublicKeys:    []crypto.PublicKey{w.publicKeys[i]},
+		}
+		pool[spendConditions.CoinAddress()] = i
+	}
+	addresses := make([]consensus.CoinAddress, 0, len(t.Inputs)+len(t.Outputs))
+	for _, input := range t.Inputs {
+		addresses = append(addresses, input.SpendConditions.CoinAddress())
+	}
+	for _, output := range t.Outputs {
+		addresses = append(addresses, output.SpendHash)
+	}
+	last := -1
+	for _, address := range addresses {
+		if i, exists := pool[address]; exists && i > last {
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil
+	}
+	for w.nextKey <= last {
+		w.addAddress(consensus.SpendConditions{
+			NumSignatures: 1,
+			PublicKeys:    []crypto.PublicKey{w.publicKeys[w.nextKey]},
+		}, w.nextKey)
+		w.nextKey++
+	}
+	return w.save()
+}
+
+// checkUnsignedTransaction checks that the values of the spent outputs given
+// with ut add up to what the transaction pays out, and match the outputs that
+// the wallet knows of, and that the miner fees do not exceed maxFee. sent is
+// the value paid to addresses other than the wallet's, which is the amount
+// spent once the change is taken off.
+func (w *Wallet) checkUnsignedTransaction(ut components.UnsignedTransaction, maxFee consensus.Currency) (sent consensus.Currency, err error) {
+	t := ut.Transaction
+	if len(ut.Values) != len(t.Inputs) {
+		err = errors.New("unsigned transaction does not give the value of every input")
+		return
+	}
+	var inputSum, outputSum, fee consensus.Currency
+	for i, input := range t.Inputs {
+		if sa, exists := w.trackedAddress(input.SpendConditions.CoinAddress()); exists {
+			if so, exists := sa.spendableOutputs[input.OutputID]; exists && so.output.Value != ut.Values[i] {
+				err = errors.New("unsigned transaction gives the wrong value for an input")
+				return
+			}
+		}
+		inputSum += ut.Values[i]
+	}
+	for _, minerFee := range t.MinerFees {
+		fee += minerFee
+	}
+	for _, output := range t.Outputs {
+		if _, exists := w.spendableAddresses[output.SpendHash]; !exists {
+			sent += output.Value
+		}
+		outputSum += output.Value
+	}
+	for _, contract := range t.FileContracts {
+		sent += contract.ContractFund
+		outputSum += contract.ContractFund
+	}
+	if inputSum != outputSum+fee {
+		err = errors.New("values of the inputs do not add up to the outputs and fees of the transaction")
+		return
+	}
+	if fee > maxFee {
+		err = ErrFeeTooHigh
+		return
+	}
+	return
+}
+
+// SignUnsignedTransaction implements the core.Wallet interface. Only whole
+// transactions are signed, so that the outputs and fees that were checked
+// cannot be changed afterwards. The outputs that are spent are marked as
+// spent if the wallet knows of them.
+func (w *Wallet) SignUnsignedTransaction(ut components.UnsignedTransaction, maxFee consensus.Currency) (t consensus.Transaction, sent consensus.Currency, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+	t = ut.Transaction
+	if err = validCoveredFields(t, ut.CoveredFields); err != nil {
+		return
+	}
+	if !ut.CoveredFields.WholeTransaction {
+		err = ErrPartialCover
+		return
+	}
+	if err = w.adoptKeyPool(t); err != nil {
+		return
+	}
+	if sent, err = w.checkUnsignedTransaction(ut, maxFee); err != nil {
+		return
+	}
+
+	// Check every input before signing any of them.
+	signed := make(map[uint64]struct{})
+	for _, inputIndex := range ut.Inputs {
+		if inputIndex >= uint64(len(t.Inputs)) {
+			err = errors.New("unsigned transaction refers to an input that does not exist")
+			return
+		}
+		if _, exists := signed[inputIndex]; exists {
+			err = errors.New("unsigned transaction lists an input twice")
+			return
+		}
+		signed[inputIndex] = struct{}{}
+		if _, exists := w.spendableAddresses[t.Inputs[inputIndex].SpendConditions.CoinAddress()]; !exists {
+			err = ErrUnknownInput
+			return
+		}
+	}
+
+	for _, inputIndex := range ut.Inputs {
+		input := t.Inputs[inputIndex]
+		sa := w.spendableAddresses[input.SpendConditions.CoinAddress()]
+		err = signInput(&t, int(inputIndex), ut.CoveredFields, w.secretKeys[sa.keyIndex])
+		if err != nil {
+			return
+		}
+		if so, exists := sa.spendableOutputs[input.OutputID]; exists {
+			so.spentCounter = w.spentCounter
+		}
+	}
+	return
+}
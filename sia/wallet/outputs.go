@@ -1,11 +1,7 @@
 package wallet
 
 import (
-	"errors"
-
 	"github.com/NebulousLabs/Sia/consensus"
-	"github.com/NebulousLabs/Sia/crypto"
-	"github.com/NebulousLabs/Sia/sia/components"
 )
 
 // openTransaction is a type that the wallet uses to track a transaction as it
@@ -29,47 +25,11 @@ type spendableOutput struct {
 }
 
 // openOutput contains an output and the conditions needed to spend the output,
-// including secret keys.
+// including the index of the secret key.
 type spendableAddress struct {
 	spendableOutputs map[consensus.OutputID]*spendableOutput
 	spendConditions  consensus.SpendConditions
-	secretKey        crypto.SecretKey
-}
-
-// findOutputs returns a set of spendable outputs that add up to at least
-// `amount` of coins, returning an error if it cannot. It also returns the
-// `total`, which is the sum of all the outputs. It does not adjust the outputs
-// in any way.
-func (w *Wallet) findOutputs(amount consensus.Currency) (spendableOutputs []*spendableOutput, total consensus.Currency, err error) {
-	if amount == consensus.Currency(0) {
-		err = errors.New("cannot fund 0 coins") // should this be an error or nil?
-		return
-	}
-
-	// Iterate through all outputs until enough coins have been assembled.
-	for _, spendableAddress := range w.spendableAddresses {
-		for _, spendableOutput := range spendableAddress.spendableOutputs {
-			if !spendableOutput.spendable || spendableOutput.spentCounter == w.spentCounter {
-				continue
-			}
-			total += spendableOutput.output.Value
-			spendableOutputs = append(spendableOutputs, spendableOutput)
-
-			if total >= amount {
-				return
-			}
-		}
-	}
-
-	// This code will only be reached if total < amount, meaning insufficient
-	// funds.
-	//
-	// TODO: Figure out some way to return a named error that also contains
-	// custom information.
-	// err = fmt.Errorf("insufficient funds, requested %v but only have %v", amount, total)
-	err = components.LowBalanceErr
-
-	return
+	keyIndex         int
 }
 
 // Balance implements the core.Wallet interface.
@@ -92,14 +52,15 @@ func (w *Wallet) Balance(full bool) (total consensus.Currency) {
 	return
 }
 
-// Update implements the core.Wallet interface.
-func (w *Wallet) Update(diffs []consensus.OutputDiff) error {
+// Update implements the core.Wallet interface. The outputs are updated before
+// the transaction history, so that the values of spent outputs are known.
+func (w *Wallet) Update(initialStateHeight consensus.BlockHeight, rewoundBlocks []consensus.Block, appliedBlocks []consensus.Block, diffs []consensus.OutputDiff) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	for _, diff := range diffs {
 		if diff.New {
-			if spendableAddress, exists := w.spendableAddresses[diff.Output.SpendHash]; exists {
+			if spendableAddress, exists := w.trackedAddress(diff.Output.SpendHash); exists {
 				spendableAddress.spendableOutputs[diff.ID] = &spendableOutput{
 					spendable: true,
 					id:        diff.ID,
@@ -107,11 +68,12 @@ func (w *Wallet) Update(diffs []consensus.OutputDiff) error {
 				}
 			}
 		} else {
-			if spendableAddress, exists := w.spendableAddresses[diff.Output.SpendHash]; exists {
+			if spendableAddress, exists := w.trackedAddress(diff.Output.SpendHash); exists {
+				// Outputs sent to an address before the wallet tracked
+				// it, such as a restored, multisig or watch-only
+				// address, are unknown until a rescan finds them.
 				if spendableOutput, exists := spendableAddress.spendableOutputs[diff.ID]; exists {
 					spendableOutput.spendable = false
-				} else {
-					panic("output should exist!")
 				}
 			}
 		}
@@ -148,5 +110,5 @@ func (w *Wallet) Update(diffs []consensus.OutputDiff) error {
 	}
 	w.prevHeight = height
 
-	return nil
+	return w.updateHistory(rewoundBlocks, appliedBlocks)
 }
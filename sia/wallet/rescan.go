@@ -0,0 +1,167 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+var (
+	ErrRescanning = errors.New("wallet is already rescanning the blockchain")
+)
+
+// contractOutputIDs returns the ids of every output that a file contract can
+// create: one valid and one missed proof output for each challenge window,
+// and the outputs created when the contract terminates.
+func contractOutputIDs(fc consensus.FileContract, fcID consensus.ContractID) (ids []consensus.OutputID) {
+	if fc.ChallengeWindow == 0 {
+		return
+	}
+	for height := fc.Start; height < fc.End; height += fc.ChallengeWindow {
+		for _, proofValid := range []bool{true, false} {
+			id, err := fc.StorageProofOutputID(fcID, height, proofValid)
+			if err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return append(ids,
+		consensus.ContractTerminationOutputID(fcID, true),
+		consensus.ContractTerminationOutputID(fcID, false),
+	)
+}
+
+// Rescan implements the core.Wallet interface. It walks the blocks of the
+// current path from fromHeight onwards, finding the outputs that were sent to
+// the wallet's addresses, and then rebuilds the wallet's outputs from the
+// ones that are still unspent. Outputs created by file contracts are only
+// found if the contract was formed at or after fromHeight. The transaction
+// history of the scanned blocks is rebuilt as well, keeping existing labels.
+//
+// The blocks are read without holding the wallet's lock, and the progress of
+// the scan is reported by WalletInfo.
+func (w *Wallet) Rescan(fromHeight consensus.BlockHeight) (err error) {
+	// Take a snapshot of the wallet's addresses.
+	w.mu.Lock()
+	if w.rescanning {
+		w.mu.Unlock()
+		return ErrRescanning
+	}
+	addresses := w.addressSet()
+	targetHeight := w.state.Height()
+	w.rescanning = true
+	w.rescanHeight = fromHeight
+	w.rescanTarget = targetHeight
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.rescanning = false
+		w.mu.Unlock()
+	}()
+
+	// Collect the ids of every output that was sent to one of the addresses,
+	// and the history entries of each block. The values of the outputs are
+	// recorded so that the history can count the outputs that are spent
+	// later in the scan.
+	candidates := make(map[consensus.OutputID]struct{})
+	values := make(map[consensus.OutputID]consensus.Currency)
+	outputValue := func(_ consensus.CoinAddress, id consensus.OutputID) (value consensus.Currency, exists bool) {
+		value, exists = values[id]
+		return
+	}
+	var history []components.WalletTransaction
+	for height := fromHeight; height <= targetHeight; height++ {
+		var b consensus.Block
+		b, err = w.state.BlockAtHeight(height)
+		if err != nil {
+			return
+		}
+		if _, exists := addresses[b.MinerAddress]; exists {
+			candidates[b.SubsidyID()] = struct{}{}
+			subsidy := consensus.CalculateCoinbase(height)
+			for _, txn := range b.Transactions {
+				for _, fee := range txn.MinerFees {
+					subsidy += fee
+				}
+			}
+			values[b.SubsidyID()] = subsidy
+		}
+		for _, txn := range b.Transactions {
+			for i, output := range txn.Outputs {
+				if _, exists := addresses[output.SpendHash]; exists {
+					candidates[txn.OutputID(i)] = struct{}{}
+					values[txn.OutputID(i)] = output.Value
+				}
+			}
+			for i, fc := range txn.FileContracts {
+				_, valid := addresses[fc.ValidProofAddress]
+				_, missed := addresses[fc.MissedProofAddress]
+				if valid || missed {
+					for _, id := range contractOutputIDs(fc, txn.FileContractID(i)) {
+						candidates[id] = struct{}{}
+					}
+				}
+			}
+		}
+		history = append(history, blockHistory(b, height, addresses, outputValue)...)
+
+		w.mu.Lock()
+		w.rescanHeight = height + 1
+		w.mu.Unlock()
+	}
+
+	// Rebuild the outputs. Known outputs that have been spent are marked
+	// unspendable, and unspent outputs that were found are added.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, sa := range addresses {
+		for id, so := range sa.spendableOutputs {
+			_, err := w.state.Output(id)
+			so.spendable = err == nil
+		}
+	}
+	for id := range candidates {
+		output, err := w.state.Output(id)
+		if err != nil {
+			continue
+		}
+		sa, exists := addresses[output.SpendHash]
+		if !exists {
+			continue
+		}
+		if _, exists := sa.spendableOutputs[id]; !exists {
+			sa.spendableOutputs[id] = &spendableOutput{
+				spendable: true,
+				id:        id,
+				output:    output,
+			}
+		}
+	}
+	w.mergeHistory(fromHeight, targetHeight, history)
+	return w.save()
+}
+
+// mergeHistory replaces the history entries confirmed between fromHeight and
+// targetHeight with the entries found by a rescan. Entries that are found
+// again keep their labels.
+func (w *Wallet) mergeHistory(fromHeight, targetHeight consensus.BlockHeight, rescanned []components.WalletTransaction) {
+	labels := make(map[consensus.TransactionID]string)
+	var before, after []components.WalletTransaction
+	for _, entry := range w.history {
+		switch {
+		case entry.ConfirmationHeight < fromHeight:
+			before = append(before, entry)
+		case entry.ConfirmationHeight > targetHeight:
+			// confirmed while the rescan was running
+			after = append(after, entry)
+		default:
+			labels[entry.ID] = entry.Label
+		}
+	}
+	for i := range rescanned {
+		rescanned[i].Label = labels[rescanned[i].ID]
+	}
+	w.history = append(append(before, rescanned...), after...)
+	w.indexHistory()
+}
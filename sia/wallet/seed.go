@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
+)
+
+const (
+	// restoreLookahead is the number of consecutive unused addresses that a
+	// restored wallet derives after the last address found to be used. The
+	// original wallet is assumed not to have handed out more than this many
+	// addresses without any of them receiving coins.
+	restoreLookahead = 50
+)
+
+// Seed implements the core.Wallet interface. It returns the mnemonic form of
+// the seed from which the wallet's keys are derived. The wallet must be
+// unlocked.
+func (w *Wallet) Seed() (mnemonic string, err error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+	mnemonic = w.seed.Mnemonic()
+	return
+}
+
+// Restore implements the core.Wallet interface. It replaces the seed of the
+// wallet with the seed encoded by mnemonic, adds the addresses derived from
+// it, and rescans the blockchain for their outputs. Addresses are derived in
+// batches, until restoreLookahead unused addresses follow the last used one.
+// Existing addresses are kept. The wallet must be unlocked.
+func (w *Wallet) Restore(mnemonic string) (err error) {
+	w.mu.Lock()
+	err = w.restore(mnemonic)
+	w.mu.Unlock()
+	for err == nil {
+		if err = w.Rescan(0); err != nil {
+			return
+		}
+		w.mu.Lock()
+		if w.locked() {
+			w.mu.Unlock()
+			return ErrLocked
+		}
+		gap := w.unusedSeedAddresses()
+		if gap >= restoreLookahead {
+			w.mu.Unlock()
+			return
+		}
+		err = w.deriveSeedAddresses(restoreLookahead - gap)
+		w.mu.Unlock()
+	}
+	return
+}
+
+// restore replaces the seed of the wallet and adds the first restoreLookahead
+// addresses derived from it.
+func (w *Wallet) restore(mnemonic string) (err error) {
+	if w.locked() {
+		return ErrLocked
+	}
+	seed, err := crypto.ParseMnemonic(mnemonic)
+	if err != nil {
+		return
+	}
+
+	// Keys in the key pool have never been handed out, so they can be
+	// discarded.
+	w.publicKeys = w.publicKeys[:w.nextKey]
+	w.secretKeys = w.secretKeys[:w.nextKey]
+
+	*w.seed = seed
+	w.seedIndex = 0
+	return w.deriveSeedAddresses(restoreLookahead)
+}
+
+// deriveSeedAddresses derives the next n addresses from the seed, and adds
+// them to the wallet. Addresses that the wallet already holds, such as those
+// added by restoring the same seed before, are skipped.
+func (w *Wallet) deriveSeedAddresses(n int) error {
+	for i := 0; i < n; i++ {
+		sk, pk := crypto.DeriveSignatureKeys(*w.seed, w.seedIndex)
+		w.seedIndex++
+		spendConditions := consensus.SpendConditions{
+			NumSignatures: 1,
+			PublicKeys:    []crypto.PublicKey{pk},
+		}
+		if _, exists := w.spendableAddresses[spendConditions.CoinAddress()]; exists {
+			continue
+		}
+		w.publicKeys = append(w.publicKeys, pk)
+		w.secretKeys = append(w.secretKeys, sk)
+		w.addAddress(spendConditions, w.nextKey)
+		w.nextKey++
+	}
+	return w.save()
+}
+
+// unusedSeedAddresses returns the number of addresses derived from the seed
+// after the last address that has received coins, according to the outputs
+// and history of the wallet.
+func (w *Wallet) unusedSeedAddresses() int {
+	used := w.usedAddresses()
+	for i := w.seedIndex; i > 0; i-- {
+		_, pk := crypto.DeriveSignatureKeys(*w.seed, i-1)
+		spendConditions := consensus.SpendConditions{
+			NumSignatures: 1,
+			PublicKeys:    []crypto.PublicKey{pk},
+		}
+		if _, exists := used[spendConditions.CoinAddress()]; exists {
+			return int(w.seedIndex - i)
+		}
+	}
+	return int(w.seedIndex)
+}
@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+const (
+	// branchAndBoundTries limits the number of subsets that the
+	// branch-and-bound strategy considers before falling back to
+	// largest-first.
+	branchAndBoundTries = 100000
+)
+
+var (
+	ErrUnknownStrategy      = errors.New("unknown coin selection strategy")
+	ErrNothingToConsolidate = errors.New("wallet has fewer than two outputs to consolidate")
+)
+
+// sortOutputs sorts outputs by value from largest to smallest. Outputs of equal
+// value are sorted by id, so that selection is reproducible.
+func sortOutputs(outputs []*spendableOutput) {
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].output.Value != outputs[j].output.Value {
+			return outputs[i].output.Value > outputs[j].output.Value
+		}
+		return bytes.Compare(outputs[i].id[:], outputs[j].id[:]) < 0
+	})
+}
+
+// unspentOutputs returns the outputs of an address that have not been spent,
+// either in the blockchain or by a transaction created since the last reset.
+func (w *Wallet) unspentOutputs(sa *spendableAddress) (outputs []*spendableOutput) {
+	for _, so := range sa.spendableOutputs {
+		if so.spendable && so.spentCounter != w.spentCounter {
+			outputs = append(outputs, so)
+		}
+	}
+	return
+}
+
+// availableOutputs returns the outputs that can be spent, sorted by value from
+// largest to smallest.
+func (w *Wallet) availableOutputs() (outputs []*spendableOutput) {
+	for _, sa := range w.spendableAddresses {
+		outputs = append(outputs, w.unspentOutputs(sa)...)
+	}
+	sortOutputs(outputs)
+	return
+}
+
+// takeUntil returns the outputs from the front of the list that are needed to
+// cover amount.
+func takeUntil(outputs []*spendableOutput, amount consensus.Currency) (selected []*spendableOutput, total consensus.Currency, err error) {
+	for _, so := range outputs {
+		if total >= amount {
+			return
+		}
+		total += so.output.Value
+		selected = append(selected, so)
+	}
+	if total < amount {
+		err = components.LowBalanceErr
+	}
+	return
+}
+
+// selectBranchAndBound searches for a set of outputs that adds up to exactly
+// amount, so that no change output is needed. The outputs must be sorted from
+// largest to smallest. ok is false if no such set is found.
+func selectBranchAndBound(outputs []*spendableOutput, amount consensus.Currency) (selected []*spendableOutput, ok bool) {
+	// remaining[i] is the total value of outputs[i:], which bounds the value
+	// that can still be added.
+	remaining := make([]consensus.Currency, len(outputs)+1)
+	for i := len(outputs) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + outputs[i].output.Value
+	}
+
+	tries := 0
+	var search func(i int, total consensus.Currency) bool
+	search = func(i int, total consensus.Currency) bool {
+		if total == amount {
+			return true
+		}
+		tries++
+		if i == len(outputs) || total+remaining[i] < amount || tries > branchAndBoundTries {
+			return false
+		}
+		// Try including the output, then excluding it.
+		if total+outputs[i].output.Value <= amount {
+			selected = append(selected, outputs[i])
+			if search(i+1, total+outputs[i].output.Value) {
+				return true
+			}
+			selected = selected[:len(selected)-1]
+		}
+		return search(i+1, total)
+	}
+	ok = search(0, 0)
+	return
+}
+
+// selectPrivate selects outputs so that as few addresses as possible are
+// linked by the transaction. If a single address can cover amount, the
+// address with the smallest sufficient balance is used. Otherwise, whole
+// addresses are spent, largest balance first, so that no outputs are left
+// behind at addresses that have been linked.
+func (w *Wallet) selectPrivate(outputs []*spendableOutput, amount consensus.Currency) (selected []*spendableOutput, total consensus.Currency, err error) {
+	type group struct {
+		address consensus.CoinAddress
+		outputs []*spendableOutput
+		total   consensus.Currency
+	}
+	groupIndex := make(map[consensus.CoinAddress]int)
+	var groups []group
+	for _, so := range outputs {
+		i, exists := groupIndex[so.output.SpendHash]
+		if !exists {
+			i = len(groups)
+			groupIndex[so.output.SpendHash] = i
+			groups = append(groups, group{address: so.output.SpendHash})
+		}
+		groups[i].outputs = append(groups[i].outputs, so)
+		groups[i].total += so.output.Value
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].total != groups[j].total {
+			return groups[i].total > groups[j].total
+		}
+		return bytes.Compare(groups[i].address[:], groups[j].address[:]) < 0
+	})
+
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i].total >= amount {
+			return takeUntil(groups[i].outputs, amount)
+		}
+	}
+	for _, g := range groups {
+		if total >= amount {
+			return
+		}
+		selected = append(selected, g.outputs...)
+		total += g.total
+	}
+	if total < amount {
+		err = components.LowBalanceErr
+	}
+	return
+}
+
+// findOutputs returns a set of spendable outputs that add up to at least
+// `amount` of coins, chosen according to strategy, returning an error if it
+// cannot. It also returns the `total`, which is the sum of all the outputs. It
+// does not adjust the outputs in any way.
+func (w *Wallet) findOutputs(amount consensus.Currency, strategy components.SelectionStrategy) (selected []*spendableOutput, total consensus.Currency, err error) {
+	if amount == consensus.Currency(0) {
+		err = errors.New("cannot fund 0 coins") // should this be an error or nil?
+		return
+	}
+
+	outputs := w.availableOutputs()
+	switch strategy {
+	case components.LargestFirst:
+		return takeUntil(outputs, amount)
+
+	case components.SmallestFirst:
+		for i, j := 0, len(outputs)-1; i < j; i, j = i+1, j-1 {
+			outputs[i], outputs[j] = outputs[j], outputs[i]
+		}
+		return takeUntil(outputs, amount)
+
+	case components.BranchAndBound:
+		if exact, ok := selectBranchAndBound(outputs, amount); ok {
+			return exact, amount, nil
+		}
+		return takeUntil(outputs, amount)
+
+	case components.PrivacyPreserving:
+		return w.selectPrivate(outputs, amount)
+
+	default:
+		err = ErrUnknownStrategy
+		return
+	}
+}
+
+// Consolidate implements the core.Wallet interface. It creates and signs a
+// transaction that sweeps up to maxInputs of the wallet's smallest outputs
+// with values below threshold into a single output at a new address, paying
+// fee to the miners.
+func (w *Wallet) Consolidate(threshold consensus.Currency, maxInputs int, fee consensus.Currency) (t consensus.Transaction, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+
+	// Take the smallest outputs below the threshold.
+	outputs := w.availableOutputs()
+	var selected []*spendableOutput
+	var total consensus.Currency
+	for i := len(outputs) - 1; i >= 0 && len(selected) < maxInputs; i-- {
+		if outputs[i].output.Value >= threshold {
+			break
+		}
+		selected = append(selected, outputs[i])
+		total += outputs[i].output.Value
+	}
+	if len(selected) < 2 {
+		err = ErrNothingToConsolidate
+		return
+	} else if total <= fee {
+		err = errors.New("consolidated outputs are worth less than the fee")
+		return
+	}
+
+	coinAddress, _, err := w.coinAddress()
+	if err != nil {
+		return
+	}
+	ot := new(openTransaction)
+	ot.transaction = &consensus.Transaction{
+		MinerFees: []consensus.Currency{fee},
+		Outputs:   []consensus.Output{{Value: total - fee, SpendHash: coinAddress}},
+	}
+	w.addInputs(ot, selected)
+	return w.signTransaction(ot, true)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/sia/components"
 )
 
 // Reset implements the core.Wallet interface.
@@ -31,27 +32,85 @@ func (w *Wallet) RegisterTransaction(t consensus.Transaction) (id string, err er
 	return
 }
 
-// FundTransaction implements the core.Wallet interface.
-func (w *Wallet) FundTransaction(id string, amount consensus.Currency) error {
+// DiscardTransaction implements the core.Wallet interface.
+func (w *Wallet) DiscardTransaction(id string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if _, exists := w.transactions[id]; !exists {
+		return errors.New("no transaction found for given id")
+	}
+	delete(w.transactions, id)
+	return nil
+}
+
+// FundTransaction implements the core.Wallet interface. Outputs are selected
+// largest first.
+func (w *Wallet) FundTransaction(id string, amount consensus.Currency) (change consensus.Currency, changeIndex uint64, err error) {
+	return w.FundTransactionWithStrategy(id, amount, components.LargestFirst)
+}
+
+// FundTransactionWithStrategy implements the core.Wallet interface.
+func (w *Wallet) FundTransactionWithStrategy(id string, amount consensus.Currency, strategy components.SelectionStrategy) (change consensus.Currency, changeIndex uint64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+
 	// Get the transaction.
 	ot, exists := w.transactions[id]
 	if !exists {
-		return errors.New("no transaction of given id found")
+		err = errors.New("no transaction of given id found")
+		return
 	}
-	t := ot.transaction
 
 	// Get the set of outputs.
-	spendableOutputs, total, err := w.findOutputs(amount)
+	spendableOutputs, total, err := w.findOutputs(amount, strategy)
 	if err != nil {
-		return err
+		return
 	}
 
-	// Create and add all of the inputs.
+	// Get the change address before adding any inputs, so that the
+	// transaction is left untouched if it cannot be created.
+	var changeAddress consensus.CoinAddress
+	if total > amount {
+		changeAddress, _, err = w.coinAddress()
+		if err != nil {
+			return
+		}
+	}
+	change, changeIndex = w.fundTransaction(ot, amount, spendableOutputs, total, changeAddress)
+	return
+}
+
+// fundTransaction adds the selected outputs, worth total, to an open
+// transaction as inputs, along with a change output paying changeAddress the
+// amount by which they exceed amount.
+func (w *Wallet) fundTransaction(ot *openTransaction, amount consensus.Currency, spendableOutputs []*spendableOutput, total consensus.Currency, changeAddress consensus.CoinAddress) (change consensus.Currency, changeIndex uint64) {
+	t := ot.transaction
+	change = total - amount
+	w.addInputs(ot, spendableOutputs)
+
+	// Return any surplus to the wallet, since it would otherwise be paid to
+	// the miners.
+	if change > 0 {
+		changeIndex = uint64(len(t.Outputs))
+		t.Outputs = append(t.Outputs, consensus.Output{
+			Value:     change,
+			SpendHash: changeAddress,
+		})
+	}
+	return
+}
+
+// addInputs adds an input to an open transaction for each output.
+func (w *Wallet) addInputs(ot *openTransaction, spendableOutputs []*spendableOutput) {
+	t := ot.transaction
 	for _, spendableOutput := range spendableOutputs {
-		spendableAddress := w.spendableAddresses[spendableOutput.output.SpendHash]
+		spendableAddress, _ := w.trackedAddress(spendableOutput.output.SpendHash)
 		newInput := consensus.Input{
 			OutputID:        spendableOutput.id,
 			SpendConditions: spendableAddress.spendConditions,
@@ -59,23 +118,6 @@ func (w *Wallet) FundTransaction(id string, amount consensus.Currency) error {
 		ot.inputs = append(ot.inputs, len(t.Inputs))
 		t.Inputs = append(t.Inputs, newInput)
 	}
-
-	// Add a refund output if needed.
-	if total-amount > 0 {
-		coinAddress, _, err := w.coinAddress()
-
-		if err != nil {
-			return err
-		}
-		t.Outputs = append(
-			t.Outputs,
-			consensus.Output{
-				Value:     total - amount,
-				SpendHash: coinAddress,
-			},
-		)
-	}
-	return nil
 }
 
 // AddMinerFee implements the core.Wallet interface.
@@ -182,66 +224,110 @@ func (w *Wallet) SignTransaction(id string, wholeTransaction bool) (transaction
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.locked() {
+		err = ErrLocked
+		return
+	}
+
 	// Fetch the transaction.
 	openTransaction, exists := w.transactions[id]
 	if !exists {
 		err = errors.New("no transaction found for given id")
 		return
 	}
-	transaction = *openTransaction.transaction
+	transaction, err = w.signTransaction(openTransaction, wholeTransaction)
+	if err != nil {
+		return
+	}
+
+	// Delete the open transaction.
+	delete(w.transactions, id)
 
-	// Get the coveredfields struct.
-	var coveredFields consensus.CoveredFields
+	return
+}
+
+// coveredFields returns the fields of a transaction that the wallet's
+// signatures cover: either the whole transaction, or every field that the
+// transaction currently has.
+func coveredFields(transaction consensus.Transaction, wholeTransaction bool) (coveredFields consensus.CoveredFields) {
 	if wholeTransaction {
-		coveredFields = consensus.CoveredFields{WholeTransaction: true}
-	} else {
-		for i := range transaction.MinerFees {
-			coveredFields.MinerFees = append(coveredFields.MinerFees, uint64(i))
-		}
-		for i := range transaction.Inputs {
-			coveredFields.Inputs = append(coveredFields.Inputs, uint64(i))
-		}
-		for i := range transaction.Outputs {
-			coveredFields.Outputs = append(coveredFields.Outputs, uint64(i))
-		}
-		for i := range transaction.FileContracts {
-			coveredFields.Contracts = append(coveredFields.Contracts, uint64(i))
-		}
-		for i := range transaction.StorageProofs {
-			coveredFields.StorageProofs = append(coveredFields.StorageProofs, uint64(i))
-		}
-		for i := range transaction.ArbitraryData {
-			coveredFields.ArbitraryData = append(coveredFields.ArbitraryData, uint64(i))
-		}
+		return consensus.CoveredFields{WholeTransaction: true}
+	}
+	for i := range transaction.MinerFees {
+		coveredFields.MinerFees = append(coveredFields.MinerFees, uint64(i))
+	}
+	for i := range transaction.Inputs {
+		coveredFields.Inputs = append(coveredFields.Inputs, uint64(i))
+	}
+	for i := range transaction.Outputs {
+		coveredFields.Outputs = append(coveredFields.Outputs, uint64(i))
+	}
+	for i := range transaction.FileContracts {
+		coveredFields.Contracts = append(coveredFields.Contracts, uint64(i))
+	}
+	for i := range transaction.StorageProofs {
+		coveredFields.StorageProofs = append(coveredFields.StorageProofs, uint64(i))
+	}
+	for i := range transaction.ArbitraryData {
+		coveredFields.ArbitraryData = append(coveredFields.ArbitraryData, uint64(i))
+	}
 
-		// TODO: Should we also sign all of the known signatures?
+	// TODO: Should we also sign all of the known signatures?
+	return
+}
+
+// signInput adds a signature for an input of a transaction, made with secKey
+// and covering coveredFields.
+func signInput(transaction *consensus.Transaction, inputIndex int, coveredFields consensus.CoveredFields, secKey crypto.SecretKey) (err error) {
+	sig := consensus.TransactionSignature{
+		InputID:        transaction.Inputs[inputIndex].OutputID,
+		CoveredFields:  coveredFields,
+		PublicKeyIndex: 0,
+	}
+	transaction.Signatures = append(transaction.Signatures, sig)
+
+	// Hash the transaction according to the covered fields and produce the
+	// cryptographic signature.
+	sigIndex := len(transaction.Signatures) - 1
+	sigHash := transaction.SigHash(sigIndex)
+	transaction.Signatures[sigIndex].Signature, err = crypto.SignBytes(sigHash[:], secKey)
+	return
+}
+
+// MarkSpent implements the core.Wallet interface. Transactions that are
+// signed by more than one party, such as multisig transactions, are only
+// marked once they are complete, since they may never be submitted.
+func (w *Wallet) MarkSpent(t consensus.Transaction) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, input := range t.Inputs {
+		if sa, exists := w.trackedAddress(input.SpendConditions.CoinAddress()); exists {
+			if so, exists := sa.spendableOutputs[input.OutputID]; exists {
+				so.spentCounter = w.spentCounter
+			}
+		}
 	}
+}
+
+// signTransaction signs the inputs that the wallet added to an open
+// transaction, marks them as spent, and returns the signed transaction.
+func (w *Wallet) signTransaction(openTransaction *openTransaction, wholeTransaction bool) (transaction consensus.Transaction, err error) {
+	transaction = *openTransaction.transaction
+	coveredFields := coveredFields(transaction, wholeTransaction)
 
 	// For each input in the transaction that we added, provide a signature.
 	for _, inputIndex := range openTransaction.inputs {
 		input := transaction.Inputs[inputIndex]
-		sig := consensus.TransactionSignature{
-			InputID:        input.OutputID,
-			CoveredFields:  coveredFields,
-			PublicKeyIndex: 0,
-		}
-		transaction.Signatures = append(transaction.Signatures, sig)
-
-		// Hash the transaction according to the covered fields and produce the
-		// cryptographic signature.
-		secKey := w.spendableAddresses[input.SpendConditions.CoinAddress()].secretKey
-		sigHash := transaction.SigHash(len(transaction.Signatures) - 1)
-		transaction.Signatures[len(transaction.Signatures)-1].Signature, err = crypto.SignBytes(sigHash[:], secKey)
+		spendableAddress := w.spendableAddresses[input.SpendConditions.CoinAddress()]
+		err = signInput(&transaction, inputIndex, coveredFields, w.secretKeys[spendableAddress.keyIndex])
 		if err != nil {
 			return
 		}
 
 		// Mark the input as spent. Maps :)
-		w.spendableAddresses[input.SpendConditions.CoinAddress()].spendableOutputs[input.OutputID].spentCounter = w.spentCounter
+		spendableAddress.spendableOutputs[input.OutputID].spentCounter = w.spentCounter
 	}
 
-	// Delete the open transaction.
-	delete(w.transactions, id)
-
 	return
 }
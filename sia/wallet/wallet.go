@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
@@ -32,6 +33,53 @@ type Wallet struct {
 	spendableAddresses           map[consensus.CoinAddress]*spendableAddress
 	timelockedSpendableAddresses map[consensus.BlockHeight][]*spendableAddress
 
+	// multisigAddresses holds the M-of-N addresses that the wallet has a key
+	// for. Their outputs are tracked, but are only spent by multisig
+	// transactions, and are not counted in the balance.
+	multisigAddresses map[consensus.CoinAddress]*spendableAddress
+
+	// watchAddresses holds the addresses that the wallet tracks without
+	// holding their secret keys. Their spend conditions are only known if
+	// they were imported along with the address. Their outputs are never
+	// spent by the wallet, and are not counted in the balance.
+	watchAddresses map[consensus.CoinAddress]*spendableAddress
+
+	// Keys are stored in the order they were created, and each address
+	// refers to its key by index. Keys from nextKey onwards have not been
+	// used yet, and form the key pool. secretKeys is nil while the wallet is
+	// locked, and may also be nil while it is unlocked if it has no keys.
+	publicKeys []crypto.PublicKey
+	secretKeys []crypto.SecretKey
+	nextKey    int
+
+	// New keys are derived from seed, and seedIndex is the index of the next
+	// key to derive. seed is nil exactly while the wallet is locked.
+	seed      *crypto.Seed
+	seedIndex uint64
+
+	// An encrypted wallet stores its secret keys and seed encrypted with
+	// fileKey, which is derived from the password using kdf. sealedKeys and
+	// sealedSeed hold the encrypted data, so that the wallet can be saved
+	// while locked.
+	encrypted  bool
+	kdf        crypto.KDFParams
+	fileKey    crypto.EncryptionKey
+	sealedKeys []byte
+	sealedSeed []byte
+
+	// history holds the confirmed transactions that involve the wallet, in
+	// the order they were confirmed, and historyIndex maps the id of each
+	// transaction to its position in history.
+	history      []components.WalletTransaction
+	historyIndex map[consensus.TransactionID]int
+
+	// While the wallet is rescanning the blockchain, rescanHeight is the
+	// height of the next block to scan, and rescanTarget is the height at
+	// which the scan will stop.
+	rescanning   bool
+	rescanHeight consensus.BlockHeight
+	rescanTarget consensus.BlockHeight
+
 	transactionCounter int
 	transactions       map[string]*openTransaction
 
@@ -39,7 +87,8 @@ type Wallet struct {
 }
 
 // New creates a new wallet, loading any known addresses from the input file
-// name and then using the file to save in the future.
+// name and then using the file to save in the future. An encrypted wallet
+// starts out locked. An unencrypted wallet without a seed is given a new one.
 func New(state *consensus.State, filename string) (w *Wallet, err error) {
 	w = &Wallet{
 		state: state,
@@ -49,6 +98,10 @@ func New(state *consensus.State, filename string) (w *Wallet, err error) {
 		spentCounter:                 1,
 		spendableAddresses:           make(map[consensus.CoinAddress]*spendableAddress),
 		timelockedSpendableAddresses: make(map[consensus.BlockHeight][]*spendableAddress),
+		multisigAddresses:            make(map[consensus.CoinAddress]*spendableAddress),
+		watchAddresses:               make(map[consensus.CoinAddress]*spendableAddress),
+
+		historyIndex: make(map[consensus.TransactionID]int),
 
 		transactions: make(map[string]*openTransaction),
 	}
@@ -57,6 +110,14 @@ func New(state *consensus.State, filename string) (w *Wallet, err error) {
 	if err != nil {
 		return
 	}
+	if !w.encrypted && w.seed == nil {
+		var seed crypto.Seed
+		seed, err = crypto.GenerateSeed()
+		if err != nil {
+			return
+		}
+		w.seed = &seed
+	}
 
 	return
 }
@@ -67,9 +128,22 @@ func (w *Wallet) WalletInfo() (status components.WalletInfo, err error) {
 	defer w.mu.RUnlock()
 
 	status = components.WalletInfo{
-		Balance:      w.Balance(false),
-		FullBalance:  w.Balance(true),
-		NumAddresses: len(w.spendableAddresses),
+		Balance:           w.Balance(false),
+		FullBalance:       w.Balance(true),
+		NumAddresses:      len(w.spendableAddresses),
+		Encrypted:         w.encrypted,
+		Locked:            w.locked(),
+		NumWatchAddresses: len(w.watchAddresses),
+	}
+	for _, sa := range w.watchAddresses {
+		for _, so := range w.unspentOutputs(sa) {
+			status.WatchBalance += so.output.Value
+		}
+	}
+	if w.rescanning {
+		status.Rescanning = true
+		status.RescanHeight = w.rescanHeight
+		status.RescanTarget = w.rescanTarget
 	}
 
 	return
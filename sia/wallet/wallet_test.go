@@ -0,0 +1,865 @@
+package wallet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+// A walletTester creates the wallets used by a test. The wallets share a
+// genesis state and a temporary directory, which is removed when the test
+// finishes.
+type walletTester struct {
+	t     *testing.T
+	dir   string
+	state *consensus.State
+}
+
+// newWalletTester returns a walletTester for t.
+func newWalletTester(t *testing.T) *walletTester {
+	state, _ := consensus.CreateGenesisState()
+	return &walletTester{
+		t:     t,
+		dir:   t.TempDir(),
+		state: state,
+	}
+}
+
+// filename returns the path of the named wallet file.
+func (wt *walletTester) filename(name string) string {
+	return filepath.Join(wt.dir, name)
+}
+
+// wallet loads the named wallet, creating it if it does not exist.
+func (wt *walletTester) wallet(name string) *Wallet {
+	wt.t.Helper()
+	w, err := New(wt.state, wt.filename(name))
+	if err != nil {
+		wt.t.Fatal(err)
+	}
+	return w
+}
+
+// mineBlock mines a block that pays its subsidy to address, and applies it to
+// the state and to each of the wallets.
+func (wt *walletTester) mineBlock(address consensus.CoinAddress, wallets ...*Wallet) {
+	wt.t.Helper()
+	b := consensus.Block{
+		ParentBlockID: wt.state.CurrentBlock().ID(),
+		Timestamp:     consensus.Timestamp(time.Now().Unix()),
+		MinerAddress:  address,
+	}
+	b.MerkleRoot = b.TransactionMerkleRoot()
+	for target := wt.state.CurrentTarget(); !b.CheckTarget(target); b.Nonce++ {
+	}
+	rewound, applied, diffs, err := wt.state.AcceptBlock(b)
+	if err != nil {
+		wt.t.Fatal(err)
+	}
+	for _, w := range wallets {
+		if err = w.Update(wt.state.Height(), rewound, applied, diffs); err != nil {
+			wt.t.Fatal(err)
+		}
+	}
+}
+
+// TestWalletLocking encrypts a wallet, and checks that it refuses to spend
+// while locked, survives a reload, and can only be unlocked with the correct
+// password.
+func TestWalletLocking(t *testing.T) {
+	wt := newWalletTester(t)
+
+	w := wt.wallet("test.wallet")
+	_, _, err := w.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Lock() != ErrNotEncrypted {
+		t.Fatal("expected ErrNotEncrypted when locking an unencrypted wallet")
+	}
+
+	// Encrypt and lock the wallet.
+	if err = w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving the wallet replaces a world-readable wallet file with one that
+	// only its owner can read, without leaving temporary files behind.
+	if err = os.Chmod(wt.filename("test.wallet"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Save(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(wt.filename("test.wallet"))
+	if err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Fatal("wallet file should have mode 0600, has", fi.Mode())
+	}
+	if files, _ := ioutil.ReadDir(wt.dir); len(files) != 1 {
+		t.Fatal("expected only the wallet file, found", len(files), "files")
+	}
+	if err = w.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := w.WalletInfo()
+	if !info.Encrypted || !info.Locked {
+		t.Fatal("wallet should be encrypted and locked:", info)
+	}
+
+	// A locked wallet can hand out addresses, but not spend.
+	if _, _, err = w.CoinAddress(); err != nil {
+		t.Fatal(err)
+	}
+	id, err := w.RegisterTransaction(consensus.Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = w.FundTransaction(id, 1); err != ErrLocked {
+		t.Fatal("expected ErrLocked when funding a transaction")
+	}
+	if _, err = w.SignTransaction(id, true); err != ErrLocked {
+		t.Fatal("expected ErrLocked when signing a transaction")
+	}
+
+	// Reload the wallet, which should be locked.
+	w = wt.wallet("test.wallet")
+	info, _ = w.WalletInfo()
+	if !info.Locked || info.NumAddresses != 2 {
+		t.Fatal("reloaded wallet has the wrong status:", info)
+	}
+	if w.Unlock("wrong") != ErrBadPassword {
+		t.Fatal("expected ErrBadPassword")
+	}
+	if err = w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	info, _ = w.WalletInfo()
+	if info.Locked {
+		t.Fatal("wallet is still locked")
+	}
+
+	// A wallet that is encrypted before it has any keys is unlocked, and can
+	// be locked, reloaded and unlocked again.
+	w = wt.wallet("empty.wallet")
+	if err = w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = w.WalletInfo(); info.Locked {
+		t.Fatal("newly encrypted wallet is locked")
+	}
+	address, _, err := w.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	w = wt.wallet("empty.wallet")
+	if err = w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := w.trackedAddress(address); !exists {
+		t.Error("address was lost after reloading the wallet")
+	}
+	if _, _, err = w.CoinAddress(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A wallet file with unusable key derivation parameters is rejected when
+	// it is loaded.
+	for _, kdf := range []crypto.KDFParams{
+		{Time: 1, Memory: 64, Threads: 0},
+		{Time: 1, Memory: crypto.MaxKDFMemory + 1, Threads: 1},
+		{Time: crypto.MaxKDFTime + 1, Memory: 64, Threads: 1},
+	} {
+		w.kdf = kdf
+		if err = w.save(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = New(wt.state, wt.filename("empty.wallet")); err != crypto.ErrInvalidKDFParams {
+			t.Error("expected ErrInvalidKDFParams, got", err)
+		}
+	}
+}
+
+// TestWalletSeed backs up the seed of a wallet, restores it into a new
+// wallet, and checks that both wallets derive the same addresses.
+func TestWalletSeed(t *testing.T) {
+	wt := newWalletTester(t)
+
+	w := wt.wallet("original.wallet")
+	mnemonic, err := w.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := w.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.mineBlock(first, w)
+	var fortieth consensus.CoinAddress
+	for i := 1; i < 40; i++ {
+		if fortieth, _, err = w.CoinAddress(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wt.mineBlock(fortieth, w)
+
+	// Restore the seed into a second wallet. The 40th address was used, so
+	// the wallet derives 50 addresses after it, 90 in total. Reload it to
+	// check that the seed was saved.
+	restored := wt.wallet("restored.wallet")
+	if restored.Restore("not a mnemonic") == nil {
+		t.Fatal("restored from an invalid mnemonic")
+	}
+	if err = restored.Restore(mnemonic); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Balance(true) != w.Balance(true) || restored.Balance(true) == 0 {
+		t.Errorf("restored wallet has a balance of %v, expected %v", restored.Balance(true), w.Balance(true))
+	}
+	restored = wt.wallet("restored.wallet")
+	if restoredMnemonic, _ := restored.Seed(); restoredMnemonic != mnemonic {
+		t.Fatal("restored wallet has a different seed")
+	}
+	if info, _ := restored.WalletInfo(); info.NumAddresses != 90 {
+		t.Fatal("restored wallet has the wrong number of addresses:", info.NumAddresses)
+	}
+
+	// Restoring the same seed again does not add duplicate keys.
+	if err = restored.Restore(mnemonic); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := restored.WalletInfo(); info.NumAddresses != 90 || len(restored.publicKeys) != 90 {
+		t.Fatal("restoring the seed twice added duplicate keys:", info.NumAddresses, len(restored.publicKeys))
+	}
+
+	// The rescan found the subsidies paid to the first and 40th addresses,
+	// and recorded them in the history, newest first.
+	history, total, err := restored.History(nil, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || !history[0].MinerPayout || history[0].Addresses[0] != fortieth || history[1].Addresses[0] != first {
+		t.Error("restored wallet has the wrong history:", history)
+	}
+
+	// The next address of the restored wallet is the 91st address of the
+	// original wallet, whose first 40 addresses were taken above.
+	var address consensus.CoinAddress
+	for i := 40; i < 91; i++ {
+		if address, _, err = w.CoinAddress(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restoredAddress, _, err := restored.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredAddress != address {
+		t.Fatal("restored wallet derived a different address")
+	}
+
+	// A restored address may see spends of outputs that the rescan has not
+	// found yet, which are ignored.
+	diffs := []consensus.OutputDiff{{
+		New:    false,
+		ID:     consensus.OutputID{1},
+		Output: consensus.Output{Value: 10, SpendHash: restoredAddress},
+	}}
+	if err = restored.Update(0, nil, nil, diffs); err != nil {
+		t.Fatal(err)
+	}
+
+	// The seed is unavailable while the wallet is locked, and survives
+	// encryption.
+	if err = w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Seed(); err != ErrLocked {
+		t.Fatal("expected ErrLocked when reading the seed of a locked wallet")
+	}
+	if err = w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if unlockedMnemonic, _ := w.Seed(); unlockedMnemonic != mnemonic {
+		t.Fatal("seed changed after encrypting the wallet")
+	}
+}
+
+// TestCoinSelection funds transactions from a known set of outputs with each
+// selection strategy, and consolidates the smallest outputs.
+func TestCoinSelection(t *testing.T) {
+	wt := newWalletTester(t)
+
+	w := wt.wallet("selection.wallet")
+	small, _, err := w.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, _, err := w.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the wallet outputs worth 1, 2 and 5 at one address, and 10 and 20
+	// at another.
+	var diffs []consensus.OutputDiff
+	for i, value := range []consensus.Currency{1, 2, 5, 10, 20} {
+		address := small
+		if value >= 10 {
+			address = large
+		}
+		diffs = append(diffs, consensus.OutputDiff{
+			New:    true,
+			ID:     consensus.OutputID{byte(i + 1)},
+			Output: consensus.Output{Value: value, SpendHash: address},
+		})
+	}
+	if err = w.Update(0, nil, nil, diffs); err != nil {
+		t.Fatal(err)
+	}
+
+	// fund funds a transaction and returns the values of its inputs, and the
+	// number of outputs.
+	fund := func(amount consensus.Currency, strategy components.SelectionStrategy) (inputs []consensus.Currency, outputs int, err error) {
+		defer w.Reset()
+		id, err := w.RegisterTransaction(consensus.Transaction{})
+		if err != nil {
+			return
+		}
+		if _, _, err = w.FundTransactionWithStrategy(id, amount, strategy); err != nil {
+			return
+		}
+		txn, err := w.SignTransaction(id, true)
+		if err != nil {
+			return
+		}
+		for _, input := range txn.Inputs {
+			inputs = append(inputs, consensus.Currency(input.OutputID[0]))
+		}
+		return inputs, len(txn.Outputs), nil
+	}
+
+	// Inputs are identified by their output ids, 1 through 5.
+	tests := []struct {
+		amount   consensus.Currency
+		strategy components.SelectionStrategy
+		inputs   []consensus.Currency
+		outputs  int
+	}{
+		{15, components.LargestFirst, []consensus.Currency{5}, 1},
+		{15, components.SmallestFirst, []consensus.Currency{1, 2, 3, 4}, 1},
+		{17, components.BranchAndBound, []consensus.Currency{4, 3, 2}, 0},
+		{7, components.PrivacyPreserving, []consensus.Currency{3, 2}, 0},
+		{9, components.PrivacyPreserving, []consensus.Currency{5}, 1},
+	}
+	for _, test := range tests {
+		inputs, outputs, err := fund(test.amount, test.strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(inputs) != fmt.Sprint(test.inputs) || outputs != test.outputs {
+			t.Errorf("%v with %v: expected inputs %v and %v outputs, got %v and %v", test.strategy, test.amount, test.inputs, test.outputs, inputs, outputs)
+		}
+	}
+	if _, _, err = fund(100, components.LargestFirst); err != components.LowBalanceErr {
+		t.Error("expected LowBalanceErr, got", err)
+	}
+	if _, _, err = fund(1, "random"); err != ErrUnknownStrategy {
+		t.Error("expected ErrUnknownStrategy, got", err)
+	}
+
+	// The change output is appended after the existing outputs, and pays to
+	// a new wallet address.
+	id, err := w.RegisterTransaction(consensus.Transaction{Outputs: []consensus.Output{{Value: 12}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, changeIndex, err := w.FundTransaction(id, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, err := w.SignTransaction(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != 5 || changeIndex != 1 || txn.Outputs[changeIndex].Value != change {
+		t.Errorf("expected change of 5 at index 1, got %v at index %v", change, changeIndex)
+	}
+	if sa := txn.Outputs[changeIndex].SpendHash; sa == small || sa == large {
+		t.Error("change was sent to an existing address")
+	}
+	w.Reset()
+
+	// Consolidate the outputs worth less than 6.
+	txn, err = w.Consolidate(6, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.Inputs) != 3 || len(txn.Outputs) != 1 || txn.Outputs[0].Value != 7 {
+		t.Error("consolidation produced the wrong transaction:", txn)
+	}
+	if _, err = w.Consolidate(6, 10, 1); err != ErrNothingToConsolidate {
+		t.Error("expected ErrNothingToConsolidate, got", err)
+	}
+}
+
+// TestMultisig creates a 2-of-3 address shared by three wallets, and spends
+// from it with signatures made in turn and independently.
+func TestMultisig(t *testing.T) {
+	wt := newWalletTester(t)
+
+	var wallets []*Wallet
+	var keys []crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		w := wt.wallet(fmt.Sprintf("multisig%d.wallet", i))
+		pk, err := w.MultisigKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallets = append(wallets, w)
+		keys = append(keys, pk)
+	}
+
+	// Every wallet gets the same address, whatever the order of the keys.
+	address, spendConditions, err := wallets[0].CreateMultisigAddress(2, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range wallets[1:] {
+		reversed := []crypto.PublicKey{keys[2], keys[1], keys[0]}
+		a, _, err := w.CreateMultisigAddress(2, reversed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != address {
+			t.Fatalf("wallet %v created a different address", i+1)
+		}
+	}
+	other := wt.wallet("other.wallet")
+	if _, _, err = other.CreateMultisigAddress(2, keys); err != ErrNoLocalKey {
+		t.Error("expected ErrNoLocalKey, got", err)
+	}
+
+	// Send 50 coins to the address.
+	diffs := []consensus.OutputDiff{{
+		New:    true,
+		ID:     consensus.OutputID{1},
+		Output: consensus.Output{Value: 50, SpendHash: address},
+	}}
+	for _, w := range wallets {
+		if err = w.Update(0, nil, nil, diffs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wallets[0].Balance(false) != 0 {
+		t.Error("multisig outputs should not be part of the balance")
+	}
+	infos, err := wallets[0].MultisigAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Address != address || infos[0].NumSignatures != 2 || infos[0].NumKeys != 3 || infos[0].Balance != 50 {
+		t.Error("wrong multisig addresses:", infos)
+	}
+
+	// checkSignatures checks that a transaction has n valid signatures.
+	checkSignatures := func(txn consensus.Transaction, n int) {
+		if len(txn.Signatures) != n {
+			t.Errorf("expected %v signatures, got %v", n, len(txn.Signatures))
+		}
+		for i, sig := range txn.Signatures {
+			sigHash := txn.SigHash(i)
+			if !crypto.VerifyBytes(sigHash[:], spendConditions.PublicKeys[sig.PublicKeyIndex], sig.Signature) {
+				t.Error("invalid signature", i)
+			}
+		}
+	}
+
+	// Sign in turn.
+	dest, _, err := other.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := wallets[0].MultisigSend(address, 30, dest, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignatures(partial, 1)
+	if len(partial.Outputs) != 2 || partial.Outputs[1].Value != 15 || partial.Outputs[1].SpendHash != address {
+		t.Error("change should be returned to the multisig address")
+	}
+	signed, err := wallets[1].SignMultisig(partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignatures(signed, 2)
+
+	// Sign independently and merge. Only two signatures are needed.
+	unsigned := partial
+	unsigned.Signatures = nil
+	signed1, err := wallets[1].SignMultisig(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed2, err := wallets[2].SignMultisig(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := wallets[0].MergeMultisig([]consensus.Transaction{partial, signed1, signed2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignatures(merged, 2)
+
+	// Invalid signatures are dropped when merging.
+	forged := signed1
+	forged.Signatures = append([]consensus.TransactionSignature(nil), signed1.Signatures...)
+	forged.Signatures[0].Signature[0]++
+	merged, err = wallets[0].MergeMultisig([]consensus.Transaction{partial, forged, signed2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignatures(merged, 2)
+	if merged.Signatures[1].PublicKeyIndex != signed2.Signatures[0].PublicKeyIndex {
+		t.Error("forged signature was kept")
+	}
+	changed := signed2
+	changed.MinerFees = []consensus.Currency{6}
+	if _, err = wallets[0].MergeMultisig([]consensus.Transaction{partial, changed}); err != ErrMismatchedMerge {
+		t.Error("expected ErrMismatchedMerge, got", err)
+	}
+
+	// Signing does not mark the outputs as spent, since the transaction may
+	// never be completed. They are marked once it is submitted.
+	multisigBalance := func(w *Wallet) consensus.Currency {
+		infos, err := w.MultisigAddresses()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return infos[0].Balance
+	}
+	for i, w := range wallets {
+		if multisigBalance(w) != 50 {
+			t.Errorf("wallet %v marked outputs as spent before the transaction was complete", i)
+		}
+	}
+	wallets[0].MarkSpent(merged)
+	if multisigBalance(wallets[0]) != 0 {
+		t.Error("outputs of a submitted transaction should be marked as spent")
+	}
+
+	// The address is kept when the wallet is reloaded.
+	reloaded := wt.wallet("multisig0.wallet")
+	infos, err = reloaded.MultisigAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Address != address {
+		t.Error("multisig address was not reloaded:", infos)
+	}
+}
+
+// TestOfflineSigning funds a transaction on a locked copy of a wallet, signs
+// it with the original wallet, and checks the signatures.
+func TestOfflineSigning(t *testing.T) {
+	wt := newWalletTester(t)
+
+	// Create the offline wallet, and give a locked copy of it to the online
+	// machine.
+	offline := wt.wallet("offline.wallet")
+	address, spendConditions, err := offline.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = offline.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if err = offline.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(wt.filename("offline.wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = offline.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(wt.filename("online.wallet"), contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	online := wt.wallet("online.wallet")
+
+	diffs := []consensus.OutputDiff{{
+		New:    true,
+		ID:     consensus.OutputID{1},
+		Output: consensus.Output{Value: 50, SpendHash: address},
+	}}
+	for _, w := range []*Wallet{offline, online} {
+		if err = w.Update(0, nil, nil, diffs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Export a transaction from the locked wallet.
+	id, err := online.RegisterTransaction(consensus.Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, changeIndex, err := online.FundUnsignedTransaction(id, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = online.AddMinerFee(id, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err = online.AddOutput(id, consensus.Output{Value: 25}); err != nil {
+		t.Fatal(err)
+	}
+	ut, err := online.ExportTransaction(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ut.Inputs) != 1 || len(ut.Transaction.Signatures) != 0 || ut.Transaction.Inputs[0].SpendConditions.CoinAddress() != address {
+		t.Fatal("wrong unsigned transaction:", ut)
+	}
+	if len(ut.Values) != 1 || ut.Values[0] != 50 {
+		t.Fatal("wrong input values:", ut.Values)
+	}
+	if online.Balance(false) != 0 {
+		t.Error("exported outputs should be marked as spent")
+	}
+	if _, _, err = online.SignUnsignedTransaction(ut, 5); err != ErrLocked {
+		t.Error("expected ErrLocked, got", err)
+	}
+
+	// The change goes to a new address of the online wallet's key pool rather
+	// than back to the address it came from.
+	changeAddress := ut.Transaction.Outputs[changeIndex].SpendHash
+	if change != 20 || changeAddress == address {
+		t.Fatal("change should be sent to a new address:", ut.Transaction.Outputs)
+	}
+	if _, exists := offline.spendableAddresses[changeAddress]; exists {
+		t.Fatal("change address should not be known to the offline wallet before signing")
+	}
+
+	// The offline wallet refuses transactions that pay too much in fees,
+	// misreport the values of their inputs, or are not signed in whole.
+	if _, _, err = offline.SignUnsignedTransaction(ut, 4); err != ErrFeeTooHigh {
+		t.Error("expected ErrFeeTooHigh, got", err)
+	}
+	for _, values := range [][]consensus.Currency{nil, {60}, {50, 1}} {
+		bad := ut
+		bad.Values = values
+		if _, _, err = offline.SignUnsignedTransaction(bad, 5); err == nil {
+			t.Error("signed a transaction with input values", values)
+		}
+	}
+	partial := ut
+	partial.CoveredFields = coveredFields(ut.Transaction, false)
+	if _, _, err = offline.SignUnsignedTransaction(partial, 5); err != ErrPartialCover {
+		t.Error("expected ErrPartialCover, got", err)
+	}
+	partial.CoveredFields = consensus.CoveredFields{Outputs: []uint64{5}}
+	if _, _, err = offline.SignUnsignedTransaction(partial, 5); err == nil {
+		t.Error("signed a transaction with out of range covered fields")
+	}
+
+	// Pass the transaction through its encoding, and sign it offline.
+	var decoded components.UnsignedTransaction
+	if err = encoding.Unmarshal(encoding.Marshal(ut), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	signed, sent, err := offline.SignUnsignedTransaction(decoded, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 25 {
+		t.Error("expected 25 coins to be sent, got", sent)
+	}
+	if len(signed.Signatures) != 1 {
+		t.Fatal("expected 1 signature, got", len(signed.Signatures))
+	}
+	sigHash := signed.SigHash(0)
+	if !crypto.VerifyBytes(sigHash[:], spendConditions.PublicKeys[0], signed.Signatures[0].Signature) {
+		t.Error("invalid signature")
+	}
+
+	// A wallet without the address refuses to sign.
+	other := wt.wallet("other.wallet")
+	if _, _, err = other.SignUnsignedTransaction(ut, 5); err != ErrUnknownInput {
+		t.Error("expected ErrUnknownInput, got", err)
+	}
+
+	// The offline wallet tracks the change address once it has signed the
+	// transaction, so once the transaction is confirmed the change can be
+	// exported and signed again.
+	if _, exists := offline.spendableAddresses[changeAddress]; !exists {
+		t.Fatal("offline wallet should track the change address after signing")
+	}
+	diffs = []consensus.OutputDiff{
+		{New: false, ID: consensus.OutputID{1}, Output: diffs[0].Output},
+		{New: true, ID: signed.OutputID(int(changeIndex)), Output: signed.Outputs[changeIndex]},
+	}
+	for _, w := range []*Wallet{offline, online} {
+		if err = w.Update(1, nil, nil, diffs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id, err = online.RegisterTransaction(consensus.Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = online.FundUnsignedTransaction(id, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err = online.AddOutput(id, consensus.Output{Value: 20}); err != nil {
+		t.Fatal(err)
+	}
+	ut, err = online.ExportTransaction(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = offline.SignUnsignedTransaction(ut, 0); err != nil {
+		t.Error("could not sign a transaction spending the change:", err)
+	}
+
+	// A discarded transaction can no longer be exported.
+	id, err = online.RegisterTransaction(consensus.Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = online.DiscardTransaction(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = online.ExportTransaction(id, true); err == nil {
+		t.Error("exported a discarded transaction")
+	}
+}
+
+// TestWatchOnly tracks the addresses of a cold wallet from another wallet, and
+// checks that they are reported separately and are not spent.
+func TestWatchOnly(t *testing.T) {
+	wt := newWalletTester(t)
+
+	cold := wt.wallet("cold.wallet")
+	online := wt.wallet("online.wallet")
+	bare, _, err := cold.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, spendConditions, err := cold.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, freshConditions, err := cold.CoinAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Watch one address by itself, and one with its spend conditions.
+	if err = online.WatchAddress(bare, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = online.WatchAddress(known, &spendConditions); err != nil {
+		t.Fatal(err)
+	}
+	if err = online.WatchAddress(bare, nil); err != ErrAlreadyTracked {
+		t.Error("expected ErrAlreadyTracked, got", err)
+	}
+	if err = online.WatchAddress(consensus.CoinAddress{1}, &spendConditions); err == nil {
+		t.Error("watched an address with the wrong spend conditions")
+	}
+
+	// Send 100 coins to the bare address and 50 to the other. The spend of an
+	// output that the wallet never saw is ignored.
+	diffs := []consensus.OutputDiff{
+		{New: true, ID: consensus.OutputID{1}, Output: consensus.Output{Value: 100, SpendHash: bare}},
+		{New: true, ID: consensus.OutputID{2}, Output: consensus.Output{Value: 50, SpendHash: known}},
+		{New: false, ID: consensus.OutputID{3}, Output: consensus.Output{Value: 7, SpendHash: bare}},
+	}
+	for _, w := range []*Wallet{cold, online} {
+		if err = w.Update(0, nil, nil, diffs[:2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = online.Update(0, nil, nil, diffs[2:]); err != nil {
+		t.Fatal(err)
+	}
+	info, err := online.WalletInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Balance != 0 || info.NumWatchAddresses != 2 || info.WatchBalance != 150 {
+		t.Error("watch-only addresses reported incorrectly:", info)
+	}
+
+	// The wallet refuses to spend from the addresses.
+	id, err := online.RegisterTransaction(consensus.Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = online.FundTransaction(id, 40); err != components.LowBalanceErr {
+		t.Error("expected LowBalanceErr when spending from watch-only addresses, got", err)
+	}
+
+	// A transaction can be exported from the address with spend conditions,
+	// and signed by the cold wallet. The change goes to an unused watched
+	// address of the cold wallet rather than to an address of the online
+	// wallet, so the export fails until there is one.
+	if _, _, err = online.FundUnsignedTransaction(id, 40); err != ErrNoChangeAddress {
+		t.Error("expected ErrNoChangeAddress, got", err)
+	}
+	if err = online.WatchAddress(fresh, &freshConditions); err != nil {
+		t.Fatal(err)
+	}
+	change, changeIndex, err := online.FundUnsignedTransaction(id, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = online.AddOutput(id, consensus.Output{Value: 40}); err != nil {
+		t.Fatal(err)
+	}
+	ut, err := online.ExportTransaction(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ut.Transaction.Inputs) != 1 || ut.Transaction.Inputs[0].OutputID != diffs[1].ID {
+		t.Fatal("expected the output with known spend conditions to be spent:", ut.Transaction.Inputs)
+	}
+	if change != 10 || ut.Transaction.Outputs[changeIndex].SpendHash != fresh {
+		t.Error("change should be sent to the unused watched address:", ut.Transaction.Outputs)
+	}
+	if _, sent, err := cold.SignUnsignedTransaction(ut, 0); err != nil || sent != 40 {
+		t.Fatal("expected 40 coins to be sent, got", sent, err)
+	}
+
+	// The addresses are kept when the wallet is reloaded.
+	reloaded := wt.wallet("online.wallet")
+	addresses, err := reloaded.WatchAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 3 {
+		t.Fatal("expected 3 watch-only addresses, got", len(addresses))
+	}
+	for _, a := range addresses {
+		if a.HasSpendConditions != (a.Address != bare) {
+			t.Error("spend conditions were not reloaded correctly for", a.Address)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/sia/components"
+)
+
+var (
+	ErrAlreadyTracked = errors.New("address is already tracked by the wallet")
+)
+
+// addWatch starts tracking a watch-only address. spendConditions is the zero
+// value if the spend conditions are not known.
+func (w *Wallet) addWatch(coinAddress consensus.CoinAddress, spendConditions consensus.SpendConditions) {
+	w.watchAddresses[coinAddress] = &spendableAddress{
+		spendableOutputs: make(map[consensus.OutputID]*spendableOutput),
+		spendConditions:  spendConditions,
+		keyIndex:         -1,
+	}
+}
+
+// hasSpendConditions returns whether the spend conditions of a watch-only
+// address are known.
+func hasSpendConditions(coinAddress consensus.CoinAddress, sa *spendableAddress) bool {
+	return sa.spendConditions.CoinAddress() == coinAddress
+}
+
+// watchedAddresses returns every watch-only address, sorted by address.
+func (w *Wallet) watchedAddresses() (addresses []watchedAddress) {
+	for coinAddress, sa := range w.watchAddresses {
+		wa := watchedAddress{Address: coinAddress}
+		if hasSpendConditions(coinAddress, sa) {
+			wa.SpendConditions = sa.spendConditions
+		}
+		addresses = append(addresses, wa)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Address[:], addresses[j].Address[:]) < 0
+	})
+	return
+}
+
+// watchedOutputs returns the unspent outputs of the watch-only addresses whose
+// spend conditions are known.
+func (w *Wallet) watchedOutputs() (outputs []*spendableOutput) {
+	for coinAddress, sa := range w.watchAddresses {
+		if hasSpendConditions(coinAddress, sa) {
+			outputs = append(outputs, w.unspentOutputs(sa)...)
+		}
+	}
+	return
+}
+
+// WatchAddress implements the core.Wallet interface. Outputs that were sent
+// to the address before it was added are only found by a rescan.
+func (w *Wallet) WatchAddress(coinAddress consensus.CoinAddress, spendConditions *consensus.SpendConditions) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.trackedAddress(coinAddress); exists {
+		return ErrAlreadyTracked
+	}
+	var sc consensus.SpendConditions
+	if spendConditions != nil {
+		if spendConditions.CoinAddress() != coinAddress {
+			return errors.New("spend conditions do not match the address")
+		}
+		sc = *spendConditions
+	}
+	w.addWatch(coinAddress, sc)
+	return w.save()
+}
+
+// WatchAddresses implements the core.Wallet interface.
+func (w *Wallet) WatchAddresses() (addresses []components.WatchAddressInfo, err error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, wa := range w.watchedAddresses() {
+		info := components.WatchAddressInfo{
+			Address:            wa.Address,
+			HasSpendConditions: wa.SpendConditions.CoinAddress() == wa.Address,
+		}
+		for _, so := range w.unspentOutputs(w.watchAddresses[wa.Address]) {
+			info.Balance += so.output.Value
+		}
+		addresses = append(addresses, info)
+	}
+	return
+}
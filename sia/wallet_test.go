@@ -1,9 +1,14 @@
 package sia
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/sia/components"
+	"github.com/NebulousLabs/Sia/sia/wallet"
 )
 
 // testSendToSelf does a send from the wallet to itself, and checks that all of
@@ -51,6 +56,115 @@ func testSendToSelf(t *testing.T, c *Core) {
 	}
 }
 
+// testWalletRescan sends coins to a wallet that is not connected to the core,
+// and checks that the wallet finds them by rescanning the blockchain.
+func testWalletRescan(t *testing.T, c *Core) {
+	dir, err := ioutil.TempDir("", "sia-wallet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	w, err := wallet.New(c.state, filepath.Join(dir, "rescan.wallet"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dest, _, err := w.CoinAddress()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Send coins to the wallet and mine them into a block.
+	amount := consensus.Currency(25)
+	txn, err := c.SpendCoins(amount, dest)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = c.processTransaction(txn)
+	if err != nil && err != consensus.ConflictingTransactionErr {
+		t.Error(err)
+	}
+	mineSingleBlock(t, c)
+
+	// The wallet only learns of the coins by rescanning. Rescanning twice
+	// should not count the coins twice.
+	if w.Balance(false) != 0 {
+		t.Error("wallet should not know about the coins before rescanning")
+	}
+	for i := 0; i < 2; i++ {
+		if err = w.Rescan(0); err != nil {
+			t.Error(err)
+			return
+		}
+		if w.Balance(false) != amount {
+			t.Errorf("Expecting a balance of %v after rescanning, got %v", amount, w.Balance(false))
+		}
+	}
+	info, _ := w.WalletInfo()
+	if info.Rescanning {
+		t.Error("wallet still reports a rescan in progress")
+	}
+}
+
+// testWalletHistory checks the history entry of the send made by
+// testWalletRescan, and checks pagination and labels.
+func testWalletHistory(t *testing.T, c *Core) {
+	txns, total, err := c.WalletHistory(nil, 0, 1000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(txns) != total || total == 0 {
+		t.Errorf("expected %v history entries, got %v", total, len(txns))
+		return
+	}
+
+	// The most recent block contains the send, followed by the subsidy.
+	if !txns[0].MinerPayout {
+		t.Error("expected the most recent entry to be a miner payout")
+	}
+	var send components.WalletTransaction
+	for _, txn := range txns {
+		if !txn.MinerPayout {
+			send = txn
+			break
+		}
+	}
+	if send.Fees == 0 || send.Outflow-send.Inflow != 25+send.Fees {
+		t.Errorf("send has the wrong values: %+v", send)
+	}
+	if len(send.Counterparties) != 1 {
+		t.Error("expected the send to have one counterparty, got", len(send.Counterparties))
+	}
+
+	// Check pagination and filtering by address.
+	page, pageTotal, err := c.WalletHistory(nil, 1, 2)
+	if err != nil || pageTotal != total || len(page) != 2 || page[0].ID != txns[1].ID {
+		t.Error("history pagination returned the wrong entries")
+	}
+	ledger, _, err := c.WalletHistory(&send.Addresses[0], 0, 1000)
+	if err != nil || len(ledger) == 0 {
+		t.Error("expected entries in the ledger of a wallet address")
+	}
+
+	// Label the send.
+	if err = c.LabelWalletTransaction(send.ID, "rent"); err != nil {
+		t.Error(err)
+	}
+	if c.LabelWalletTransaction(consensus.TransactionID{}, "none") != wallet.ErrUnknownTransaction {
+		t.Error("expected ErrUnknownTransaction when labeling an unknown transaction")
+	}
+	txns, _, _ = c.WalletHistory(nil, 0, 1000)
+	for _, txn := range txns {
+		if txn.ID == send.ID && txn.Label != "rent" {
+			t.Error("label was not set")
+		}
+	}
+}
+
 // testWalletInfo calles wallet.Info to see if an error is thrown. Also make sure
 // there is no deadlock.
 func testWalletInfo(t *testing.T, c *Core) {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
@@ -56,6 +57,36 @@ func callAPI(call string) (err error) {
 	return
 }
 
+// post wraps a POST request with a status code check, such that if the POST
+// does not return 200, the error will be read and returned. POST is used for
+// sensitive values, such as passwords, that should not appear in URLs.
+func post(call string, values url.Values) (err error) {
+	resp, err := http.PostForm(hostname+call, values)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		errResp, _ := ioutil.ReadAll(resp.Body)
+		err = errors.New(strings.TrimSpace(string(errResp)))
+	}
+	return
+}
+
+// postAPI makes a POST request and decodes the response.
+func postAPI(call string, values url.Values, obj interface{}) (err error) {
+	resp, err := http.PostForm(hostname+call, values)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		errResp, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(strings.TrimSpace(string(errResp)))
+	}
+	return json.NewDecoder(resp.Body).Decode(obj)
+}
+
 // wrap wraps a generic command with a check that the command has been
 // passed the correct number of arguments. The command must take only strings
 // as arguments.
@@ -110,7 +141,10 @@ func main() {
 	minerCmd.AddCommand(minerStartCmd, minerStatusCmd, minerStopCmd)
 
 	root.AddCommand(walletCmd)
-	walletCmd.AddCommand(walletAddressCmd, walletSendCmd, walletStatusCmd)
+	walletCmd.AddCommand(walletAddressCmd, walletSendCmd, walletConsolidateCmd, walletStatusCmd, walletLockCmd, walletUnlockCmd, walletSeedCmd, walletRestoreCmd, walletHistoryCmd, walletLabelCmd, walletMultisigCmd, walletOfflineCmd, walletWatchCmd)
+	walletMultisigCmd.AddCommand(walletMultisigKeyCmd, walletMultisigCreateCmd, walletMultisigAddressesCmd, walletMultisigSendCmd, walletMultisigSignCmd, walletMultisigMergeCmd, walletMultisigBroadcastCmd)
+	walletOfflineCmd.AddCommand(walletOfflineExportCmd, walletOfflineSignCmd, walletOfflineBroadcastCmd)
+	walletWatchCmd.AddCommand(walletWatchAddCmd, walletWatchListCmd)
 
 	root.AddCommand(fileCmd)
 	fileCmd.AddCommand(fileUploadCmd, fileDownloadCmd, fileStatusCmd)
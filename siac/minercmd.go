@@ -57,7 +57,7 @@ func minerstatuscmd() {
 	fmt.Printf(`Miner status:
 State:   %s
 Threads: %d (%d active)
-Address: %x
+Address: %s
 `, status.State, status.Threads, status.RunningThreads, status.Address)
 }
 
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/NebulousLabs/Sia/consensus"
 	"github.com/NebulousLabs/Sia/sia/components"
 )
 
@@ -26,10 +31,163 @@ var (
 	walletSendCmd = &cobra.Command{
 		Use:   "send [amount] [dest]",
 		Short: "Send coins to another wallet",
-		Long:  "Send coins to another wallet. 'dest' must be a 64-byte hexadecimal address.",
+		Long:  "Send coins to another wallet. 'dest' must be a 76-character checksummed address, as returned by 'wallet address'.",
 		Run:   wrap(walletsendcmd),
 	}
 
+	walletConsolidateCmd = &cobra.Command{
+		Use:   "consolidate [threshold]",
+		Short: "Combine small outputs",
+		Long:  "Sweep up to 50 of the wallet's outputs worth less than 'threshold' coins into a single output, so that future transactions need fewer inputs.",
+		Run:   wrap(walletconsolidatecmd),
+	}
+
+	walletLockCmd = &cobra.Command{
+		Use:   "lock",
+		Short: "Lock the wallet",
+		Long:  "Erase the wallet's secret keys from memory. Coins cannot be sent until the wallet is unlocked.",
+		Run:   wrap(walletlockcmd),
+	}
+
+	walletUnlockCmd = &cobra.Command{
+		Use:   "unlock",
+		Short: "Unlock the wallet",
+		Long:  "Decrypt the wallet's secret keys, prompting for the password. If the wallet is not encrypted yet, it is encrypted with the password.",
+		Run:   wrap(walletunlockcmd),
+	}
+
+	walletSeedCmd = &cobra.Command{
+		Use:   "seed",
+		Short: "Show the wallet seed",
+		Long:  "Print the wallet's seed as a list of words. Every address in the wallet can be recovered from the seed, so it should be written down and kept secret. The wallet must be unlocked.",
+		Run:   wrap(walletseedcmd),
+	}
+
+	walletRestoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore the wallet from a seed",
+		Long:  "Replace the wallet's seed with a seed previously printed by 'wallet seed', prompting for the words, and recover the addresses derived from it. The wallet must be unlocked.",
+		Run:   wrap(walletrestorecmd),
+	}
+
+	walletHistoryCmd = &cobra.Command{
+		Use:   "history [page]",
+		Short: "View the wallet's transaction history",
+		Long:  "List the confirmed transactions that sent coins to or from the wallet, most recent first. Page 1 holds the most recent transactions.",
+		Run:   wrap(wallethistorycmd),
+	}
+
+	walletLabelCmd = &cobra.Command{
+		Use:   "label [id] [label]",
+		Short: "Label a transaction",
+		Long:  "Set the label of a transaction in the wallet's history. 'id' is the transaction id shown by 'wallet history'.",
+		Run:   wrap(walletlabelcmd),
+	}
+
+	walletMultisigCmd = &cobra.Command{
+		Use:   "multisig",
+		Short: "Manage multisignature addresses",
+		Long:  "Create addresses shared with cosigners, and create, sign, and submit transactions that spend from them.",
+		Run:   wrap(walletmultisigaddressescmd),
+	}
+
+	walletMultisigKeyCmd = &cobra.Command{
+		Use:   "key",
+		Short: "Get a public key for a multisig address",
+		Long:  "Generate a public key of the wallet, to be given to the cosigners of a multisig address.",
+		Run:   wrap(walletmultisigkeycmd),
+	}
+
+	walletMultisigCreateCmd = &cobra.Command{
+		Use:   "create [required] [keys]",
+		Short: "Create a multisig address",
+		Long:  "Create an address that needs 'required' signatures to spend from. 'keys' is a comma-separated list of the public keys of every cosigner, including one from 'wallet multisig key'. Every cosigner should create the address from the same keys.",
+		Run:   wrap(walletmultisigcreatecmd),
+	}
+
+	walletMultisigAddressesCmd = &cobra.Command{
+		Use:   "addresses",
+		Short: "List multisig addresses",
+		Long:  "List the multisig addresses of the wallet and their balances.",
+		Run:   wrap(walletmultisigaddressescmd),
+	}
+
+	walletMultisigSendCmd = &cobra.Command{
+		Use:   "send [address] [amount] [dest]",
+		Short: "Send coins from a multisig address",
+		Long:  "Create a transaction sending coins from a multisig address, signed by the wallet. If more signatures are needed, the transaction is printed so that it can be passed to the cosigners.",
+		Run:   wrap(walletmultisigsendcmd),
+	}
+
+	walletMultisigSignCmd = &cobra.Command{
+		Use:   "sign [transaction]",
+		Short: "Sign a multisig transaction",
+		Long:  "Add the wallet's signatures to a transaction printed by 'wallet multisig send' or 'wallet multisig sign'.",
+		Run:   wrap(walletmultisigsigncmd),
+	}
+
+	walletMultisigMergeCmd = &cobra.Command{
+		Use:   "merge [transaction1] [transaction2]",
+		Short: "Merge multisig signatures",
+		Long:  "Combine the signatures of two copies of a transaction that were signed by different cosigners.",
+		Run:   wrap(walletmultisigmergecmd),
+	}
+
+	walletMultisigBroadcastCmd = &cobra.Command{
+		Use:   "broadcast [transaction]",
+		Short: "Submit a multisig transaction",
+		Long:  "Submit a transaction that has been signed by enough cosigners.",
+		Run:   wrap(walletmultisigbroadcastcmd),
+	}
+
+	walletOfflineCmd = &cobra.Command{
+		Use:   "offline",
+		Short: "Sign transactions offline",
+		Long:  "Create transactions on a wallet without its secret keys, sign them on a wallet kept offline, and submit the signed transactions.",
+	}
+
+	walletOfflineExportCmd = &cobra.Command{
+		Use:   "export [amount] [dest]",
+		Short: "Create an unsigned transaction",
+		Long:  "Create a transaction sending coins to 'dest' without signing it. The wallet does not need to be unlocked. The transaction is printed so that it can be signed by 'wallet offline sign' on the wallet holding the secret keys.",
+		Run:   wrap(walletofflineexportcmd),
+	}
+
+	walletOfflineSignCmd = &cobra.Command{
+		Use:   "sign [transaction]",
+		Short: "Sign an exported transaction",
+		Long:  "Sign a transaction printed by 'wallet offline export'. Transactions paying a fee of more than 10 are refused. The signed transaction is printed with the coins it sends and its fee, and is not submitted.",
+		Run:   wrap(walletofflinesigncmd),
+	}
+
+	walletOfflineBroadcastCmd = &cobra.Command{
+		Use:   "broadcast [transaction]",
+		Short: "Submit a signed transaction",
+		Long:  "Submit a transaction printed by 'wallet offline sign'.",
+		Run:   wrap(walletofflinebroadcastcmd),
+	}
+
+	walletWatchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Manage watch-only addresses",
+		Long:  "Track the balances of addresses whose secret keys are kept elsewhere. The wallet never spends from watch-only addresses.",
+		Run:   wrap(walletwatchlistcmd),
+	}
+
+	walletWatchAddCmd = &cobra.Command{
+		Use:   "add [address]",
+		Short: "Add a watch-only address",
+		Long:  "Track the outputs of an address, and rescan the blockchain for them. 'address' is either a 76-character address, or the spend conditions of the address as a JSON object, which allows transactions spending from the address to be created with 'wallet offline export'.",
+		Run:   wrap(walletwatchaddcmd),
+	}
+
+	walletWatchListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List watch-only addresses",
+		Long:  "List the watch-only addresses of the wallet and their balances.",
+		Run:   wrap(walletwatchlistcmd),
+	}
+
 	walletStatusCmd = &cobra.Command{
 		Use:   "status",
 		Short: "View wallet status",
@@ -54,6 +212,12 @@ func walletaddresscmd() {
 }
 
 func walletsendcmd(amount, dest string) {
+	// Check the address before sending it to the daemon, so that a mistyped
+	// address can be reported clearly.
+	if _, err := consensus.ParseCoinAddress(dest); err != nil {
+		fmt.Println("Invalid destination address:", err)
+		return
+	}
 	err := callAPI(fmt.Sprintf("/wallet/send?amount=%s&dest=%s", amount, dest))
 	if err != nil {
 		fmt.Println("Could not send:", err)
@@ -62,6 +226,310 @@ func walletsendcmd(amount, dest string) {
 	fmt.Printf("Sent %s coins to %s\n", amount, dest)
 }
 
+func walletconsolidatecmd(threshold string) {
+	var result struct {
+		Inputs int
+		Value  consensus.Currency
+	}
+	err := getAPI("/wallet/consolidate?threshold="+url.QueryEscape(threshold), &result)
+	if err != nil {
+		fmt.Println("Could not consolidate wallet:", err)
+		return
+	}
+	fmt.Printf("Combined %d outputs into one output of %v coins\n", result.Inputs, result.Value)
+}
+
+func walletlockcmd() {
+	err := callAPI("/wallet/lock")
+	if err != nil {
+		fmt.Println("Could not lock wallet:", err)
+		return
+	}
+	fmt.Println("Wallet locked")
+}
+
+// readSecret prompts for a line of input on stdin. Secrets are read from stdin
+// rather than the command line, so that they do not appear in the shell
+// history or process list.
+func readSecret(prompt string) (line string, err error) {
+	fmt.Print(prompt)
+	line, err = bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line != "" {
+		err = nil
+	}
+	line = strings.TrimRight(line, "\r\n")
+	return
+}
+
+func walletunlockcmd() {
+	password, err := readSecret("Wallet password: ")
+	if err != nil {
+		fmt.Println("Could not read password:", err)
+		return
+	}
+	err = post("/wallet/unlock", url.Values{"password": {password}})
+	if err != nil {
+		fmt.Println("Could not unlock wallet:", err)
+		return
+	}
+	fmt.Println("Wallet unlocked")
+}
+
+func walletseedcmd() {
+	var seed struct {
+		Mnemonic string
+	}
+	err := getAPI("/wallet/seed", &seed)
+	if err != nil {
+		fmt.Println("Could not get wallet seed:", err)
+		return
+	}
+	fmt.Println("Wallet seed:")
+	fmt.Println(seed.Mnemonic)
+}
+
+func walletrestorecmd() {
+	mnemonic, err := readSecret("Wallet seed: ")
+	if err != nil {
+		fmt.Println("Could not read seed:", err)
+		return
+	}
+	err = post("/wallet/restore", url.Values{"mnemonic": {mnemonic}})
+	if err != nil {
+		fmt.Println("Could not restore wallet:", err)
+		return
+	}
+	fmt.Println("Wallet restored")
+}
+
+// historyPageSize is the number of transactions shown on each page of the
+// wallet history.
+const historyPageSize = 20
+
+func wallethistorycmd(page string) {
+	var pageNum int
+	if _, err := fmt.Sscan(page, &pageNum); err != nil || pageNum < 1 {
+		fmt.Println("Invalid page number:", page)
+		return
+	}
+	var history struct {
+		Total        int
+		Transactions []components.WalletTransaction
+	}
+	err := getAPI(fmt.Sprintf("/wallet/transactions?offset=%d&limit=%d", (pageNum-1)*historyPageSize, historyPageSize), &history)
+	if err != nil {
+		fmt.Println("Could not get wallet history:", err)
+		return
+	}
+	if len(history.Transactions) == 0 {
+		fmt.Println("No transactions")
+		return
+	}
+	fmt.Printf("Transactions %d-%d of %d:\n", (pageNum-1)*historyPageSize+1, (pageNum-1)*historyPageSize+len(history.Transactions), history.Total)
+	for _, txn := range history.Transactions {
+		net := fmt.Sprintf("+%v", txn.Inflow-txn.Outflow)
+		if txn.Outflow > txn.Inflow {
+			net = fmt.Sprintf("-%v", txn.Outflow-txn.Inflow)
+		}
+		kind := "transaction"
+		if txn.MinerPayout {
+			kind = "miner payout"
+		}
+		fmt.Printf("%x\n\tHeight: %v\tNet: %v\tFees: %v\t(%s)\n", txn.ID, txn.ConfirmationHeight, net, txn.Fees, kind)
+		if txn.Label != "" {
+			fmt.Printf("\tLabel: %s\n", txn.Label)
+		}
+	}
+}
+
+func walletlabelcmd(id, label string) {
+	err := callAPI(fmt.Sprintf("/wallet/label?id=%s&label=%s", id, url.QueryEscape(label)))
+	if err != nil {
+		fmt.Println("Could not label transaction:", err)
+		return
+	}
+	fmt.Println("Transaction labeled")
+}
+
+func walletmultisigkeycmd() {
+	var key struct {
+		PublicKey string
+	}
+	err := getAPI("/wallet/multisig/key", &key)
+	if err != nil {
+		fmt.Println("Could not get public key:", err)
+		return
+	}
+	fmt.Println("Public key:", key.PublicKey)
+}
+
+func walletmultisigcreatecmd(required, keys string) {
+	addr := new(walletAddr)
+	err := postAPI("/wallet/multisig/create", url.Values{"required": {required}, "keys": {keys}}, addr)
+	if err != nil {
+		fmt.Println("Could not create multisig address:", err)
+		return
+	}
+	fmt.Printf("Created multisig address: %s\n", addr.Address)
+}
+
+func walletmultisigaddressescmd() {
+	var result struct {
+		Addresses []components.MultisigAddressInfo
+	}
+	err := getAPI("/wallet/multisig/addresses", &result)
+	if err != nil {
+		fmt.Println("Could not get multisig addresses:", err)
+		return
+	}
+	if len(result.Addresses) == 0 {
+		fmt.Println("No multisig addresses")
+		return
+	}
+	for _, info := range result.Addresses {
+		fmt.Printf("%s\n\t%d of %d\tBalance: %v\n", info.Address, info.NumSignatures, info.NumKeys, info.Balance)
+	}
+}
+
+// multisigTransaction is the response of the multisig calls that return a
+// transaction.
+type multisigTransaction struct {
+	Transaction string
+	Complete    bool
+}
+
+// printMultisigTransaction prints a multisig transaction, and whether it
+// needs more signatures.
+func printMultisigTransaction(t multisigTransaction) {
+	fmt.Println(t.Transaction)
+	if t.Complete {
+		fmt.Println("The transaction has enough signatures")
+	} else {
+		fmt.Println("The transaction needs more signatures")
+	}
+}
+
+func walletmultisigsendcmd(address, amount, dest string) {
+	var t multisigTransaction
+	err := postAPI("/wallet/multisig/send", url.Values{"address": {address}, "amount": {amount}, "dest": {dest}}, &t)
+	if err != nil {
+		fmt.Println("Could not send:", err)
+		return
+	}
+	if t.Complete {
+		fmt.Printf("Sent %s coins to %s\n", amount, dest)
+		return
+	}
+	printMultisigTransaction(t)
+}
+
+func walletmultisigsigncmd(transaction string) {
+	var t multisigTransaction
+	err := postAPI("/wallet/multisig/sign", url.Values{"transaction": {transaction}}, &t)
+	if err != nil {
+		fmt.Println("Could not sign transaction:", err)
+		return
+	}
+	printMultisigTransaction(t)
+}
+
+func walletmultisigmergecmd(transaction1, transaction2 string) {
+	var t multisigTransaction
+	err := postAPI("/wallet/multisig/merge", url.Values{"transaction": {transaction1, transaction2}}, &t)
+	if err != nil {
+		fmt.Println("Could not merge transactions:", err)
+		return
+	}
+	printMultisigTransaction(t)
+}
+
+func walletmultisigbroadcastcmd(transaction string) {
+	var result struct {
+		ID consensus.TransactionID
+	}
+	err := postAPI("/wallet/multisig/broadcast", url.Values{"transaction": {transaction}}, &result)
+	if err != nil {
+		fmt.Println("Could not broadcast transaction:", err)
+		return
+	}
+	fmt.Printf("Submitted transaction %x\n", result.ID)
+}
+
+func walletofflineexportcmd(amount, dest string) {
+	var result struct {
+		Transaction string
+	}
+	err := postAPI("/wallet/offline/export", url.Values{"amount": {amount}, "dest": {dest}}, &result)
+	if err != nil {
+		fmt.Println("Could not create transaction:", err)
+		return
+	}
+	fmt.Println(result.Transaction)
+}
+
+func walletofflinesigncmd(transaction string) {
+	var result struct {
+		Transaction string
+		Sent        consensus.Currency
+		Fee         consensus.Currency
+	}
+	err := postAPI("/wallet/offline/sign", url.Values{"transaction": {transaction}}, &result)
+	if err != nil {
+		fmt.Println("Could not sign transaction:", err)
+		return
+	}
+	fmt.Printf("Sends %v coins and pays a fee of %v. Check these before broadcasting the transaction:\n", result.Sent, result.Fee)
+	fmt.Println(result.Transaction)
+}
+
+func walletofflinebroadcastcmd(transaction string) {
+	var result struct {
+		ID consensus.TransactionID
+	}
+	err := postAPI("/wallet/offline/broadcast", url.Values{"transaction": {transaction}}, &result)
+	if err != nil {
+		fmt.Println("Could not broadcast transaction:", err)
+		return
+	}
+	fmt.Printf("Submitted transaction %x\n", result.ID)
+}
+
+func walletwatchaddcmd(address string) {
+	values := url.Values{"address": {address}}
+	if strings.HasPrefix(strings.TrimSpace(address), "{") {
+		values = url.Values{"conditions": {address}}
+	}
+	addr := new(walletAddr)
+	err := postAPI("/wallet/watch", values, addr)
+	if err != nil {
+		fmt.Println("Could not watch address:", err)
+		return
+	}
+	fmt.Printf("Watching address: %s\n", addr.Address)
+}
+
+func walletwatchlistcmd() {
+	var result struct {
+		Addresses []components.WatchAddressInfo
+	}
+	err := getAPI("/wallet/watch/addresses", &result)
+	if err != nil {
+		fmt.Println("Could not get watch-only addresses:", err)
+		return
+	}
+	if len(result.Addresses) == 0 {
+		fmt.Println("No watch-only addresses")
+		return
+	}
+	for _, info := range result.Addresses {
+		conditions := "address only"
+		if info.HasSpendConditions {
+			conditions = "spend conditions known"
+		}
+		fmt.Printf("%s\n\tBalance: %v\t(%s)\n", info.Address, info.Balance, conditions)
+	}
+}
+
 func walletstatuscmd() {
 	status := new(components.WalletInfo)
 	err := getAPI("/wallet/status", status)
@@ -73,5 +541,13 @@ func walletstatuscmd() {
 Balance:   %v (confirmed) 
            %v (unconfirmed)
 Addresses: %d
-`, status.Balance, status.FullBalance, status.NumAddresses)
+Encrypted: %v
+Locked:    %v
+`, status.Balance, status.FullBalance, status.NumAddresses, status.Encrypted, status.Locked)
+	if status.NumWatchAddresses != 0 {
+		fmt.Printf("Watch-only: %v (%d addresses)\n", status.WatchBalance, status.NumWatchAddresses)
+	}
+	if status.Rescanning {
+		fmt.Printf("Rescanning: block %v of %v\n", status.RescanHeight, status.RescanTarget)
+	}
 }
@@ -26,7 +26,27 @@ func (d *daemon) handle(addr string) {
 	// Wallet API Calls
 	http.HandleFunc("/wallet/address", d.walletAddressHandler)
 	http.HandleFunc("/wallet/send", d.walletSendHandler)
+	http.HandleFunc("/wallet/consolidate", d.walletConsolidateHandler)
 	http.HandleFunc("/wallet/status", d.walletStatusHandler)
+	http.HandleFunc("/wallet/lock", d.walletLockHandler)
+	http.HandleFunc("/wallet/unlock", d.walletUnlockHandler)
+	http.HandleFunc("/wallet/seed", d.walletSeedHandler)
+	http.HandleFunc("/wallet/restore", d.walletRestoreHandler)
+	http.HandleFunc("/wallet/rescan", d.walletRescanHandler)
+	http.HandleFunc("/wallet/transactions", d.walletTransactionsHandler)
+	http.HandleFunc("/wallet/label", d.walletLabelHandler)
+	http.HandleFunc("/wallet/multisig/key", d.walletMultisigKeyHandler)
+	http.HandleFunc("/wallet/multisig/create", d.walletMultisigCreateHandler)
+	http.HandleFunc("/wallet/multisig/addresses", d.walletMultisigAddressesHandler)
+	http.HandleFunc("/wallet/multisig/send", d.walletMultisigSendHandler)
+	http.HandleFunc("/wallet/multisig/sign", d.walletMultisigSignHandler)
+	http.HandleFunc("/wallet/multisig/merge", d.walletMultisigMergeHandler)
+	http.HandleFunc("/wallet/multisig/broadcast", d.walletMultisigBroadcastHandler)
+	http.HandleFunc("/wallet/offline/export", d.walletOfflineExportHandler)
+	http.HandleFunc("/wallet/offline/sign", d.walletOfflineSignHandler)
+	http.HandleFunc("/wallet/offline/broadcast", d.walletOfflineBroadcastHandler)
+	http.HandleFunc("/wallet/watch", d.walletWatchHandler)
+	http.HandleFunc("/wallet/watch/addresses", d.walletWatchAddressesHandler)
 
 	// File API Calls
 	http.HandleFunc("/file/upload", d.fileUploadHandler)
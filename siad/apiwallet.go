@@ -1,10 +1,17 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/NebulousLabs/Sia/consensus"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/sia/components"
 )
 
 // walletAddressHandler manages requests for CoinAddresses from the wallet.
@@ -16,7 +23,7 @@ func (d *daemon) walletAddressHandler(w http.ResponseWriter, req *http.Request)
 	}
 	writeJSON(w, struct {
 		Address string
-	}{fmt.Sprintf("%x", coinAddress)})
+	}{coinAddress.String()})
 }
 
 // walletSendHandler manages 'send' requests that are made to the wallet.
@@ -35,21 +42,24 @@ func (d *daemon) walletSendHandler(w http.ResponseWriter, req *http.Request) {
 	// if ca, ok := e.friends[destString]; ok {
 	// 	destString = ca
 	// }
-	// if len(destString) != 64 {
+	// if len(destString) != consensus.AddressStringLen {
 	// 	http.Error(w, "Friend not found (or malformed coin address)", 400)
 	// 	return
 	// }
 
-	var destAddressBytes []byte
-	_, err = fmt.Sscanf(destString, "%x", &destAddressBytes)
+	dest, err = consensus.ParseCoinAddress(destString)
 	if err != nil {
-		http.Error(w, "Malformed coin address", 400)
+		http.Error(w, "Malformed coin address: "+err.Error(), 400)
 		return
 	}
-	copy(dest[:], destAddressBytes)
 
-	// Spend the coins.
-	_, err = d.core.SpendCoins(amount, dest)
+	// Spend the coins, selecting outputs largest first unless another
+	// strategy is requested.
+	strategy := components.LargestFirst
+	if req.FormValue("strategy") != "" {
+		strategy = components.SelectionStrategy(req.FormValue("strategy"))
+	}
+	_, err = d.core.SpendCoinsWithStrategy(amount, dest, strategy)
 	if err != nil {
 		http.Error(w, "Failed to create transaction: "+err.Error(), 500)
 		return
@@ -58,6 +68,478 @@ func (d *daemon) walletSendHandler(w http.ResponseWriter, req *http.Request) {
 	writeSuccess(w)
 }
 
+// walletConsolidateHandler handles requests to sweep the wallet's outputs worth
+// less than `threshold` into a single output.
+func (d *daemon) walletConsolidateHandler(w http.ResponseWriter, req *http.Request) {
+	var threshold consensus.Currency
+	_, err := fmt.Sscan(req.FormValue("threshold"), &threshold)
+	if err != nil {
+		http.Error(w, "Malformed threshold", 400)
+		return
+	}
+	maxInputs := 50
+	if req.FormValue("max") != "" {
+		if _, err = fmt.Sscan(req.FormValue("max"), &maxInputs); err != nil {
+			http.Error(w, "Malformed max", 400)
+			return
+		}
+	}
+	fee := consensus.Currency(10) // TODO: wallet supplied miner fee
+	if req.FormValue("fee") != "" {
+		if _, err = fmt.Sscan(req.FormValue("fee"), &fee); err != nil {
+			http.Error(w, "Malformed fee", 400)
+			return
+		}
+	}
+
+	t, err := d.core.ConsolidateWallet(threshold, maxInputs, fee)
+	if err != nil {
+		http.Error(w, "Failed to consolidate wallet: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		ID     consensus.TransactionID
+		Inputs int
+		Value  consensus.Currency
+	}{t.ID(), len(t.Inputs), t.Outputs[0].Value})
+}
+
+// walletUnlockHandler handles requests to unlock the wallet. If the wallet is
+// not encrypted, it is encrypted with the supplied password.
+func (d *daemon) walletUnlockHandler(w http.ResponseWriter, req *http.Request) {
+	err := d.core.UnlockWallet(req.FormValue("password"))
+	if err != nil {
+		http.Error(w, "Failed to unlock wallet: "+err.Error(), 400)
+		return
+	}
+	writeSuccess(w)
+}
+
+// walletLockHandler handles requests to lock the wallet.
+func (d *daemon) walletLockHandler(w http.ResponseWriter, req *http.Request) {
+	err := d.core.LockWallet()
+	if err != nil {
+		http.Error(w, "Failed to lock wallet: "+err.Error(), 400)
+		return
+	}
+	writeSuccess(w)
+}
+
+// walletSeedHandler returns the mnemonic form of the wallet's seed.
+func (d *daemon) walletSeedHandler(w http.ResponseWriter, req *http.Request) {
+	mnemonic, err := d.core.WalletSeed()
+	if err != nil {
+		http.Error(w, "Failed to get wallet seed: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		Mnemonic string
+	}{mnemonic})
+}
+
+// walletRestoreHandler handles requests to restore the wallet from a seed.
+func (d *daemon) walletRestoreHandler(w http.ResponseWriter, req *http.Request) {
+	err := d.core.RestoreWallet(req.FormValue("mnemonic"))
+	if err != nil {
+		http.Error(w, "Failed to restore wallet: "+err.Error(), 400)
+		return
+	}
+	writeSuccess(w)
+}
+
+// walletRescanHandler handles requests to rescan the blockchain for the
+// wallet's outputs. The scan starts at the genesis block unless a height is
+// given. The request returns once the scan has finished; its progress is
+// reported by /wallet/status.
+func (d *daemon) walletRescanHandler(w http.ResponseWriter, req *http.Request) {
+	var height consensus.BlockHeight
+	if req.FormValue("height") != "" {
+		_, err := fmt.Sscan(req.FormValue("height"), &height)
+		if err != nil {
+			http.Error(w, "Malformed height", 400)
+			return
+		}
+	}
+	err := d.core.RescanWallet(height)
+	if err != nil {
+		http.Error(w, "Failed to rescan wallet: "+err.Error(), 500)
+		return
+	}
+	writeSuccess(w)
+}
+
+// walletTransactionsHandler returns a page of the wallet's transaction
+// history, most recent first. At most `limit` entries are returned, starting
+// after the first `offset`, and only transactions involving `address` are
+// returned if it is given.
+func (d *daemon) walletTransactionsHandler(w http.ResponseWriter, req *http.Request) {
+	offset, limit := 0, 50
+	if req.FormValue("offset") != "" {
+		if _, err := fmt.Sscan(req.FormValue("offset"), &offset); err != nil {
+			http.Error(w, "Malformed offset", 400)
+			return
+		}
+	}
+	if req.FormValue("limit") != "" {
+		if _, err := fmt.Sscan(req.FormValue("limit"), &limit); err != nil {
+			http.Error(w, "Malformed limit", 400)
+			return
+		}
+	}
+	var address *consensus.CoinAddress
+	if req.FormValue("address") != "" {
+		ca, err := consensus.ParseCoinAddress(req.FormValue("address"))
+		if err != nil {
+			http.Error(w, "Malformed coin address: "+err.Error(), 400)
+			return
+		}
+		address = &ca
+	}
+
+	txns, total, err := d.core.WalletHistory(address, offset, limit)
+	if err != nil {
+		http.Error(w, "Failed to get wallet history: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		Total        int
+		Transactions []components.WalletTransaction
+	}{total, txns})
+}
+
+// walletLabelHandler sets the label of a transaction in the wallet's history.
+func (d *daemon) walletLabelHandler(w http.ResponseWriter, req *http.Request) {
+	var id consensus.TransactionID
+	b, err := hex.DecodeString(req.FormValue("id"))
+	if err != nil || len(b) != len(id) {
+		http.Error(w, "Malformed transaction id", 400)
+		return
+	}
+	copy(id[:], b)
+	err = d.core.LabelWalletTransaction(id, req.FormValue("label"))
+	if err != nil {
+		http.Error(w, "Failed to label transaction: "+err.Error(), 400)
+		return
+	}
+	writeSuccess(w)
+}
+
+// encodeObject encodes a transaction, or a transaction in progress, for the
+// multisig and offline signing APIs. Transactions are passed between wallets
+// as base64 strings.
+func encodeObject(v interface{}) string {
+	return base64.StdEncoding.EncodeToString(encoding.Marshal(v))
+}
+
+// decodeObject decodes an object encoded by encodeObject.
+func decodeObject(s string, v interface{}) (err error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return
+	}
+	return encoding.Unmarshal(b, v)
+}
+
+// decodeTransaction decodes a transaction encoded by encodeObject.
+func decodeTransaction(s string) (t consensus.Transaction, err error) {
+	err = decodeObject(s, &t)
+	return
+}
+
+// writeMultisigTransaction writes a multisig transaction, and whether it has
+// been signed by enough cosigners to be valid.
+func writeMultisigTransaction(w http.ResponseWriter, t consensus.Transaction, complete bool) {
+	writeJSON(w, struct {
+		Transaction string
+		Complete    bool
+	}{encodeObject(t), complete})
+}
+
+// walletMultisigKeyHandler returns a public key of the wallet, to be shared
+// with cosigners.
+func (d *daemon) walletMultisigKeyHandler(w http.ResponseWriter, req *http.Request) {
+	pk, err := d.core.MultisigKey()
+	if err != nil {
+		http.Error(w, "Failed to get a public key: "+err.Error(), 500)
+		return
+	}
+	writeJSON(w, struct {
+		PublicKey string
+	}{hex.EncodeToString(pk[:])})
+}
+
+// walletMultisigCreateHandler creates an address that requires `required`
+// signatures from the comma-separated hex public keys in `keys`.
+func (d *daemon) walletMultisigCreateHandler(w http.ResponseWriter, req *http.Request) {
+	var required uint64
+	_, err := fmt.Sscan(req.FormValue("required"), &required)
+	if err != nil {
+		http.Error(w, "Malformed number of required signatures", 400)
+		return
+	}
+	var keys []crypto.PublicKey
+	for _, key := range strings.Split(req.FormValue("keys"), ",") {
+		pk := new(crypto.JSONPublicKey)
+		b, err := hex.DecodeString(strings.TrimSpace(key))
+		if err != nil || len(b) != len(pk) {
+			http.Error(w, "Malformed public key: "+key, 400)
+			return
+		}
+		copy(pk[:], b)
+		keys = append(keys, pk.PublicKey())
+	}
+
+	address, err := d.core.CreateMultisigAddress(required, keys)
+	if err != nil {
+		http.Error(w, "Failed to create multisig address: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		Address string
+	}{address.String()})
+}
+
+// walletMultisigAddressesHandler lists the multisig addresses of the wallet.
+func (d *daemon) walletMultisigAddressesHandler(w http.ResponseWriter, req *http.Request) {
+	addresses, err := d.core.MultisigAddresses()
+	if err != nil {
+		http.Error(w, "Failed to get multisig addresses: "+err.Error(), 500)
+		return
+	}
+	writeJSON(w, struct {
+		Addresses []components.MultisigAddressInfo
+	}{addresses})
+}
+
+// walletMultisigSendHandler creates a transaction sending coins from a
+// multisig address, signed by the wallet. The transaction is submitted if the
+// wallet's signatures are enough; otherwise it must be passed to cosigners.
+func (d *daemon) walletMultisigSendHandler(w http.ResponseWriter, req *http.Request) {
+	address, err := consensus.ParseCoinAddress(req.FormValue("address"))
+	if err != nil {
+		http.Error(w, "Malformed multisig address: "+err.Error(), 400)
+		return
+	}
+	dest, err := consensus.ParseCoinAddress(req.FormValue("dest"))
+	if err != nil {
+		http.Error(w, "Malformed coin address: "+err.Error(), 400)
+		return
+	}
+	var amount consensus.Currency
+	if _, err = fmt.Sscan(req.FormValue("amount"), &amount); err != nil {
+		http.Error(w, "Malformed amount", 400)
+		return
+	}
+	fee := consensus.Currency(10) // TODO: wallet supplied miner fee
+	if req.FormValue("fee") != "" {
+		if _, err = fmt.Sscan(req.FormValue("fee"), &fee); err != nil {
+			http.Error(w, "Malformed fee", 400)
+			return
+		}
+	}
+
+	t, complete, err := d.core.MultisigSend(address, amount, dest, fee)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), 400)
+		return
+	}
+	writeMultisigTransaction(w, t, complete)
+}
+
+// walletMultisigSignHandler adds the wallet's signatures to a multisig
+// transaction.
+func (d *daemon) walletMultisigSignHandler(w http.ResponseWriter, req *http.Request) {
+	t, err := decodeTransaction(req.FormValue("transaction"))
+	if err != nil {
+		http.Error(w, "Malformed transaction", 400)
+		return
+	}
+	t, complete, err := d.core.SignMultisig(t)
+	if err != nil {
+		http.Error(w, "Failed to sign transaction: "+err.Error(), 400)
+		return
+	}
+	writeMultisigTransaction(w, t, complete)
+}
+
+// walletMultisigMergeHandler combines the signatures of every `transaction`
+// value, which must be copies of the same multisig transaction.
+func (d *daemon) walletMultisigMergeHandler(w http.ResponseWriter, req *http.Request) {
+	err := req.ParseForm()
+	if err != nil {
+		http.Error(w, "Malformed request", 400)
+		return
+	}
+	var ts []consensus.Transaction
+	for _, s := range req.Form["transaction"] {
+		t, err := decodeTransaction(s)
+		if err != nil {
+			http.Error(w, "Malformed transaction", 400)
+			return
+		}
+		ts = append(ts, t)
+	}
+	t, complete, err := d.core.MergeMultisig(ts)
+	if err != nil {
+		http.Error(w, "Failed to merge transactions: "+err.Error(), 400)
+		return
+	}
+	writeMultisigTransaction(w, t, complete)
+}
+
+// walletMultisigBroadcastHandler submits a multisig transaction that has been
+// signed by enough cosigners.
+func (d *daemon) walletMultisigBroadcastHandler(w http.ResponseWriter, req *http.Request) {
+	t, err := decodeTransaction(req.FormValue("transaction"))
+	if err != nil {
+		http.Error(w, "Malformed transaction", 400)
+		return
+	}
+	err = d.core.BroadcastTransaction(t)
+	if err != nil {
+		http.Error(w, "Failed to broadcast transaction: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		ID consensus.TransactionID
+	}{t.ID()})
+}
+
+// walletOfflineExportHandler creates a transaction sending `amount` coins to
+// `dest` without signing it, so that it can be signed by an offline wallet.
+func (d *daemon) walletOfflineExportHandler(w http.ResponseWriter, req *http.Request) {
+	dest, err := consensus.ParseCoinAddress(req.FormValue("dest"))
+	if err != nil {
+		http.Error(w, "Malformed coin address: "+err.Error(), 400)
+		return
+	}
+	var amount consensus.Currency
+	if _, err = fmt.Sscan(req.FormValue("amount"), &amount); err != nil {
+		http.Error(w, "Malformed amount", 400)
+		return
+	}
+	fee := consensus.Currency(10) // TODO: wallet supplied miner fee
+	if req.FormValue("fee") != "" {
+		if _, err = fmt.Sscan(req.FormValue("fee"), &fee); err != nil {
+			http.Error(w, "Malformed fee", 400)
+			return
+		}
+	}
+
+	ut, err := d.core.ExportTransaction(amount, dest, fee)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		Transaction string
+	}{encodeObject(ut)})
+}
+
+// walletOfflineSignHandler signs a transaction exported by another wallet if
+// it pays no more than `maxfee` to the miners. The value sent and the fee are
+// returned with the transaction, so that they can be checked before it is
+// broadcast.
+func (d *daemon) walletOfflineSignHandler(w http.ResponseWriter, req *http.Request) {
+	var ut components.UnsignedTransaction
+	err := decodeObject(req.FormValue("transaction"), &ut)
+	if err != nil {
+		http.Error(w, "Malformed transaction", 400)
+		return
+	}
+	maxFee := consensus.Currency(10)
+	if req.FormValue("maxfee") != "" {
+		if _, err = fmt.Sscan(req.FormValue("maxfee"), &maxFee); err != nil {
+			http.Error(w, "Malformed fee", 400)
+			return
+		}
+	}
+	t, sent, err := d.core.SignExportedTransaction(ut, maxFee)
+	if err != nil {
+		http.Error(w, "Failed to sign transaction: "+err.Error(), 400)
+		return
+	}
+	var fee consensus.Currency
+	for _, minerFee := range t.MinerFees {
+		fee += minerFee
+	}
+	writeJSON(w, struct {
+		Transaction string
+		Sent        consensus.Currency
+		Fee         consensus.Currency
+	}{encodeObject(t), sent, fee})
+}
+
+// walletOfflineBroadcastHandler submits a transaction signed by an offline
+// wallet.
+func (d *daemon) walletOfflineBroadcastHandler(w http.ResponseWriter, req *http.Request) {
+	t, err := decodeTransaction(req.FormValue("transaction"))
+	if err != nil {
+		http.Error(w, "Malformed transaction", 400)
+		return
+	}
+	err = d.core.BroadcastTransaction(t)
+	if err != nil {
+		http.Error(w, "Failed to broadcast transaction: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		ID consensus.TransactionID
+	}{t.ID()})
+}
+
+// walletWatchHandler adds a watch-only address to the wallet. The address is
+// given either as `address`, or as `conditions`, a JSON object holding the
+// spend conditions of the address. The blockchain is rescanned for the
+// address's outputs unless `rescan` is false.
+func (d *daemon) walletWatchHandler(w http.ResponseWriter, req *http.Request) {
+	var address consensus.CoinAddress
+	var spendConditions *consensus.SpendConditions
+	var err error
+	if req.FormValue("conditions") != "" {
+		spendConditions = new(consensus.SpendConditions)
+		if err = json.Unmarshal([]byte(req.FormValue("conditions")), spendConditions); err != nil {
+			http.Error(w, "Malformed spend conditions: "+err.Error(), 400)
+			return
+		}
+		address = spendConditions.CoinAddress()
+	} else {
+		address, err = consensus.ParseCoinAddress(req.FormValue("address"))
+		if err != nil {
+			http.Error(w, "Malformed coin address: "+err.Error(), 400)
+			return
+		}
+	}
+	rescan := true
+	if req.FormValue("rescan") != "" {
+		if _, err = fmt.Sscan(req.FormValue("rescan"), &rescan); err != nil {
+			http.Error(w, "Malformed rescan", 400)
+			return
+		}
+	}
+
+	err = d.core.WatchAddress(address, spendConditions, rescan)
+	if err != nil {
+		http.Error(w, "Failed to watch address: "+err.Error(), 400)
+		return
+	}
+	writeJSON(w, struct {
+		Address string
+	}{address.String()})
+}
+
+// walletWatchAddressesHandler lists the watch-only addresses of the wallet.
+func (d *daemon) walletWatchAddressesHandler(w http.ResponseWriter, req *http.Request) {
+	addresses, err := d.core.WatchAddresses()
+	if err != nil {
+		http.Error(w, "Failed to get watch-only addresses: "+err.Error(), 500)
+		return
+	}
+	writeJSON(w, struct {
+		Addresses []components.WatchAddressInfo
+	}{addresses})
+}
+
 // I wasn't sure the best way to manage this. I've implemented it so that the
 // wallet returns some arbitrary JSON and it's up to the front-end to figure
 // out how to use the json. The daemon and envrionment don't really know what's
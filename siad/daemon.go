@@ -57,10 +57,11 @@ func startDaemon(config Config) (err error) {
 	}
 
 	siaconfig := sia.Config{
-		HostDir:     config.Siacore.HostDirectory,
-		WalletFile:  config.Siad.WalletFile,
-		ServerAddr:  config.Siacore.RPCaddr,
-		Nobootstrap: config.Siacore.NoBootstrap,
+		HostDir:      config.Siacore.HostDirectory,
+		WalletFile:   config.Siad.WalletFile,
+		ServerAddr:   config.Siacore.RPCaddr,
+		Nobootstrap:  config.Siacore.NoBootstrap,
+		LANDiscovery: config.Siacore.LANDiscovery,
 
 		State: state,
 